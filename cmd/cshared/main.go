@@ -0,0 +1,84 @@
+//go:build cgo
+
+// This program exports the codec APIs over a C ABI so other languages can
+// benchmark against these reference implementations. Build it with:
+//
+//	go build -buildmode=c-shared -o libdbinternals.so ./cmd/cshared
+//
+// which also emits libdbinternals.h. Encodings are referenced through opaque
+// handles that must be released with the matching Free function.
+
+package main
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+	rle "github.com/rahil/database-internals/pkg/rle"
+)
+
+//export DENew
+func DENew() C.uintptr_t {
+	return C.uintptr_t(cgo.NewHandle(deltaEncoding.InitDE()))
+}
+
+//export DEAppend
+func DEAppend(handle C.uintptr_t, id C.int, value C.int64_t, ts C.int64_t) {
+	de := cgo.Handle(handle).Value().(*deltaEncoding.DeltaEncoding)
+	de.AppendRow(deltaEncoding.Row{ID: int(id), Value: int64(value), TS: int64(ts)})
+}
+
+// DEReconstructRow writes the decoded value and ts of rowID into the out
+// parameters. It returns 0 on success and -1 if the row does not exist.
+//
+//export DEReconstructRow
+func DEReconstructRow(handle C.uintptr_t, rowID C.int, value *C.int64_t, ts *C.int64_t) C.int {
+	de := cgo.Handle(handle).Value().(*deltaEncoding.DeltaEncoding)
+	row, err := de.ReconstructRow(int(rowID))
+	if err != nil {
+		return -1
+	}
+	*value = C.int64_t(row.Value)
+	*ts = C.int64_t(row.TS)
+	return 0
+}
+
+//export DEFree
+func DEFree(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+//export RLENew
+func RLENew() C.uintptr_t {
+	return C.uintptr_t(cgo.NewHandle(rle.InitRLE()))
+}
+
+//export RLEAppend
+func RLEAppend(handle C.uintptr_t, id C.int, value C.int, ts *C.char) {
+	rleInst := cgo.Handle(handle).Value().(*rle.RLE)
+	rleInst.AppendRow(rle.Row{ID: int(id), Value: int(value), TS: C.GoString(ts)})
+}
+
+// RLECountOfTS returns the number of rows with the given ts, or -1 if absent.
+//
+//export RLECountOfTS
+func RLECountOfTS(handle C.uintptr_t, ts *C.char) C.int {
+	rleInst := cgo.Handle(handle).Value().(*rle.RLE)
+	count, err := rleInst.GetCountofTSFaster(C.GoString(ts))
+	if err != nil {
+		return -1
+	}
+	return C.int(count)
+}
+
+//export RLEFree
+func RLEFree(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+func main() {}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+// bench encodes synthetic series with several checkpoint intervals and prints
+// encode time, point-query latency and encoded size for each, so the interval
+// can be picked from measurements instead of guesses. Sizes are the
+// varint-encoded columns plus checkpoint overhead, as in Stats.
+
+type measurement struct {
+	encodePerRow time.Duration
+	lookup       time.Duration
+	bytes        int
+}
+
+func measure(rows []deltaEncoding.Row, interval, lookups int, seed int64) (measurement, error) {
+	start := time.Now()
+	de, err := deltaEncoding.NewDE(deltaEncoding.WithCheckpointInterval(interval))
+	if err != nil {
+		return measurement{}, err
+	}
+	de.AppendRows(rows)
+	encode := time.Since(start)
+
+	r := rand.New(rand.NewSource(seed))
+	ids := make([]int, lookups)
+	for i := range ids {
+		ids[i] = r.Intn(len(rows)) + 1
+	}
+	start = time.Now()
+	for _, id := range ids {
+		if _, err := de.ReconstructRow(id); err != nil {
+			return measurement{}, err
+		}
+	}
+	lookup := time.Since(start)
+
+	return measurement{
+		encodePerRow: encode / time.Duration(len(rows)),
+		lookup:       lookup / time.Duration(lookups),
+		bytes:        de.Stats().TotalBytes(),
+	}, nil
+}
+
+func parseIntervals(list string) ([]int, error) {
+	intervals := []int{}
+	for _, field := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, usageError("invalid checkpoint interval %q", field)
+		}
+		intervals = append(intervals, n)
+	}
+	return intervals, nil
+}
+
+func runBench(args []string) error {
+	fs := newFlagSet("bench", "")
+	rows := fs.Int("rows", 100000, "rows per series")
+	intervalList := fs.String("intervals", "1,4,16,64,256", "comma-separated checkpoint intervals")
+	seriesList := fs.String("series", "all", "comma-separated series, or all")
+	lookups := fs.Int("lookups", 10000, "point queries per measurement")
+	seed := fs.Int64("seed", 1, "random seed for data and lookups")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	intervals, err := parseIntervals(*intervalList)
+	if err != nil {
+		return err
+	}
+	series, err := parseSeries(*seriesList)
+	if err != nil {
+		return err
+	}
+	if *rows < 1 || *lookups < 1 {
+		return usageError("-rows and -lookups must be >= 1")
+	}
+
+	t := newTable(os.Stdout)
+	fmt.Fprintf(t, "series\tinterval\tencode/row\tpoint query\tbytes\tbytes/row\n")
+	for _, name := range series {
+		data := generate(name, *rows, *seed)
+		for _, interval := range intervals {
+			m, err := measure(data, interval, *lookups, *seed)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(t, "%s\t%d\t%s\t%s\t%d\t%.2f\n", name, interval, m.encodePerRow, m.lookup, m.bytes, float64(m.bytes)/float64(*rows))
+		}
+	}
+	return t.Flush()
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+	"github.com/rahil/database-internals/pkg/gorilla"
+	rle "github.com/rahil/database-internals/pkg/rle"
+)
+
+// compare runs one dataset through plain and varint storage, the delta
+// encoding with and without checkpoints and in frame-of-reference form, a
+// dictionary of distinct values, Gorilla and RLE, and prints a side-by-side
+// table of encoded sizes and point-query latencies.
+//
+// Sizes cover the value and ts columns only; ids are identical across codecs.
+// RLE only encodes the ts column, so its value column is stored as varints.
+// Gorilla only encodes the value column, so its ts column is stored as varint
+// deltas.
+
+func varintSize(data ...int64) int {
+	buf := make([]byte, binary.MaxVarintLen64)
+	total := 0
+	for _, v := range data {
+		total += binary.PutVarint(buf, v)
+	}
+	return total
+}
+
+type codecResult struct {
+	codec   string
+	bytes   int
+	latency time.Duration
+}
+
+// timeLookups returns the average latency of lookup over a fixed set of ids.
+func timeLookups(n int, lookup func(id int)) time.Duration {
+	const lookups = 10000
+	r := rand.New(rand.NewSource(1))
+	ids := make([]int, lookups)
+	for i := range ids {
+		ids[i] = r.Intn(n) + 1
+	}
+	start := time.Now()
+	for _, id := range ids {
+		lookup(id)
+	}
+	return time.Since(start) / lookups
+}
+
+// forColumn is a column bit-packed in frame-of-reference blocks, the layout
+// of EncodingFOR, to time point queries against.
+type forColumn struct {
+	interval int
+	mins     []int64
+	widths   []int
+	packed   [][]uint64 // offsets of each block, least significant bit first
+}
+
+func packFOR(data []int64, interval int) forColumn {
+	c := forColumn{interval: interval}
+	for block := range slices.Chunk(data, interval) {
+		lo := slices.Min(block)
+		width := bits.Len64(uint64(slices.Max(block)) - uint64(lo))
+		words := make([]uint64, (len(block)*width+63)/64)
+		for i, v := range block {
+			if width == 0 {
+				break // every offset is 0 and takes no bits
+			}
+			offset, pos := uint64(v)-uint64(lo), i*width
+			words[pos/64] |= offset << (pos % 64)
+			if pos%64+width > 64 {
+				words[pos/64+1] |= offset >> (64 - pos%64)
+			}
+		}
+		c.mins, c.widths, c.packed = append(c.mins, lo), append(c.widths, width), append(c.packed, words)
+	}
+	return c
+}
+
+// get returns the value at index i from its block's minimum and offset.
+func (c forColumn) get(i int) int64 {
+	block, width := i/c.interval, c.widths[i/c.interval]
+	if width == 0 {
+		return c.mins[block]
+	}
+	words, pos := c.packed[block], i%c.interval*width
+	offset := words[pos/64] >> (pos % 64)
+	if pos%64+width > 64 {
+		offset |= words[pos/64+1] << (64 - pos%64)
+	}
+	return int64(uint64(c.mins[block]) + offset&(1<<width-1))
+}
+
+// dictionaryColumn is a column stored as its distinct values and, per row, the
+// index of its value among them.
+type dictionaryColumn struct {
+	values  []int64
+	rows    int
+	indexes forColumn // a single frame of reference starting at index 0
+}
+
+func buildDictionary(data []int64) dictionaryColumn {
+	c := dictionaryColumn{rows: len(data)}
+	positions := map[int64]int64{}
+	indexes := make([]int64, len(data))
+	for i, v := range data {
+		index, ok := positions[v]
+		if !ok {
+			index = int64(len(c.values))
+			positions[v] = index
+			c.values = append(c.values, v)
+		}
+		indexes[i] = index
+	}
+	c.indexes = packFOR(indexes, len(data))
+	return c
+}
+
+// size returns the bytes of the distinct values and the packed indexes.
+func (c dictionaryColumn) size() int {
+	return varintSize(c.values...) + (c.rows*c.indexes.widths[0]+7)/8
+}
+
+// get returns the value at row index i.
+func (c dictionaryColumn) get(i int) int64 {
+	return c.values[c.indexes.get(i)]
+}
+
+func compareCodecs(rows []deltaEncoding.Row) []codecResult {
+	n := len(rows)
+	values, ts := make([]int64, n), make([]int64, n)
+	for i, row := range rows {
+		values[i], ts[i] = row.Value, row.TS
+	}
+	results := []codecResult{}
+
+	// plain: fixed 8 bytes per int64.
+	results = append(results, codecResult{"plain", 16 * n, timeLookups(n, func(id int) {
+		_, _ = values[id-1], ts[id-1]
+	})})
+
+	// varint: every absolute value varint-encoded; lookups need a sequential walk.
+	varint := make([]byte, 0, n*2*binary.MaxVarintLen64)
+	for i := range n {
+		varint = binary.AppendVarint(varint, values[i])
+		varint = binary.AppendVarint(varint, ts[i])
+	}
+	results = append(results, codecResult{"varint", len(varint), timeLookups(n, func(id int) {
+		buf := varint
+		for range 2 * id {
+			_, size := binary.Varint(buf)
+			buf = buf[size:]
+		}
+	})})
+
+	de := deltaEncoding.InitDE()
+	de.AppendRows(rows)
+	deltaBytes, checkpointBytes := 0, 0
+	for index := range de.NumBlocks() {
+		block, _ := de.Block(index)
+		deltaBytes += varintSize(block.ValueDeltas...) + varintSize(block.TSDeltas...)
+		checkpointBytes += varintSize(block.CheckpointValue, block.CheckpointTS)
+	}
+	// delta: only the base value, so a point query walks every delta before it
+	// instead of jumping to the nearest checkpoint.
+	results = append(results, codecResult{"delta", deltaBytes + varintSize(values[0], ts[0]), timeLookups(n, func(id int) {
+		for row := range de.All() {
+			if row.ID == id {
+				break
+			}
+		}
+	})})
+	results = append(results, codecResult{fmt.Sprintf("delta+checkpoint(%d)", de.CheckpointInterval()), deltaBytes + checkpointBytes, timeLookups(n, func(id int) {
+		_, _ = de.ReconstructRow(id)
+	})})
+
+	// frame-of-reference: the file size of both columns with every block in
+	// EncodingFOR. A point query reads the block's minimum and one offset.
+	stats := de.Stats()
+	valueFOR, tsFOR := packFOR(values, de.CheckpointInterval()), packFOR(ts, de.CheckpointInterval())
+	results = append(results, codecResult{"frame-of-reference", stats.ValueFORBytes + stats.TSFORBytes, timeLookups(n, func(id int) {
+		_, _ = valueFOR.get(id-1), tsFOR.get(id-1)
+	})})
+
+	// dictionary: each column's distinct values as varints, and every row's
+	// index into them bit-packed at the width of the largest index.
+	valueDict, tsDict := buildDictionary(values), buildDictionary(ts)
+	results = append(results, codecResult{"dictionary", valueDict.size() + tsDict.size(), timeLookups(n, func(id int) {
+		_, _ = valueDict.get(id-1), tsDict.get(id-1)
+	})})
+
+	// gorilla: values XOR-compressed as float64, ts as varint deltas. The stream
+	// has no checkpoints, so a point query decodes from the first value.
+	col := gorilla.InitColumn()
+	for _, v := range values {
+		col.Append(float64(v))
+	}
+	tsDeltas := make([]int64, n)
+	for i := range n {
+		tsDeltas[i] = ts[i]
+		if i > 0 {
+			tsDeltas[i] -= ts[i-1]
+		}
+	}
+	results = append(results, codecResult{"gorilla(value)", col.Stats().EncodedBytes + varintSize(tsDeltas...), timeLookups(n, func(id int) {
+		_, _ = col.Get(id - 1)
+	})})
+
+	rleInst := rle.InitRLE()
+	for i := range n {
+		rleInst.AppendRow(rle.Row{ID: i + 1, Value: int(values[i]), TS: strconv.FormatInt(ts[i], 10)})
+	}
+	rleBytes := varintSize(values...)
+	for _, run := range rleInst.TSRuns {
+		runTS, _ := strconv.ParseInt(run.TS(), 10, 64)
+		rleBytes += varintSize(runTS, int64(run.Count()))
+	}
+	results = append(results, codecResult{"rle(ts)", rleBytes, timeLookups(n, func(id int) {
+		_, _ = rleInst.ReconstructRow(id)
+	})})
+
+	return results
+}
+
+func runCompare(args []string) error {
+	fs := newFlagSet("compare", "[file.csv]")
+	series := fs.String("series", "random-walk", "series to generate: "+strings.Join(seriesOrder, ", "))
+	rows := fs.Int("rows", 100000, "number of generated rows")
+	perTS := fs.Int("per-ts", 2, "generated rows sharing each timestamp")
+	seed := fs.Int64("seed", 1, "random seed for the generated dataset")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("expected at most one input file, got %d", fs.NArg())
+	}
+
+	var data []deltaEncoding.Row
+	if fs.NArg() == 1 {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if data, err = readCSV(file, fs.Arg(0)); err != nil {
+			return err
+		}
+	} else {
+		if _, ok := generators[*series]; !ok {
+			return usageError("unknown series %q (want %s)", *series, strings.Join(seriesOrder, ", "))
+		}
+		if *rows < 1 || *perTS < 1 {
+			return usageError("-rows and -per-ts must be >= 1")
+		}
+		data = generate(*series, *rows, *seed)
+		for i := range data {
+			data[i].TS = 1_700_000_000 + int64(i / *perTS)
+		}
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("dataset is empty")
+	}
+	return printComparison(os.Stdout, compareCodecs(data))
+}
+
+func printComparison(w io.Writer, results []codecResult) error {
+	plain := results[0].bytes
+	t := newTable(w)
+	fmt.Fprintf(t, "codec\tbytes\tratio\tpoint query\n")
+	for _, r := range results {
+		fmt.Fprintf(t, "%s\t%d\t%.2fx\t%s\n", r.codec, r.bytes, float64(plain)/float64(r.bytes), r.latency)
+	}
+	return t.Flush()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+// Series generated by datagen and bench:
+//
+//	steady       a counter growing by a constant step every sample
+//	spiky        a flat baseline with rare large spikes
+//	plateau      long constant stretches separated by level shifts
+//	random-walk  a gauge moving by a random amount every sample
+var generators = map[string]func(r *rand.Rand, i int, prev int64) int64{
+	"steady": func(r *rand.Rand, i int, prev int64) int64 {
+		return prev + 100
+	},
+	"spiky": func(r *rand.Rand, i int, prev int64) int64 {
+		if r.Intn(100) == 0 {
+			return 500 + int64(r.Intn(1_000_000))
+		}
+		return 500 + int64(r.Intn(4))
+	},
+	"plateau": func(r *rand.Rand, i int, prev int64) int64 {
+		if r.Intn(500) == 0 {
+			return prev + int64(r.Intn(20_000)) - 10_000
+		}
+		return prev
+	},
+	"random-walk": func(r *rand.Rand, i int, prev int64) int64 {
+		return prev + int64(r.Intn(2<<16)) - 1<<16
+	},
+}
+
+var seriesOrder = []string{"steady", "spiky", "plateau", "random-walk"}
+
+func generate(name string, rows int, seed int64) []deltaEncoding.Row {
+	r := rand.New(rand.NewSource(seed))
+	next := generators[name]
+	out := make([]deltaEncoding.Row, rows)
+	value := int64(1 << 30)
+	for i := range out {
+		value = next(r, i, value)
+		out[i] = deltaEncoding.Row{ID: i + 1, Value: value, TS: 1_700_000_000 + int64(i)*15}
+	}
+	return out
+}
+
+func parseSeries(list string) ([]string, error) {
+	if list == "all" {
+		return seriesOrder, nil
+	}
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := generators[name]; !ok {
+			return nil, usageError("unknown series %q (want %s)", name, strings.Join(seriesOrder, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// readCSV reads lines of "value,ts" as rows with sequential ids.
+func readCSV(r io.Reader, name string) ([]deltaEncoding.Row, error) {
+	rows := []deltaEncoding.Row{}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		valueField, tsField, ok := strings.Cut(line, ",")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected value,ts", name, lineNo)
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(valueField), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+		}
+		ts, err := strconv.ParseInt(strings.TrimSpace(tsField), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+		}
+		rows = append(rows, deltaEncoding.Row{ID: len(rows) + 1, Value: value, TS: ts})
+	}
+	return rows, scanner.Err()
+}
+
+func runDatagen(args []string) error {
+	fs := newFlagSet("datagen", "")
+	series := fs.String("series", "random-walk", "series to generate: "+strings.Join(seriesOrder, ", "))
+	rows := fs.Int("rows", 100000, "rows to generate")
+	seed := fs.Int64("seed", 1, "random seed")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if _, ok := generators[*series]; !ok {
+		return usageError("unknown series %q (want %s)", *series, strings.Join(seriesOrder, ", "))
+	}
+	if *rows < 0 {
+		return usageError("-rows must be >= 0")
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, row := range generate(*series, *rows, *seed) {
+		fmt.Fprintf(w, "%d,%d\n", row.Value, row.TS)
+	}
+	return w.Flush()
+}
+
+func runLoad(args []string) error {
+	fs := newFlagSet("load", "[file.csv]")
+	var store storeFlags
+	store.register(fs)
+	interval := fs.Int("interval", 4, "checkpoint interval")
+	dod := fs.Bool("dod", false, "store ts as delta-of-deltas")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if store.path == "" {
+		return usageError("-store is required")
+	}
+	if fs.NArg() > 1 {
+		return usageError("expected at most one input file, got %d", fs.NArg())
+	}
+	opts, err := store.options()
+	if err != nil {
+		return err
+	}
+	opts = append(opts, deltaEncoding.WithCheckpointInterval(*interval))
+	if *dod {
+		opts = append(opts, deltaEncoding.WithTSDeltaOfDelta())
+	}
+	de, err := deltaEncoding.NewDE(opts...)
+	if err != nil {
+		return usageError("%v", err)
+	}
+
+	in, name := io.Reader(os.Stdin), "stdin"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	rows, err := readCSV(in, name)
+	if err != nil {
+		return err
+	}
+	de.AppendRows(rows)
+	if err := de.WriteToFile(store.path); err != nil {
+		return err
+	}
+
+	stats := de.Stats()
+	t := newTable(os.Stdout)
+	fmt.Fprintf(t, "rows\t%d\n", stats.Rows)
+	fmt.Fprintf(t, "file\t%s\n", store.path)
+	fmt.Fprintf(t, "bytes\t%d\n", stats.CompressedFileBytes)
+	return t.Flush()
+}
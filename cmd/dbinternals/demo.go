@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+	rle "github.com/rahil/database-internals/pkg/rle"
+)
+
+// demo walks through one codec on a handful of rows, printing what each call
+// returns:
+//
+//	delta  memory usage sampled every 2s, with checkpoints and size stats
+//	rle    a sorted string ts column with repeated timestamps
+
+var demos = map[string]func(){
+	"delta": demoDelta,
+	"rle":   demoRLE,
+}
+
+func demoDelta() {
+	de := deltaEncoding.InitDE(deltaEncoding.WithVerification(true))
+
+	// columns: id, value, ts.
+	// Assume we are storing memory usage for every 2 sec interval
+	de.AppendRow(deltaEncoding.Row{ID: 1, Value: 10737418240, TS: 1000})  // base 10 GB
+	de.AppendRow(deltaEncoding.Row{ID: 2, Value: 10747914240, TS: 1002})  // +10 MB (small increase)
+	de.AppendRow(deltaEncoding.Row{ID: 3, Value: 10758390272, TS: 1004})  // +10 MB (steady rise)
+	de.AppendRow(deltaEncoding.Row{ID: 4, Value: 10758390272, TS: 1006})  // 0 (plateau)
+	de.AppendRow(deltaEncoding.Row{ID: 5, Value: 10727939072, TS: 1008})  // -29 MB (dip)
+	de.AppendRow(deltaEncoding.Row{ID: 6, Value: 10821304320, TS: 1010})  // +88 MB (spike)
+	de.AppendRow(deltaEncoding.Row{ID: 7, Value: 10569646080, TS: 1012})  // -252 MB (drop)
+	de.AppendRow(deltaEncoding.Row{ID: 8, Value: 10580344320, TS: 1014})  // +10 MB (noise)
+	de.AppendRow(deltaEncoding.Row{ID: 9, Value: 10569646080, TS: 1016})  // -10 MB (noise)
+	de.AppendRow(deltaEncoding.Row{ID: 10, Value: 10569646080, TS: 1018}) // 0 (plateau again)
+
+	fmt.Println(de.ReconstructRow(1))
+	fmt.Println(de.ReconstructRow(3))
+	fmt.Println(de.ReconstructRow(5))
+	fmt.Println(de.ReconstructRow(10))
+
+	correct, err := de.VerifyDeltaEncodingCorrectness()
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Printf("\n\nIs delta encoding correct: %t\n", correct)
+
+	de.PrintStats()
+}
+
+func printCountOrError(count int, err error) {
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(count)
+	}
+}
+
+func demoRLE() {
+	rleInst := rle.InitRLE()
+
+	// columns: id, value, ts.
+	rleInst.AppendRow(rle.Row{ID: 1, Value: 100, TS: "10:00:00"})
+	rleInst.AppendRow(rle.Row{ID: 2, Value: 200, TS: "10:00:00"})
+	rleInst.AppendRow(rle.Row{ID: 3, Value: 300, TS: "10:00:02"})
+	rleInst.AppendRow(rle.Row{ID: 4, Value: 400, TS: "10:00:02"})
+	rleInst.AppendRow(rle.Row{ID: 5, Value: 500, TS: "10:00:02"})
+	rleInst.AppendRow(rle.Row{ID: 6, Value: 600, TS: "10:00:03"})
+
+	fmt.Println(rleInst.TSRuns)
+
+	fmt.Println(rleInst.ReconstructRow(1))
+	fmt.Println(rleInst.ReconstructRow(7))
+
+	fmt.Println(rleInst.GetTSFromRowID(1))
+	fmt.Println(rleInst.GetTSFromRowID(5))
+	fmt.Println(rleInst.GetTSFromRowID(6))
+
+	fmt.Println(rleInst.GetTSFromRowIDFaster(1))
+	fmt.Println(rleInst.GetTSFromRowIDFaster(5))
+	fmt.Println(rleInst.GetTSFromRowIDFaster(6))
+
+	printCountOrError(rleInst.GetCountofTS("10:00:00"))
+	printCountOrError(rleInst.GetCountofTS("10:00:01"))
+	printCountOrError(rleInst.GetCountofTSFaster("10:00:00"))
+	printCountOrError(rleInst.GetCountofTSFaster("10:00:01"))
+}
+
+func runDemo(args []string) error {
+	fs := newFlagSet("demo", "delta|rle")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("expected one codec, delta or rle")
+	}
+	demo, ok := demos[fs.Arg(0)]
+	if !ok {
+		return usageError("unknown codec %q, want delta or rle", fs.Arg(0))
+	}
+	demo()
+	return nil
+}
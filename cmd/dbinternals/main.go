@@ -0,0 +1,207 @@
+// dbinternals is the command line entry point for the codecs in this repository.
+// Every subcommand that works on a stored encoding opens it with the same
+// flags and prints its results as aligned tables.
+//
+// Usage:
+//
+//	go run ./cmd/dbinternals <command> [flags]
+//
+// Commands:
+//
+//	datagen  write a synthetic series as "value,ts" CSV lines
+//	load     encode "value,ts" CSV lines into a store file
+//	inspect  print the stats and checkpoint blocks of a store
+//	fsck     check checksums, decoding, ts order and id uniqueness
+//	compact  rewrite a store with a new checkpoint interval or compression
+//	serve    serve a store's rows and stats over HTTP as JSON
+//	bench    compare checkpoint intervals across synthetic series
+//	tsbs     bytes per point of each codec on TSBS devops data
+//	compare  sizes and point-query latency of every codec on one dataset
+//	scenario replay teaching scenarios and check their expectations
+//	demo     walk through the delta or RLE codec on a few rows
+//	repl     explore an encoding interactively
+//
+// Tools that once were binaries of their own, such as cmd/compare, are
+// subcommands here: go run ./cmd/dbinternals compare.
+//
+// Store flags, shared by load, inspect, fsck, compact, serve and repl:
+//
+//	-store path       the encoding file
+//	-compress codec   none or flate; must match the store when reading it
+//	-checksum name    crc32c, xxhash64 or sha256 for written files; defaults to
+//	                  the store's, crc32c for new stores
+//
+// For example:
+//
+//	go run ./cmd/dbinternals datagen -series spiky -rows 100000 > spiky.csv
+//	go run ./cmd/dbinternals load -store spiky.denc -interval 32 spiky.csv
+//	go run ./cmd/dbinternals inspect -store spiky.denc
+//
+// Errors go to stderr; the exit status is 2 for usage errors and 1 otherwise.
+
+package main
+
+import (
+	"compress/flate"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"datagen":  {"write a synthetic series as value,ts CSV lines", runDatagen},
+	"load":     {"encode value,ts CSV lines into a store file", runLoad},
+	"inspect":  {"print the stats and checkpoint blocks of a store", runInspect},
+	"fsck":     {"check checksums, decoding, ts order and id uniqueness", runFsck},
+	"compact":  {"rewrite a store with a new checkpoint interval or compression", runCompact},
+	"serve":    {"serve a store's rows and stats over HTTP as JSON", runServe},
+	"bench":    {"compare checkpoint intervals across synthetic series", runBench},
+	"tsbs":     {"bytes per point of each codec on TSBS devops data", runTSBS},
+	"compare":  {"sizes and point-query latency of every codec on one dataset", runCompare},
+	"scenario": {"replay teaching scenarios and check their expectations", runScenario},
+	"demo":     {"walk through the delta or RLE codec on a few rows", runDemo},
+	"repl":     {"explore an encoding interactively", runRepl},
+}
+
+// errUsage marks errors caused by how the command was invoked.
+var errUsage = errors.New("usage")
+
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: dbinternals <command> [flags]")
+	fmt.Fprintln(w)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	t := newTable(w)
+	for _, name := range names {
+		fmt.Fprintf(t, "  %s\t%s\n", name, commands[name].summary)
+	}
+	t.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run dbinternals <command> -h for the flags of a command.")
+}
+
+// newFlagSet returns a flag set for a subcommand that reports parse errors
+// instead of exiting, so main picks the exit status.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: dbinternals %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args into fs, turning parse errors into usage errors.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+// newTable returns the tab writer every command prints tables with.
+func newTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// storeFlags are the flags for opening and writing a store file.
+type storeFlags struct {
+	path     string
+	compress string
+	checksum string
+}
+
+func (s *storeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.path, "store", "", "encoding file")
+	fs.StringVar(&s.compress, "compress", "none", "block compression: none or flate")
+	fs.StringVar(&s.checksum, "checksum", "", "block checksum of written files: crc32c, xxhash64 or sha256 (default: the store's, crc32c for new stores)")
+}
+
+// options returns the encoding options selected by the flags.
+func (s *storeFlags) options() ([]deltaEncoding.Option, error) {
+	var opts []deltaEncoding.Option
+	switch s.compress {
+	case "none":
+	case "flate":
+		c, err := deltaEncoding.FlateCompression(flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, deltaEncoding.WithCompression(c))
+	default:
+		return nil, usageError("unknown -compress %q, want none or flate", s.compress)
+	}
+	// Files record their checksum, so loading needs none; an explicit one is
+	// what the loaded store is written back with.
+	switch s.checksum {
+	case "":
+	case "crc32c":
+		opts = append(opts, deltaEncoding.WithChecksum(deltaEncoding.CRC32C))
+	case "xxhash64":
+		opts = append(opts, deltaEncoding.WithChecksum(deltaEncoding.XXHash64))
+	case "sha256":
+		opts = append(opts, deltaEncoding.WithChecksum(deltaEncoding.SHA256))
+	default:
+		return nil, usageError("unknown -checksum %q, want crc32c, xxhash64 or sha256", s.checksum)
+	}
+	return opts, nil
+}
+
+// open loads the store named by -store.
+func (s *storeFlags) open(extra ...deltaEncoding.Option) (*deltaEncoding.DeltaEncoding, error) {
+	if s.path == "" {
+		return nil, usageError("-store is required")
+	}
+	opts, err := s.options()
+	if err != nil {
+		return nil, err
+	}
+	return deltaEncoding.LoadFromFile(s.path, append(opts, extra...)...)
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "dbinternals: unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	err := cmd.run(os.Args[2:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "dbinternals %s: %v\n", name, err)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "dbinternals %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+// repl is an interactive shell for exploring a delta-encoded column. With
+// -store it starts from the rows of a store file, and save writes the session
+// back to it; otherwise rows are kept in memory for the lifetime of the session.
+//
+// Commands:
+//   append <value> <ts>   append a row (ids are assigned sequentially)
+//   get <id>              reconstruct one row
+//   range <from> <to>     reconstruct rows from..to (inclusive)
+//   stats                 print compression statistics
+//   explain <id>          show the checkpoint and deltas used to decode a row
+//   inspect block <n>     dump one checkpoint block
+//   save                  write the rows to the -store file
+//   history               list previously entered commands
+//   help, quit
+
+const usage = `commands:
+  append <value> <ts>
+  get <id>
+  range <from> <to>
+  stats
+  explain <id>
+  inspect block <n>
+  save
+  history
+  help
+  quit`
+
+type repl struct {
+	de      *deltaEncoding.DeltaEncoding
+	store   string
+	history []string
+}
+
+func parseInts(args []string, want int) ([]int64, error) {
+	if len(args) != want {
+		return nil, fmt.Errorf("expected %d arguments, got %d", want, len(args))
+	}
+	nums := make([]int64, 0, want)
+	for _, arg := range args {
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", arg)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+func (r *repl) execute(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "append":
+		nums, err := parseInts(args, 2)
+		if err != nil {
+			return err
+		}
+		r.de.AppendRow(deltaEncoding.Row{ID: r.de.Len() + 1, Value: nums[0], TS: nums[1]})
+		fmt.Printf("appended row %d\n", r.de.Len())
+
+	case "get":
+		nums, err := parseInts(args, 1)
+		if err != nil {
+			return err
+		}
+		row, err := r.de.ReconstructRow(int(nums[0]))
+		if err != nil {
+			return err
+		}
+		fmt.Println(row)
+
+	case "range":
+		nums, err := parseInts(args, 2)
+		if err != nil {
+			return err
+		}
+		for id := int(nums[0]); id <= int(nums[1]); id++ {
+			row, err := r.de.ReconstructRow(id)
+			if err != nil {
+				return err
+			}
+			fmt.Println(row)
+		}
+
+	case "stats":
+		if r.de.Len() == 0 {
+			return fmt.Errorf("no rows appended yet")
+		}
+		r.de.PrintStats()
+		fmt.Println()
+
+	case "explain":
+		nums, err := parseInts(args, 1)
+		if err != nil {
+			return err
+		}
+		id := int(nums[0])
+		if id <= 0 || id > r.de.Len() {
+			return fmt.Errorf("%w: id %d", deltaEncoding.ErrRowNotFound, id)
+		}
+		block, err := r.de.Block((id - 1) / r.de.CheckpointInterval())
+		if err != nil {
+			return err
+		}
+		walked := id - block.FirstRowID + 1
+		fmt.Printf("block %d: start from checkpoint value=%d ts=%d\n", block.Index, block.CheckpointValue, block.CheckpointTS)
+		fmt.Printf("apply %d value deltas %v and ts deltas %v\n", walked, block.ValueDeltas[:walked], block.TSDeltas[:walked])
+
+	case "inspect":
+		if len(args) == 0 || args[0] != "block" {
+			return fmt.Errorf("usage: inspect block <n>")
+		}
+		nums, err := parseInts(args[1:], 1)
+		if err != nil {
+			return err
+		}
+		block, err := r.de.Block(int(nums[0]))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("block %d: rows %d..%d\n", block.Index, block.FirstRowID, block.LastRowID)
+		fmt.Printf("  checkpoint value=%d ts=%d\n", block.CheckpointValue, block.CheckpointTS)
+		fmt.Printf("  value deltas: %v\n", block.ValueDeltas)
+		fmt.Printf("  ts deltas:    %v\n", block.TSDeltas)
+
+	case "save":
+		if r.store == "" {
+			return fmt.Errorf("no store file, start the repl with -store")
+		}
+		if err := r.de.WriteToFile(r.store); err != nil {
+			return err
+		}
+		fmt.Printf("saved %d rows to %s\n", r.de.Len(), r.store)
+
+	case "history":
+		for i, entry := range r.history {
+			fmt.Printf("%4d  %s\n", i+1, entry)
+		}
+
+	case "help":
+		fmt.Println(usage)
+
+	default:
+		return fmt.Errorf("unknown command %q (try help)", cmd)
+	}
+	return nil
+}
+
+func runRepl(args []string) error {
+	fs := newFlagSet("repl", "")
+	var store storeFlags
+	store.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	r := &repl{store: store.path}
+	if store.path == "" {
+		r.de = deltaEncoding.InitDE()
+	} else {
+		de, err := store.open()
+		if errors.Is(err, os.ErrNotExist) {
+			opts, optErr := store.options()
+			if optErr != nil {
+				return optErr
+			}
+			de, err = deltaEncoding.NewDE(opts...)
+		}
+		if err != nil {
+			return err
+		}
+		r.de = de
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Println("delta-encoding repl, type help for commands")
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "quit" || line == "exit" {
+			return nil
+		}
+		if line != "" && line != "history" {
+			r.history = append(r.history, line)
+		}
+		if err := r.execute(line); err != nil {
+			fmt.Println("error:", err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+// scenario executes teaching scenarios against a delta-encoded column and
+// checks every expectation, so a chapter of the material can be replayed
+// exactly. The runner stops at the first failed step.
+//
+// Scenario format (one step per line, # starts a comment):
+//
+//	append <value> <ts>             append a row (ids are assigned sequentially)
+//	expect row <id> <value> <ts>    the row must reconstruct to value and ts
+//	expect missing <id>             the row must not exist
+//	expect rows <n>                 the encoding must hold n rows
+//	expect correct                  VerifyDeltaEncodingCorrectness must hold
+
+func runStep(de *deltaEncoding.DeltaEncoding, fields []string) error {
+	switch {
+	case fields[0] == "append":
+		nums, err := parseInts(fields[1:], 2)
+		if err != nil {
+			return err
+		}
+		de.AppendRow(deltaEncoding.Row{ID: de.Len() + 1, Value: nums[0], TS: nums[1]})
+
+	case fields[0] == "expect" && len(fields) > 1 && fields[1] == "row":
+		nums, err := parseInts(fields[2:], 3)
+		if err != nil {
+			return err
+		}
+		row, err := de.ReconstructRow(int(nums[0]))
+		if err != nil {
+			return err
+		}
+		if row.Value != nums[1] || row.TS != nums[2] {
+			return fmt.Errorf("got value=%d ts=%d, want value=%d ts=%d", row.Value, row.TS, nums[1], nums[2])
+		}
+
+	case fields[0] == "expect" && len(fields) > 1 && fields[1] == "missing":
+		nums, err := parseInts(fields[2:], 1)
+		if err != nil {
+			return err
+		}
+		if _, err := de.ReconstructRow(int(nums[0])); err == nil {
+			return fmt.Errorf("row %d exists", nums[0])
+		}
+
+	case fields[0] == "expect" && len(fields) > 1 && fields[1] == "rows":
+		nums, err := parseInts(fields[2:], 1)
+		if err != nil {
+			return err
+		}
+		if int64(de.Len()) != nums[0] {
+			return fmt.Errorf("got %d rows, want %d", de.Len(), nums[0])
+		}
+
+	case fields[0] == "expect" && len(fields) == 2 && fields[1] == "correct":
+		correct, err := de.VerifyDeltaEncodingCorrectness()
+		if err != nil {
+			return err
+		}
+		if !correct {
+			return fmt.Errorf("reconstruction does not match the appended rows")
+		}
+
+	default:
+		return fmt.Errorf("unknown step %q", strings.Join(fields, " "))
+	}
+	return nil
+}
+
+// runScenarioFile runs the steps of one scenario file and returns the number
+// that passed.
+func runScenarioFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	de := deltaEncoding.InitDE(deltaEncoding.WithVerification(true))
+	scanner := bufio.NewScanner(file)
+	steps := 0
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := runStep(de, fields); err != nil {
+			return steps, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		steps++
+	}
+	return steps, scanner.Err()
+}
+
+func runScenario(args []string) error {
+	fs := newFlagSet("scenario", "file...")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("expected at least one scenario file")
+	}
+	t := newTable(os.Stdout)
+	defer t.Flush()
+	for _, path := range fs.Args() {
+		steps, err := runScenarioFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(t, "%s\t%d steps passed\n", path, steps)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+// serve exposes a store read-only:
+//
+//	GET /stats                       size breakdown as JSON
+//	GET /rows?from=1&to=100          rows from..to (inclusive) as JSON
+//	GET /column?col=value&from=&to=  one column of rows from..to as JSON
+//
+// With stats=1, /rows and /column answer {"result": ..., "stats": ...} and set
+// X-Query-* headers with the rows scanned and returned, blocks scanned and
+// pruned, bytes decoded and the wall time of the plan and decode steps.
+
+type storeServer struct {
+	sealed *deltaEncoding.Sealed
+	stats  deltaEncoding.EncodingStats
+}
+
+// idRange parses the from and to query parameters, defaulting to every row.
+func (s *storeServer) idRange(r *http.Request) (from, to int, err error) {
+	from, to = 1, s.sealed.Len()
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid from %q", v)
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid to %q", v)
+		}
+	}
+	return from, to, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any, err error) {
+	switch {
+	case errors.Is(err, deltaEncoding.ErrRowNotFound), errors.Is(err, deltaEncoding.ErrEmptyEncoding):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *storeServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.stats, nil)
+}
+
+func (s *storeServer) handleRows(w http.ResponseWriter, r *http.Request) {
+	s.query(w, r, "", func(from, to int) (any, error) {
+		return s.sealed.ReconstructRange(from, to)
+	})
+}
+
+func (s *storeServer) handleColumn(w http.ResponseWriter, r *http.Request) {
+	col := r.URL.Query().Get("col")
+	s.query(w, r, col, func(from, to int) (any, error) {
+		return s.sealed.ReconstructColumn(col, from, to)
+	})
+}
+
+// queryStats is the stats trailer of a query response.
+type queryStats struct {
+	deltaEncoding.QueryStats
+	Timings map[string]string // wall time per step
+}
+
+// query answers a range query over col, or over whole rows when col is empty,
+// with decode. It times each step and attaches the stats if they were asked
+// for.
+func (s *storeServer) query(w http.ResponseWriter, r *http.Request, col string, decode func(from, to int) (any, error)) {
+	start := time.Now()
+	from, to, err := s.idRange(r)
+	if err != nil {
+		writeJSON(w, nil, err)
+		return
+	}
+	if r.URL.Query().Get("stats") != "1" {
+		result, err := decode(from, to)
+		writeJSON(w, result, err)
+		return
+	}
+	stats := queryStats{Timings: map[string]string{}}
+	if stats.QueryStats, err = s.sealed.QueryStats(col, from, to); err != nil {
+		writeJSON(w, nil, err)
+		return
+	}
+	planned := time.Now()
+	result, err := decode(from, to)
+	if err != nil {
+		writeJSON(w, nil, err)
+		return
+	}
+	decoded := time.Since(planned)
+	stats.Timings["plan"] = planned.Sub(start).String()
+	stats.Timings["decode"] = decoded.String()
+
+	h := w.Header()
+	h.Set("X-Query-Rows-Scanned", strconv.Itoa(stats.RowsScanned))
+	h.Set("X-Query-Rows-Returned", strconv.Itoa(stats.RowsReturned))
+	h.Set("X-Query-Blocks-Scanned", strconv.Itoa(stats.BlocksScanned))
+	h.Set("X-Query-Blocks-Pruned", strconv.Itoa(stats.BlocksPruned))
+	h.Set("X-Query-Bytes-Decoded", strconv.Itoa(stats.BytesDecoded))
+	h.Set("X-Query-Plan-Time", stats.Timings["plan"])
+	h.Set("X-Query-Decode-Time", stats.Timings["decode"])
+	writeJSON(w, map[string]any{"result": result, "stats": stats}, nil)
+}
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "")
+	var store storeFlags
+	store.register(fs)
+	addr := fs.String("addr", "localhost:8080", "listen address")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	de, err := store.open()
+	if err != nil {
+		return err
+	}
+	// The store is read-only while served, so a sealed copy answers every
+	// request without locking.
+	s := &storeServer{sealed: de.Seal(), stats: de.Stats()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", s.handleStats)
+	mux.HandleFunc("GET /rows", s.handleRows)
+	mux.HandleFunc("GET /column", s.handleColumn)
+
+	log.Printf("serving %s (%d rows) on http://%s", store.path, s.sealed.Len(), *addr)
+	return http.ListenAndServe(*addr, mux)
+}
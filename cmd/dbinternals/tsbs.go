@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+	"github.com/rahil/database-internals/pkg/gorilla"
+)
+
+// tsbs encodes data generated by the Time Series Benchmark Suite in InfluxDB
+// line protocol, as written by
+//
+//	tsbs_generate_data -use-case devops -scale 10 -format influx > devops.txt
+//
+// and prints the bytes per point each codec needs, the unit TSBS results and
+// the Gorilla paper (1.37 bytes per point) are published in. Every field of
+// every series (measurement plus tag set) is one column of value and ts; ids
+// are not counted since the points carry none. Escaped spaces and commas in
+// keys are not supported, and string and boolean fields are skipped.
+
+// publishedGorillaBytesPerPoint is the figure reported in "Gorilla: A Fast,
+// Scalable, In-Memory Time Series Database" (VLDB 2015).
+const publishedGorillaBytesPerPoint = 1.37
+
+// tsbsColumn is one field of one series.
+type tsbsColumn struct {
+	measurement string
+	ints        bool // every value had the integer suffix
+	intValues   []int64
+	values      []float64
+	ts          []int64
+}
+
+type tsbsDataset struct {
+	columns map[string]*tsbsColumn // by series key and field
+	order   []string
+	skipped int
+}
+
+// readLineProtocol parses lines of
+// "measurement[,tag=value...] field=value[,field=value...] timestamp".
+func readLineProtocol(r io.Reader, name string, measurements map[string]bool, limit int) (*tsbsDataset, error) {
+	d := &tsbsDataset{columns: map[string]*tsbsColumn{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan() && (limit == 0 || lineNo <= limit); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		first, last := strings.IndexByte(line, ' '), strings.LastIndexByte(line, ' ')
+		if first < 0 || first == last {
+			return nil, fmt.Errorf("%s:%d: expected series, fields and timestamp", name, lineNo)
+		}
+		series, fields, tsField := line[:first], line[first+1:last], line[last+1:]
+		measurement, _, _ := strings.Cut(series, ",")
+		if measurements != nil && !measurements[measurement] {
+			continue
+		}
+		ts, err := strconv.ParseInt(tsField, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+		}
+		for _, field := range strings.Split(fields, ",") {
+			key, raw, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: field %q has no value", name, lineNo, field)
+			}
+			var intValue int64
+			var value float64
+			isInt := strings.HasSuffix(raw, "i") || strings.HasSuffix(raw, "u")
+			if isInt {
+				intValue, err = strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+				value = float64(intValue)
+			} else {
+				value, err = strconv.ParseFloat(raw, 64)
+			}
+			if err != nil {
+				// Strings and booleans have no numeric encoding here.
+				d.skipped++
+				continue
+			}
+			id := series + " " + key
+			col, ok := d.columns[id]
+			if !ok {
+				col = &tsbsColumn{measurement: measurement, ints: true}
+				d.columns[id] = col
+				d.order = append(d.order, id)
+			}
+			col.ints = col.ints && isInt
+			if isInt {
+				col.intValues = append(col.intValues, intValue)
+			}
+			col.values = append(col.values, value)
+			col.ts = append(col.ts, ts)
+		}
+	}
+	return d, scanner.Err()
+}
+
+// tsbsSizes holds the encoded bytes of a set of columns under each codec.
+type tsbsSizes struct {
+	series  map[string]bool
+	columns int
+	points  int
+	delta   int
+	gorilla int
+}
+
+// deltaBytes encodes values and ts with the delta encoding and returns the size
+// of the value and ts columns and checkpoints, with ts in whichever of delta or
+// delta-of-delta is smaller.
+func deltaBytes[T deltaEncoding.Numeric](values []T, ts []int64, interval int) (int, error) {
+	de, err := deltaEncoding.NewDEOf[T](deltaEncoding.WithCheckpointInterval(interval))
+	if err != nil {
+		return 0, err
+	}
+	for i, v := range values {
+		de.AppendRow(deltaEncoding.RowOf[T]{ID: i + 1, Value: v, TS: ts[i]})
+	}
+	stats := de.Stats()
+	return stats.ValueBytes + min(stats.TSDeltaBytes, stats.TSDeltaOfDeltaBytes) + stats.CheckpointBytes, nil
+}
+
+// gorillaBytes encodes values with Gorilla's XOR compression and ts as varint
+// delta-of-deltas, the scheme of the paper.
+func gorillaBytes(values []float64, ts []int64) int {
+	col := gorilla.InitColumn()
+	for _, v := range values {
+		col.Append(v)
+	}
+	buf := []byte{}
+	var prev, prevDelta int64
+	for i, t := range ts {
+		if i == 0 {
+			buf = binary.AppendVarint(buf, t)
+		} else {
+			buf = binary.AppendVarint(buf, t-prev-prevDelta)
+			prevDelta = t - prev
+		}
+		prev = t
+	}
+	return col.Stats().EncodedBytes + len(buf)
+}
+
+// encodeColumn returns the delta and Gorilla sizes of col.
+func encodeColumn(col *tsbsColumn, interval int) (deltaSize, gorillaSize int, err error) {
+	if col.ints {
+		deltaSize, err = deltaBytes(col.intValues, col.ts, interval)
+	} else {
+		deltaSize, err = deltaBytes(col.values, col.ts, interval)
+	}
+	return deltaSize, gorillaBytes(col.values, col.ts), err
+}
+
+func (s *tsbsSizes) add(series string, points, deltaSize, gorillaSize int) {
+	s.series[series] = true
+	s.columns++
+	s.points += points
+	s.delta += deltaSize
+	s.gorilla += gorillaSize
+}
+
+func perPoint(bytes, points int) string {
+	if points == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", float64(bytes)/float64(points))
+}
+
+func runTSBS(args []string) error {
+	fs := newFlagSet("tsbs", "[devops.txt[.gz]]")
+	measurementList := fs.String("measurements", "", "comma-separated measurements to encode (default: all)")
+	limit := fs.Int("limit", 0, "lines to read, 0 for all")
+	interval := fs.Int("interval", 32, "checkpoint interval of the delta encoding")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("expected at most one input file, got %d", fs.NArg())
+	}
+	if *interval < 1 || *limit < 0 {
+		return usageError("-interval must be >= 1 and -limit >= 0")
+	}
+	var measurements map[string]bool
+	if *measurementList != "" {
+		measurements = map[string]bool{}
+		for _, m := range strings.Split(*measurementList, ",") {
+			measurements[strings.TrimSpace(m)] = true
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	name := "stdin"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+		if strings.HasSuffix(name, ".gz") {
+			gz, err := gzip.NewReader(file)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			in = gz
+		}
+	}
+	d, err := readLineProtocol(in, name, measurements, *limit)
+	if err != nil {
+		return err
+	}
+
+	byMeasurement := map[string]*tsbsSizes{}
+	total := &tsbsSizes{series: map[string]bool{}}
+	for _, key := range d.order {
+		col := d.columns[key]
+		sizes, ok := byMeasurement[col.measurement]
+		if !ok {
+			sizes = &tsbsSizes{series: map[string]bool{}}
+			byMeasurement[col.measurement] = sizes
+		}
+		deltaSize, gorillaSize, err := encodeColumn(col, *interval)
+		if err != nil {
+			return err
+		}
+		series, _, _ := strings.Cut(key, " ")
+		sizes.add(series, len(col.values), deltaSize, gorillaSize)
+		total.add(series, len(col.values), deltaSize, gorillaSize)
+	}
+	names := make([]string, 0, len(byMeasurement))
+	for m := range byMeasurement {
+		names = append(names, m)
+	}
+	slices.Sort(names)
+
+	t := newTable(os.Stdout)
+	fmt.Fprintf(t, "measurement\tseries\tcolumns\tpoints\tplain B/pt\tdelta B/pt\tgorilla B/pt\n")
+	row := func(name string, s *tsbsSizes) {
+		fmt.Fprintf(t, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n", name, len(s.series), s.columns, s.points,
+			perPoint(16*s.points, s.points), perPoint(s.delta, s.points), perPoint(s.gorilla, s.points))
+	}
+	for _, m := range names {
+		row(m, byMeasurement[m])
+	}
+	row("total", total)
+	if err := t.Flush(); err != nil {
+		return err
+	}
+	if d.skipped > 0 {
+		fmt.Printf("\nskipped %d non-numeric field values\n", d.skipped)
+	}
+	fmt.Printf("\npublished: Gorilla at Facebook, %.2f B/pt\n", publishedGorillaBytesPerPoint)
+	return nil
+}
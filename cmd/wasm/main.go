@@ -0,0 +1,73 @@
+//go:build js && wasm
+
+// This program exposes the codecs to JavaScript so the encoding visualizations can
+// run in a browser. Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o codecs.wasm ./cmd/wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+//
+// Exposed functions (all take and return plain JS arrays/objects):
+//   - deltaEncode(values, timestamps) -> {rows, correct}
+//   - rleEncode(timestamps)           -> [{ts, count}, ...]
+
+package main
+
+import (
+	"syscall/js"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+	rle "github.com/rahil/database-internals/pkg/rle"
+)
+
+func deltaEncode(this js.Value, args []js.Value) any {
+	if len(args) != 2 || args[0].Length() != args[1].Length() {
+		return js.ValueOf(map[string]any{"error": "deltaEncode expects two arrays of equal length"})
+	}
+	values, timestamps := args[0], args[1]
+
+	de := deltaEncoding.InitDE(deltaEncoding.WithVerification(true))
+	for i := range values.Length() {
+		de.AppendRow(deltaEncoding.Row{
+			ID:    i + 1,
+			Value: int64(values.Index(i).Float()),
+			TS:    int64(timestamps.Index(i).Float()),
+		})
+	}
+
+	rows := []any{}
+	for row := range de.All() {
+		rows = append(rows, map[string]any{"id": row.ID, "value": row.Value, "ts": row.TS})
+	}
+	correct, _ := de.VerifyDeltaEncodingCorrectness()
+	return js.ValueOf(map[string]any{
+		"rows":    rows,
+		"correct": correct,
+	})
+}
+
+func rleEncode(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return js.ValueOf(map[string]any{"error": "rleEncode expects one array"})
+	}
+	timestamps := args[0]
+
+	rleInst := rle.InitRLE()
+	for i := range timestamps.Length() {
+		rleInst.AppendRow(rle.Row{ID: i + 1, TS: timestamps.Index(i).String()})
+	}
+
+	runs := []any{}
+	for _, run := range rleInst.TSRuns {
+		runs = append(runs, map[string]any{"ts": run.TS(), "count": run.Count()})
+	}
+	return js.ValueOf(runs)
+}
+
+func main() {
+	js.Global().Set("deltaEncode", js.FuncOf(deltaEncode))
+	js.Global().Set("rleEncode", js.FuncOf(rleEncode))
+
+	// Keep the Go runtime alive so the exported functions stay callable.
+	select {}
+}
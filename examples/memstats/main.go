@@ -0,0 +1,117 @@
+// This program embeds a delta encoding in a small web app. It samples the Go
+// runtime's heap usage every second, stores it delta-encoded, and serves it back:
+//
+//	go run ./examples/memstats -addr :8080
+//
+//	GET /          SVG line chart of the last -window samples
+//	GET /data      the same samples as JSON
+//	GET /stats     row count and compression summary as JSON
+//
+// Heap usage moves in small steps between samples, which is exactly the shape
+// delta encoding is designed for.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	deltaEncoding "github.com/rahil/database-internals/pkg/delta-encoding"
+)
+
+type metricsStore struct {
+	mu sync.RWMutex
+	de *deltaEncoding.DeltaEncoding
+}
+
+func (s *metricsStore) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.de.AppendRow(deltaEncoding.Row{ID: s.de.Len() + 1, Value: int64(m.HeapAlloc), TS: time.Now().UnixMilli()})
+}
+
+// last returns up to n of the most recent rows.
+func (s *metricsStore) last(n int) []deltaEncoding.Row {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.de.Len() == 0 {
+		return nil
+	}
+	rows, _ := s.de.ReconstructRange(max(1, s.de.Len()-n+1), s.de.Len())
+	return rows
+}
+
+func (s *metricsStore) handleData(window int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.last(window))
+	}
+}
+
+func (s *metricsStore) handleStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	rows := s.de.Len()
+	blocks := s.de.NumBlocks()
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"rows": rows, "blocks": blocks})
+}
+
+func (s *metricsStore) handleChart(window int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const width, height = 600, 200
+		rows := s.last(window)
+
+		w.Header().Set("Content-Type", "image/svg+xml")
+		fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, width, height)
+		if len(rows) > 1 {
+			lo, hi := rows[0].Value, rows[0].Value
+			for _, row := range rows {
+				lo, hi = min(lo, row.Value), max(hi, row.Value)
+			}
+			spread := float64(max(hi-lo, 1))
+			points := []string{}
+			for i, row := range rows {
+				x := float64(i) * width / float64(len(rows)-1)
+				y := height - float64(row.Value-lo)*height/spread
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+			}
+			fmt.Fprintf(w, `<polyline fill="none" stroke="steelblue" points="%s"/>`, strings.Join(points, " "))
+			fmt.Fprintf(w, `<text x="4" y="14" font-size="12">heap %d..%d bytes</text>`, lo, hi)
+		}
+		fmt.Fprint(w, `</svg>`)
+	}
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	interval := flag.Duration("interval", time.Second, "sampling interval")
+	window := flag.Int("window", 300, "number of recent samples to serve")
+	flag.Parse()
+
+	store := &metricsStore{de: deltaEncoding.InitDE(deltaEncoding.WithCheckpointInterval(64))}
+	store.sample()
+	go func() {
+		for range time.Tick(*interval) {
+			store.sample()
+		}
+	}()
+
+	http.HandleFunc("/", store.handleChart(*window))
+	http.HandleFunc("/data", store.handleData(*window))
+	http.HandleFunc("/stats", store.handleStats)
+
+	log.Printf("serving heap usage chart on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
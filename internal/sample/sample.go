@@ -0,0 +1,33 @@
+// Package sample draws reproducible random samples for the codecs' sampling
+// APIs.
+package sample
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Indexes picks min(n, total) distinct indexes in [0, total) using Floyd's
+// algorithm and returns them sorted. The same seed always yields the same
+// indexes.
+// time complexity: O(n log n)
+func Indexes(total, n int, seed int64) []int {
+	if n > total {
+		n = total
+	}
+	r := rand.New(rand.NewSource(seed))
+	chosen := make(map[int]struct{}, n)
+	for j := total - n; j < total; j++ {
+		pick := r.Intn(j + 1)
+		if _, ok := chosen[pick]; ok {
+			pick = j
+		}
+		chosen[pick] = struct{}{}
+	}
+	indexes := make([]int, 0, n)
+	for index := range chosen {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
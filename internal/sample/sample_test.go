@@ -0,0 +1,24 @@
+package sample
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexes(t *testing.T) {
+	indexes := Indexes(100, 10, 42)
+	require.Len(t, indexes, 10)
+	for i, index := range indexes {
+		require.GreaterOrEqual(t, index, 0)
+		require.Less(t, index, 100)
+		if i > 0 {
+			require.Less(t, indexes[i-1], index)
+		}
+	}
+	require.Equal(t, indexes, Indexes(100, 10, 42))
+
+	require.Equal(t, []int{0, 1, 2}, Indexes(3, 5, 1))
+	require.Empty(t, Indexes(3, 0, 1))
+	require.Empty(t, Indexes(0, 2, 1))
+}
@@ -0,0 +1,134 @@
+package delta_encoding
+
+import "fmt"
+
+// adaptEvery is how many point lookups the adaptive mode observes before it
+// reconsiders the checkpoint interval.
+const adaptEvery = 256
+
+// DecodeStats records how many deltas point lookups had to walk from their
+// checkpoint, which is the reconstruction cost the checkpoint interval bounds.
+type DecodeStats struct {
+	Lookups      int
+	DeltasWalked int
+	MaxWalk      int
+}
+
+// AvgWalk returns the average number of deltas walked per lookup.
+func (s DecodeStats) AvgWalk() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.DeltasWalked) / float64(s.Lookups)
+}
+
+// WithAdaptiveCheckpoints re-checkpoints the encoding every few hundred point
+// lookups so that the observed average decode walk stays near targetAvgWalk.
+// Workloads that keep hitting the end of blocks get denser checkpoints; ones
+// that mostly read near checkpoints get sparser, smaller encodings.
+func WithAdaptiveCheckpoints(targetAvgWalk float64) Option {
+	return func(cfg *config) error {
+		if targetAvgWalk < 1 {
+			return fmt.Errorf("target decode walk must be >= 1, got %g", targetAvgWalk)
+		}
+		cfg.adaptiveTarget = targetAvgWalk
+		return nil
+	}
+}
+
+// DecodeStats returns the decode path lengths recorded by ReconstructRow.
+func (de *DeltaEncodingOf[T]) DecodeStats() DecodeStats {
+	de.statsMu.Lock()
+	defer de.statsMu.Unlock()
+	return de.decodeStats
+}
+
+// ResetDecodeStats clears the recorded decode path lengths.
+func (de *DeltaEncodingOf[T]) ResetDecodeStats() {
+	de.statsMu.Lock()
+	defer de.statsMu.Unlock()
+	de.decodeStats = DecodeStats{}
+}
+
+// recordWalk records one point lookup and, in adaptive mode, re-checkpoints
+// once enough lookups have been observed.
+func (de *DeltaEncodingOf[T]) recordWalk(walked int) {
+	de.statsMu.Lock()
+	de.decodeStats.Lookups++
+	de.decodeStats.DeltasWalked += walked
+	de.decodeStats.MaxWalk = max(de.decodeStats.MaxWalk, walked)
+	lookups := de.decodeStats.Lookups
+	de.statsMu.Unlock()
+
+	if de.adaptiveTarget == 0 || lookups < adaptEvery {
+		return
+	}
+	if interval := de.SuggestCheckpointInterval(de.adaptiveTarget); interval != de.checkpointInterval {
+		if de.logger != nil {
+			de.logger.Debug("adapting checkpoint interval", "from", de.checkpointInterval, "to", interval, "avgWalk", de.DecodeStats().AvgWalk())
+		}
+		_ = de.Recheckpoint(interval)
+	}
+	de.ResetDecodeStats()
+}
+
+// SuggestCheckpointInterval scales the current interval by how far the recorded
+// average decode walk is from targetAvgWalk. Without recorded lookups it assumes
+// uniform access, where the average walk is about half the interval.
+func (de *DeltaEncodingOf[T]) SuggestCheckpointInterval(targetAvgWalk float64) int {
+	avgWalk := de.DecodeStats().AvgWalk()
+	if avgWalk == 0 {
+		avgWalk = float64(de.checkpointInterval+1) / 2
+	}
+	return max(1, int(float64(de.checkpointInterval)*targetAvgWalk/avgWalk))
+}
+
+// Recheckpoint rebuilds all checkpoints (and per-block value samples) for a new
+// interval. The delta streams themselves do not depend on the interval and are
+// left untouched.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) Recheckpoint(interval int) error {
+	if interval < 1 {
+		return fmt.Errorf("checkpoint interval must be >= 1, got %d", interval)
+	}
+	rows := make([]RowOf[T], 0, len(de.idList))
+	for _, row := range de.allRows() {
+		rows = append(rows, row)
+	}
+	de.checkpointInterval = interval
+	de.rebuildCheckpoints(rows)
+	return nil
+}
+
+// rebuildCheckpoints recomputes checkpoints and value samples from decoded rows,
+// using the same placement as AppendRow.
+func (de *DeltaEncodingOf[T]) rebuildCheckpoints(rows []RowOf[T]) {
+	de.checkpointValues = de.checkpointValues[:0]
+	de.checkpointTs = de.checkpointTs[:0]
+	de.checkpointTsDeltas = de.checkpointTsDeltas[:0]
+	de.blockSamples = nil
+
+	var chain T
+	for rowIndex, row := range rows {
+		// Null and non-finite values are not part of the chain; the checkpoint
+		// holds the chain value instead.
+		if !de.nulls.get(rowIndex) && isFinite(row.Value) {
+			chain = row.Value
+			de.sampleValue(rowIndex, row.Value)
+		}
+		row.Value = chain
+		tsDelta := int64(0)
+		if rowIndex == 0 {
+			de.checkpointValues = append(de.checkpointValues, row.Value)
+			de.checkpointTs = append(de.checkpointTs, row.TS)
+			de.checkpointTsDeltas = append(de.checkpointTsDeltas, 0)
+		} else {
+			tsDelta = row.TS - rows[rowIndex-1].TS
+		}
+		if (rowIndex+1)%de.checkpointInterval == 0 {
+			de.checkpointValues = append(de.checkpointValues, row.Value)
+			de.checkpointTs = append(de.checkpointTs, row.TS)
+			de.checkpointTsDeltas = append(de.checkpointTsDeltas, tsDelta)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package delta_encoding
+
+import "slices"
+
+// AddConstant adds c to every value. Deltas are differences, so only the
+// checkpoints and the chain's last value change. Null values stay null.
+// time complexity: O(n/checkpointInterval)
+func (de *DeltaEncodingOf[T]) AddConstant(c T) {
+	de.affine(1, c)
+}
+
+// Scale multiplies every value by factor, scaling the checkpoints and every
+// delta. For floats the results may differ from scaling the decoded values by
+// rounding. Null values stay null.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) Scale(factor T) {
+	de.affine(factor, 0)
+}
+
+// ShiftTime adds delta to every ts. Only the ts checkpoints change; a time
+// bucket index is shifted when delta is a multiple of its bucket size and
+// rebuilt from the ts column otherwise.
+// time complexity: O(n/checkpointInterval), O(n) to rebuild a time bucket index
+func (de *DeltaEncodingOf[T]) ShiftTime(delta int64) {
+	for i := range de.checkpointTs {
+		de.checkpointTs[i] += delta
+	}
+	if len(de.idList) > 0 {
+		de.lastTs += delta
+	}
+	for i := range de.originalRows {
+		de.originalRows[i].TS += delta
+	}
+	if buf := de.reorder; buf != nil {
+		for i := range buf.rows {
+			buf.rows[i].TS += delta
+		}
+		buf.maxTs += delta
+	}
+	if idx := de.timeIndex; idx != nil && !idx.broken {
+		if delta%idx.size == 0 {
+			for i := range idx.buckets {
+				idx.buckets[i] += delta / idx.size
+			}
+		} else {
+			de.rebuildTimeIndex()
+		}
+	}
+}
+
+// rebuildTimeIndex recomputes the time bucket index from the ts column.
+func (de *DeltaEncodingOf[T]) rebuildTimeIndex() {
+	idx := &timeBucketIndex{size: de.timeIndex.size}
+	c := de.newCursor()
+	for rowIndex := range de.idList {
+		idx.add(rowIndex, c.seek(rowIndex).TS)
+		// Deleted rows are encoded but not live; add just placed this one in
+		// the last bucket.
+		if de.tombstones.get(rowIndex) && !idx.broken {
+			idx.counts[len(idx.counts)-1]--
+		}
+	}
+	de.timeIndex = idx
+}
+
+// affine maps every value v to v*scale + offset in place: checkpoints map like
+// values and deltas only scale. Values outside the chain (non-finite values,
+// samples, retained originals and buffered rows) are mapped directly.
+func (de *DeltaEncodingOf[T]) affine(scale, offset T) {
+	f := func(v T) T { return v*scale + offset }
+	if scale != 1 {
+		for i := range de.deltaValueList {
+			de.deltaValueList[i] *= scale
+		}
+	}
+	for i := range de.checkpointValues {
+		de.checkpointValues[i] = f(de.checkpointValues[i])
+	}
+	if len(de.idList) > 0 {
+		de.lastValue = f(de.lastValue)
+	}
+	for i := range de.nonFinite.values {
+		de.nonFinite.values[i] = f(de.nonFinite.values[i])
+	}
+	for _, samples := range de.blockSamples {
+		for i := range samples {
+			samples[i] = f(samples[i])
+		}
+		// A negative scale reverses the order of each sample.
+		slices.Sort(samples)
+	}
+	for i := range de.originalRows {
+		if !de.nulls.get(i) {
+			de.originalRows[i].Value = f(de.originalRows[i].Value)
+		}
+	}
+	if de.reorder != nil {
+		for i := range de.reorder.rows {
+			de.reorder.rows[i].Value = f(de.reorder.rows[i].Value)
+		}
+	}
+}
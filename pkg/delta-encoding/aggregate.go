@@ -0,0 +1,59 @@
+package delta_encoding
+
+import "fmt"
+
+// Aggregates holds value aggregates over a row range.
+type Aggregates[T Numeric] struct {
+	Count int
+	Sum   T
+	Min   T
+	Max   T
+}
+
+// Aggregate computes count, sum, min and max of the values in rows
+// fromID..toID (inclusive) during a single decode pass. Deleted rows and null
+// values are skipped.
+// time complexity: O(k + checkpointInterval) for k rows
+func (de *DeltaEncodingOf[T]) Aggregate(fromID, toID int) (Aggregates[T], error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return Aggregates[T]{}, err
+	}
+	agg := Aggregates[T]{}
+	c := de.newCursor()
+	for rowIndex := from; rowIndex < to; rowIndex++ {
+		if de.tombstones.get(rowIndex) || de.nulls.get(rowIndex) {
+			continue
+		}
+		value := c.seek(rowIndex).Value
+		if de.nonFinitePolicy == SkipNonFinite && !isFinite(value) {
+			continue
+		}
+		if agg.Count == 0 {
+			agg.Min, agg.Max = value, value
+		}
+		agg.Count++
+		agg.Sum += value
+		agg.Min = min(agg.Min, value)
+		agg.Max = max(agg.Max, value)
+	}
+	return agg, nil
+}
+
+// SumValues returns the sum of the values in rows fromID..toID.
+func (de *DeltaEncodingOf[T]) SumValues(fromID, toID int) (T, error) {
+	agg, err := de.Aggregate(fromID, toID)
+	return agg.Sum, err
+}
+
+// MinMax returns the smallest and largest value in rows fromID..toID.
+func (de *DeltaEncodingOf[T]) MinMax(fromID, toID int) (T, T, error) {
+	agg, err := de.Aggregate(fromID, toID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if agg.Count == 0 {
+		return 0, 0, fmt.Errorf("no live rows in range [%d, %d]", fromID, toID)
+	}
+	return agg.Min, agg.Max, nil
+}
@@ -0,0 +1,44 @@
+package delta_encoding
+
+import (
+	"context"
+	"slices"
+)
+
+// grow reserves room for n more rows in every per-row and per-checkpoint slice.
+func (de *DeltaEncodingOf[T]) grow(n int) {
+	checkpoints := n/de.checkpointInterval + 1
+	de.idList = slices.Grow(de.idList, n)
+	de.deltaValueList = slices.Grow(de.deltaValueList, n)
+	de.deltaTsList = slices.Grow(de.deltaTsList, n)
+	de.checkpointValues = slices.Grow(de.checkpointValues, checkpoints)
+	de.checkpointTs = slices.Grow(de.checkpointTs, checkpoints)
+	de.checkpointTsDeltas = slices.Grow(de.checkpointTsDeltas, checkpoints)
+	if de.verify {
+		de.originalRows = slices.Grow(de.originalRows, n)
+	}
+}
+
+// AppendRows appends rows in order after growing the internal slices once, so
+// bulk loads avoid repeated reallocation.
+// time complexity: O(len(rows))
+func (de *DeltaEncodingOf[T]) AppendRows(rows []RowOf[T]) {
+	de.grow(len(rows))
+	for _, row := range rows {
+		de.AppendRow(row)
+	}
+}
+
+// AppendRowsContext appends rows in order until ctx is done. Rows appended before
+// the deadline stay committed; the returned count says exactly how many were
+// accepted, so the caller can resume from rows[accepted:].
+func (de *DeltaEncodingOf[T]) AppendRowsContext(ctx context.Context, rows []RowOf[T]) (int, error) {
+	de.grow(len(rows))
+	for accepted, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return accepted, err
+		}
+		de.AppendRow(row)
+	}
+	return len(rows), nil
+}
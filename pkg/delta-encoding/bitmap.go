@@ -0,0 +1,60 @@
+package delta_encoding
+
+import "math/bits"
+
+// bitmap is a growable set of row indexes, one bit per row.
+type bitmap []uint64
+
+func (b bitmap) get(index int) bool {
+	word := index / 64
+	return word < len(b) && b[word]&(1<<(index%64)) != 0
+}
+
+func (b *bitmap) set(index int) {
+	for len(*b) <= index/64 {
+		*b = append(*b, 0)
+	}
+	(*b)[index/64] |= 1 << (index % 64)
+}
+
+// rank returns the number of set bits below index.
+func (b bitmap) rank(index int) int {
+	count := 0
+	for word := 0; word < len(b) && word < index/64; word++ {
+		count += bits.OnesCount64(b[word])
+	}
+	if word := index / 64; word < len(b) {
+		count += bits.OnesCount64(b[word] & (1<<(index%64) - 1))
+	}
+	return count
+}
+
+// selectUnset returns the index of the k-th unset bit for every k in ranks,
+// which must be ascending.
+// time complexity: O(n/64 + len(ranks))
+func (b bitmap) selectUnset(ranks []int) []int {
+	indexes := make([]int, 0, len(ranks))
+	below := 0 // unset bits in the words before word
+	for word := 0; len(indexes) < len(ranks); word++ {
+		var w uint64
+		if word < len(b) {
+			w = b[word]
+		}
+		zeros := 64 - bits.OnesCount64(w)
+		for len(indexes) < len(ranks) && ranks[len(indexes)] < below+zeros {
+			unset := ^w
+			for range ranks[len(indexes)] - below {
+				unset &= unset - 1 // drop the lowest unset bit
+			}
+			indexes = append(indexes, word*64+bits.TrailingZeros64(unset))
+		}
+		below += zeros
+	}
+	return indexes
+}
+
+func (b bitmap) clear(index int) {
+	if word := index / 64; word < len(b) {
+		b[word] &^= 1 << (index % 64)
+	}
+}
@@ -0,0 +1,53 @@
+package delta_encoding
+
+import "fmt"
+
+// BlockSummaryOf describes one checkpoint block: the absolute checkpoint it is
+// decoded from and the deltas stored for its rows.
+type BlockSummaryOf[T Numeric] struct {
+	Index           int
+	FirstRowID      int
+	LastRowID       int
+	CheckpointValue T
+	CheckpointTS    int64
+	ValueDeltas     []T
+	TSDeltas        []int64 // second-order deltas in delta-of-delta mode
+}
+
+type BlockSummary = BlockSummaryOf[int64]
+
+// Len returns the number of encoded rows.
+func (de *DeltaEncodingOf[T]) Len() int {
+	return len(de.idList)
+}
+
+// CheckpointInterval returns the number of rows per checkpoint block.
+func (de *DeltaEncodingOf[T]) CheckpointInterval() int {
+	return de.checkpointInterval
+}
+
+// NumBlocks returns the number of checkpoint blocks holding at least one row.
+func (de *DeltaEncodingOf[T]) NumBlocks() int {
+	return (len(de.idList) + de.checkpointInterval - 1) / de.checkpointInterval
+}
+
+// Block returns the summary of the block at the given index.
+func (de *DeltaEncodingOf[T]) Block(index int) (BlockSummaryOf[T], error) {
+	if de.NumBlocks() == 0 {
+		return BlockSummaryOf[T]{}, ErrEmptyEncoding
+	}
+	if index < 0 || index >= de.NumBlocks() {
+		return BlockSummaryOf[T]{}, fmt.Errorf("block %d does not exist", index)
+	}
+	start := index * de.checkpointInterval
+	end := min(start+de.checkpointInterval, len(de.idList))
+	return BlockSummaryOf[T]{
+		Index:           index,
+		FirstRowID:      de.idList[start],
+		LastRowID:       de.idList[end-1],
+		CheckpointValue: de.checkpointValues[index],
+		CheckpointTS:    de.checkpointTs[index],
+		ValueDeltas:     append([]T{}, de.deltaValueList[start:end]...),
+		TSDeltas:        append([]int64{}, de.deltaTsList[start:end]...),
+	}, nil
+}
@@ -0,0 +1,274 @@
+package delta_encoding
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// BlockFileOf reads an encoding file a range of checkpoint blocks at a time.
+// Blocks are fixed-size pages of CheckpointInterval rows: each starts from its
+// own checkpoint and is stored in its own checksummed section, so a large
+// column can be loaded, verified and dropped page by page instead of as a
+// whole. Opening reads the header, the trailer (deletes, nulls, non-finite
+// values and exemplars) and the length prefix of every block, but no block
+// payload. With WithPageCache, Page keeps recently used pages decoded and
+// evicts the rest. LoadBlocks and Page are safe for concurrent use.
+type BlockFileOf[T Numeric] struct {
+	r        io.ReaderAt
+	closer   io.Closer // the file when opened by path
+	header   *fileHeader
+	sections []fileSection       // every block, then the trailer
+	stored   *DeltaEncodingOf[T] // the side columns of the trailer
+	opts     []Option
+
+	mu       sync.Mutex
+	maxPages int
+	lru      list.List             // cached pages, most recently used first
+	pages    map[int]*list.Element // page index to its element in lru
+}
+
+// cachedPage is one decoded page in the page cache.
+type cachedPage[T Numeric] struct {
+	index int
+	de    *DeltaEncodingOf[T]
+}
+
+type BlockFile = BlockFileOf[int64]
+
+// fileSection locates one section: its length prefix, payload and checksum.
+type fileSection struct {
+	offset int64
+	size   int
+}
+
+// OpenBlockFile opens an int64 encoding file written by WriteToFile for
+// loading by block.
+func OpenBlockFile(path string, opts ...Option) (*BlockFile, error) {
+	return OpenBlockFileOf[int64](path, opts...)
+}
+
+// OpenBlockFileOf opens an encoding file of T for loading by block. opts are
+// applied to every encoding LoadBlocks returns, as in LoadFromFileOf.
+func OpenBlockFileOf[T Numeric](path string, opts ...Option) (*BlockFileOf[T], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f, err := NewBlockFileOf[T](file, info.Size(), opts...)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f.closer = file
+	return f, nil
+}
+
+// NewBlockFileOf is OpenBlockFileOf for an encoding file of size bytes read
+// through r.
+// time complexity: O(blocks) small reads
+func NewBlockFileOf[T Numeric](r io.ReaderAt, size int64, opts ...Option) (*BlockFileOf[T], error) {
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	f := &BlockFileOf[T]{r: r, opts: opts, maxPages: cfg.pageCache, pages: map[int]*list.Element{}}
+
+	// The header section length follows the magic bytes and version.
+	prefix, err := f.readAt(0, int(min(size, int64(len(fileMagic)+1+binary.MaxVarintLen64))))
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(prefix)
+	if len(prefix) > len(fileMagic)+1 {
+		if n, width := binary.Uvarint(prefix[len(fileMagic)+1:]); width > 0 && n <= uint64(size) {
+			headerSize = int(min(size, int64(len(fileMagic)+1+width+int(n)+CRC32C.Size())))
+		}
+	}
+	data, err := f.readAt(0, headerSize)
+	if err != nil {
+		return nil, err
+	}
+	file := &fileReader{buf: data}
+	if f.header, err = readHeader[T](file, int(size), &cfg); err != nil {
+		return nil, err
+	}
+
+	offset := int64(headerSize - len(file.buf))
+	blocks := checkpointBlocks(f.header.rows, f.header.interval)
+	for k := range blocks + 1 {
+		name, index := "block", k
+		if k == blocks {
+			name, index = "trailer", 0
+		}
+		prefix, err := f.readAt(offset, int(min(size-offset, binary.MaxVarintLen64)))
+		if err != nil {
+			return nil, err
+		}
+		n, width := binary.Uvarint(prefix)
+		if width <= 0 || n > uint64(size-offset) || int64(width)+int64(n)+int64(f.header.checksum.Size()) > size-offset {
+			return nil, &CorruptionError{Section: name, Block: index, Truncated: true}
+		}
+		section := fileSection{offset: offset, size: width + int(n) + f.header.checksum.Size()}
+		f.sections = append(f.sections, section)
+		offset += int64(section.size)
+	}
+	if offset != size {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCorruptBlock, size-offset)
+	}
+
+	trailer, err := f.section(len(f.sections)-1, "trailer", 0)
+	if err != nil {
+		return nil, err
+	}
+	if f.stored, err = readTrailer[T](trailer, f.header); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// readAt reads n bytes at offset. Section sizes are checked against the file
+// size before they are read, so only the header can come up short.
+func (f *BlockFileOf[T]) readAt(offset int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := f.r.ReadAt(buf, offset)
+	if read == n {
+		// ReadAt may report io.EOF along with a full read at the end.
+		return buf, nil
+	}
+	if errors.Is(err, io.EOF) {
+		return nil, &CorruptionError{Section: "header", Truncated: true}
+	}
+	return nil, err
+}
+
+// section reads and verifies section i, named name and index for errors.
+func (f *BlockFileOf[T]) section(i int, name string, index int) (*fileReader, error) {
+	data, err := f.readAt(f.sections[i].offset, f.sections[i].size)
+	if err != nil {
+		return nil, err
+	}
+	return f.header.section(&fileReader{buf: data}, name, index)
+}
+
+// Len returns the number of rows in the file.
+func (f *BlockFileOf[T]) Len() int {
+	return f.header.rows
+}
+
+// CheckpointInterval returns the number of rows per block.
+func (f *BlockFileOf[T]) CheckpointInterval() int {
+	return f.header.interval
+}
+
+// NumBlocks returns the number of blocks holding at least one row.
+func (f *BlockFileOf[T]) NumBlocks() int {
+	return (f.header.rows + f.header.interval - 1) / f.header.interval
+}
+
+// LoadBlocks reads, verifies and decodes blocks [from, to) into a new encoding
+// that starts from the checkpoint of block from. Rows keep their ids, and
+// deleted rows, nulls, non-finite values and exemplars carry over. A corrupt
+// block only fails the loads that include it.
+// time complexity: O((to-from) * checkpointInterval)
+func (f *BlockFileOf[T]) LoadBlocks(from, to int) (*DeltaEncodingOf[T], error) {
+	if from < 0 || to > f.NumBlocks() || from > to {
+		return nil, fmt.Errorf("blocks [%d, %d) out of range of %d", from, to, f.NumBlocks())
+	}
+	first, _ := f.header.blockRows(from)
+	var rows []RowOf[T]
+	for k := from; k < to; k++ {
+		r, err := f.section(k, "block", k)
+		if err != nil {
+			return nil, err
+		}
+		if rows, err = readBlock(r, f.header, k, rows); err != nil {
+			return nil, err
+		}
+	}
+	de, err := newFileEncoding[T](f.header, f.opts)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		de.appendFrom(f.stored, first+i, f.stored.withSideValues(first+i, row))
+	}
+	return de, nil
+}
+
+// WithPageCache keeps up to pages decoded pages of a BlockFile in memory for
+// Page, evicting the least recently used one to make room for another.
+func WithPageCache(pages int) Option {
+	return func(cfg *config) error {
+		if pages < 1 {
+			return fmt.Errorf("page cache must hold >= 1 page, got %d", pages)
+		}
+		cfg.pageCache = pages
+		return nil
+	}
+}
+
+// Page returns block k decoded as LoadBlocks(k, k+1) does, from the page cache
+// if it holds it. Cached pages are shared between callers and must not be
+// modified. Without WithPageCache every call reads the page again.
+// time complexity: O(1) when cached, O(checkpointInterval) otherwise
+func (f *BlockFileOf[T]) Page(k int) (*DeltaEncodingOf[T], error) {
+	f.mu.Lock()
+	if e, ok := f.pages[k]; ok {
+		f.lru.MoveToFront(e)
+		f.mu.Unlock()
+		return e.Value.(*cachedPage[T]).de, nil
+	}
+	f.mu.Unlock()
+
+	// Read without the lock so one slow page does not hold up cached ones.
+	de, err := f.LoadBlocks(k, k+1)
+	if err != nil || f.maxPages == 0 {
+		return de, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.pages[k]; ok {
+		// Another caller loaded it meanwhile.
+		f.lru.MoveToFront(e)
+		return e.Value.(*cachedPage[T]).de, nil
+	}
+	f.pages[k] = f.lru.PushFront(&cachedPage[T]{index: k, de: de})
+	if f.lru.Len() > f.maxPages {
+		oldest := f.lru.Remove(f.lru.Back()).(*cachedPage[T])
+		delete(f.pages, oldest.index)
+		if f.stored.logger != nil {
+			f.stored.logger.Debug("evicted page", "block", oldest.index)
+		}
+	}
+	return de, nil
+}
+
+// CachedPages returns the indexes of the cached pages, most recently used
+// first.
+func (f *BlockFileOf[T]) CachedPages() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	indexes := make([]int, 0, f.lru.Len())
+	for e := f.lru.Front(); e != nil; e = e.Next() {
+		indexes = append(indexes, e.Value.(*cachedPage[T]).index)
+	}
+	return indexes
+}
+
+// Close closes the file when the BlockFile was opened by path.
+func (f *BlockFileOf[T]) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
@@ -0,0 +1,145 @@
+package delta_encoding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/bits"
+)
+
+// Checksum computes the check value stored after every block and trailer
+// section of an encoding file. Name is stored in the file header, so a reader
+// picks the verifier a file was written with; Size is the length in bytes of
+// every sum.
+type Checksum interface {
+	Name() string
+	Size() int
+	Append(dst, payload []byte) []byte
+}
+
+// The built-in checksums. CRC32C is the default: it is cheap and catches bit
+// rot and torn writes. XXHash64 is as fast with a wider sum, and SHA256 also
+// detects deliberate tampering at a much higher cost.
+var (
+	CRC32C   Checksum = crc32cChecksum{}
+	XXHash64 Checksum = xxHash64Checksum{}
+	SHA256   Checksum = sha256Checksum{}
+)
+
+// checksumByName returns the built-in checksum called name.
+func checksumByName(name string) (Checksum, bool) {
+	for _, c := range []Checksum{CRC32C, XXHash64, SHA256} {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// WithChecksum writes files with c instead of CRC32C. Files written with a
+// built-in checksum load without this option; loading a file written with a
+// custom one needs a checksum with the same Name, passed through this option.
+func WithChecksum(c Checksum) Option {
+	return func(cfg *config) error {
+		if c == nil {
+			return errors.New("checksum must not be nil")
+		}
+		cfg.checksum = c
+		return nil
+	}
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type crc32cChecksum struct{}
+
+func (crc32cChecksum) Name() string { return "crc32c" }
+func (crc32cChecksum) Size() int    { return 4 }
+
+func (crc32cChecksum) Append(dst, payload []byte) []byte {
+	return binary.LittleEndian.AppendUint32(dst, crc32.Checksum(payload, castagnoli))
+}
+
+type sha256Checksum struct{}
+
+func (sha256Checksum) Name() string { return "sha256" }
+func (sha256Checksum) Size() int    { return sha256.Size }
+
+func (sha256Checksum) Append(dst, payload []byte) []byte {
+	sum := sha256.Sum256(payload)
+	return append(dst, sum[:]...)
+}
+
+type xxHash64Checksum struct{}
+
+func (xxHash64Checksum) Name() string { return "xxhash64" }
+func (xxHash64Checksum) Size() int    { return 8 }
+
+func (xxHash64Checksum) Append(dst, payload []byte) []byte {
+	return binary.LittleEndian.AppendUint64(dst, xxHash64Sum(payload))
+}
+
+const (
+	xxPrime1 uint64 = 0x9E3779B185EBCA87
+	xxPrime2 uint64 = 0xC2B2AE3D27D4EB4F
+	xxPrime3 uint64 = 0x165667B19E3779F9
+	xxPrime4 uint64 = 0x85EBCA77C2B2AE63
+	xxPrime5 uint64 = 0x27D4EB2F165667C5
+)
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	return bits.RotateLeft64(acc, 31) * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// xxHash64Sum is XXH64 with seed 0, as specified at
+// https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md.
+// time complexity: O(n)
+func xxHash64Sum(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		// Non-constant so the seed arithmetic wraps instead of overflowing.
+		p1 := xxPrime1
+		v1, v2, v3, v4 := p1+xxPrime2, xxPrime2, uint64(0), -p1
+		for ; len(b) >= 32; b = b[32:] {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b[0:]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = xxPrime5
+	}
+	h += uint64(n)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
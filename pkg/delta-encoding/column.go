@@ -0,0 +1,141 @@
+package delta_encoding
+
+import "fmt"
+
+// Column names accepted by ReconstructColumn.
+const (
+	ColumnID    = "id"
+	ColumnValue = "value"
+	ColumnTS    = "ts"
+)
+
+// ColumnOf holds one column decoded by ReconstructColumn. Only the slice of the
+// requested column is set.
+type ColumnOf[T Numeric] struct {
+	IDs    []int
+	Values []T
+	TS     []int64
+}
+
+type Column = ColumnOf[int64]
+
+// ReconstructColumn decodes a single column of rows fromID..toID (inclusive),
+// walking only that column's deltas from the nearest checkpoint, so a query
+// that needs just the values skips the ts stream and vice versa. Deleted rows
+// are skipped, as in ReconstructRange.
+// time complexity: O(k + checkpointInterval) for k rows
+func (de *DeltaEncodingOf[T]) ReconstructColumn(col string, fromID, toID int) (ColumnOf[T], error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return ColumnOf[T]{}, err
+	}
+	var column ColumnOf[T]
+	switch col {
+	case ColumnID:
+		column.IDs = make([]int, to-from)
+		column.IDs = column.IDs[:de.decodeIDs(column.IDs, from, to)]
+	case ColumnValue:
+		column.Values = make([]T, to-from)
+		column.Values = column.Values[:de.decodeValues(column.Values, from, to)]
+	case ColumnTS:
+		column.TS = make([]int64, to-from)
+		column.TS = column.TS[:de.decodeTS(column.TS, from, to)]
+	default:
+		return ColumnOf[T]{}, fmt.Errorf("unknown column %q, want %q, %q or %q", col, ColumnID, ColumnValue, ColumnTS)
+	}
+	return column, nil
+}
+
+// DecodeValuesInto decodes the values of rows fromID..toID (inclusive) into dst
+// and returns how many it wrote, without allocating, for hot query paths that
+// reuse one buffer across calls. dst must have room for every row in the range;
+// deleted rows are skipped, so fewer values may be written.
+// time complexity: O(k + checkpointInterval) for k rows
+func (de *DeltaEncodingOf[T]) DecodeValuesInto(dst []T, fromID, toID int) (int, error) {
+	from, to, err := de.intoRange(len(dst), fromID, toID)
+	if err != nil {
+		return 0, err
+	}
+	return de.decodeValues(dst, from, to), nil
+}
+
+// DecodeTSInto is DecodeValuesInto for the ts column.
+// time complexity: O(k + checkpointInterval) for k rows
+func (de *DeltaEncodingOf[T]) DecodeTSInto(dst []int64, fromID, toID int) (int, error) {
+	from, to, err := de.intoRange(len(dst), fromID, toID)
+	if err != nil {
+		return 0, err
+	}
+	return de.decodeTS(dst, from, to), nil
+}
+
+// DecodeIDsInto is DecodeValuesInto for the id column.
+// time complexity: O(k)
+func (de *DeltaEncodingOf[T]) DecodeIDsInto(dst []int, fromID, toID int) (int, error) {
+	from, to, err := de.intoRange(len(dst), fromID, toID)
+	if err != nil {
+		return 0, err
+	}
+	return de.decodeIDs(dst, from, to), nil
+}
+
+// intoRange resolves the row range of a DecodeInto call and checks that a
+// buffer of size n can hold it.
+func (de *DeltaEncodingOf[T]) intoRange(n, fromID, toID int) (from, to int, err error) {
+	from, to, err = de.indexRange(fromID, toID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < to-from {
+		return 0, 0, fmt.Errorf("buffer holds %d rows, range [%d, %d] has %d", n, fromID, toID, to-from)
+	}
+	return from, to, nil
+}
+
+// decodeIDs writes the ids of the live rows in [from, to) to dst.
+func (de *DeltaEncodingOf[T]) decodeIDs(dst []int, from, to int) int {
+	n := 0
+	for rowIndex := from; rowIndex < to; rowIndex++ {
+		if !de.tombstones.get(rowIndex) {
+			dst[n] = de.idList[rowIndex]
+			n++
+		}
+	}
+	return n
+}
+
+// decodeValues writes the values of the live rows in [from, to) to dst,
+// walking the value deltas from the checkpoint before from.
+func (de *DeltaEncodingOf[T]) decodeValues(dst []T, from, to int) int {
+	checkpointIndex := from / de.checkpointInterval
+	value, n := de.checkpointValues[checkpointIndex], 0
+	for rowIndex := checkpointIndex * de.checkpointInterval; rowIndex < to; rowIndex++ {
+		value += de.deltaValueList[rowIndex]
+		if rowIndex >= from && !de.tombstones.get(rowIndex) {
+			dst[n] = de.withSideValues(rowIndex, RowOf[T]{Value: value}).Value
+			n++
+		}
+	}
+	return n
+}
+
+// decodeTS writes the ts of the live rows in [from, to) to dst, walking the ts
+// deltas from the checkpoint before from.
+func (de *DeltaEncodingOf[T]) decodeTS(dst []int64, from, to int) int {
+	checkpointIndex := from / de.checkpointInterval
+	ts, tsDelta := de.checkpointTs[checkpointIndex], de.checkpointTsDeltas[checkpointIndex]
+	n := 0
+	for rowIndex := checkpointIndex * de.checkpointInterval; rowIndex < to; rowIndex++ {
+		if de.tsDeltaOfDelta {
+			tsDelta += de.deltaTsList[rowIndex]
+			ts += tsDelta
+		} else {
+			ts += de.deltaTsList[rowIndex]
+		}
+		if rowIndex >= from && !de.tombstones.get(rowIndex) {
+			dst[n] = ts
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package delta_encoding
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Compressor applies general-purpose compression to the serialized blocks of an
+// encoding file. Delta and varint streams still repeat a lot of byte patterns
+// (runs of small deltas, constant ts steps), which a byte-level compressor
+// removes. Name is stored in the file header and must identify the format.
+type Compressor interface {
+	Name() string
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+type flateCompressor struct {
+	level int
+}
+
+// FlateCompression returns a DEFLATE compressor from the standard library at the
+// given level (flate.BestSpeed to flate.BestCompression, or flate.DefaultCompression).
+func FlateCompression(level int) (Compressor, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("invalid flate level %d", level)
+	}
+	return flateCompressor{level: level}, nil
+}
+
+func (f flateCompressor) Name() string {
+	return "flate"
+}
+
+func (f flateCompressor) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, f.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f flateCompressor) Decompress(src []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(src)))
+}
+
+// WithCompression compresses each block of files written by WriteToFile with c.
+// Loading a compressed file needs a compressor with the same Name, passed to
+// LoadFromFile through this option too.
+func WithCompression(c Compressor) Option {
+	return func(cfg *config) error {
+		if c == nil {
+			return errors.New("compressor must not be nil")
+		}
+		cfg.compressor = c
+		return nil
+	}
+}
+
+// Section payloads of a compressed file start with one of these markers, since
+// blocks too small to benefit are kept as they are.
+const (
+	sectionRaw        = 0
+	sectionCompressed = 1
+)
+
+// compressSection returns the payload as stored in a file compressed with c.
+func compressSection(c Compressor, payload []byte) ([]byte, error) {
+	compressed, err := c.Compress(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) < len(payload) {
+		return append([]byte{sectionCompressed}, compressed...), nil
+	}
+	return append([]byte{sectionRaw}, payload...), nil
+}
+
+// decompressSection reverses compressSection.
+func decompressSection(c Compressor, stored []byte) ([]byte, error) {
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("%w: empty compressed section", ErrCorruptBlock)
+	}
+	switch stored[0] {
+	case sectionRaw:
+		return stored[1:], nil
+	case sectionCompressed:
+		payload, err := c.Decompress(stored[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrCorruptBlock, c.Name(), err)
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown section marker %d", ErrCorruptBlock, stored[0])
+	}
+}
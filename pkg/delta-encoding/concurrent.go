@@ -0,0 +1,118 @@
+package delta_encoding
+
+import (
+	"errors"
+	"sync"
+)
+
+// ConcurrentDeltaEncodingOf guards an encoding with an RWMutex so one writer can
+// append while several readers decode concurrently. Writes take the exclusive
+// lock; reads share it.
+type ConcurrentDeltaEncodingOf[T Numeric] struct {
+	mu sync.RWMutex
+	de *DeltaEncodingOf[T]
+}
+
+type ConcurrentDeltaEncoding = ConcurrentDeltaEncodingOf[int64]
+
+// NewConcurrentDE creates an empty thread-safe DeltaEncoding configured by opts.
+func NewConcurrentDE(opts ...Option) (*ConcurrentDeltaEncoding, error) {
+	return NewConcurrentDEOf[int64](opts...)
+}
+
+// NewConcurrentDEOf creates an empty thread-safe encoding for values of type T.
+// Adaptive checkpoints are rejected because they re-checkpoint from inside a
+// lookup, which would mutate the encoding under a read lock.
+func NewConcurrentDEOf[T Numeric](opts ...Option) (*ConcurrentDeltaEncodingOf[T], error) {
+	de, err := NewDEOf[T](opts...)
+	if err != nil {
+		return nil, err
+	}
+	if de.adaptiveTarget != 0 {
+		return nil, errors.New("adaptive checkpoints are not supported on a concurrent encoding")
+	}
+	return &ConcurrentDeltaEncodingOf[T]{de: de}, nil
+}
+
+// AppendRow appends one row under the write lock.
+func (c *ConcurrentDeltaEncodingOf[T]) AppendRow(row RowOf[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.de.AppendRow(row)
+}
+
+// AppendRows appends rows in order under a single write lock.
+func (c *ConcurrentDeltaEncodingOf[T]) AppendRows(rows []RowOf[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.de.AppendRows(rows)
+}
+
+// DeleteRow marks a row deleted under the write lock.
+func (c *ConcurrentDeltaEncodingOf[T]) DeleteRow(rowID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.de.DeleteRow(rowID)
+}
+
+// UpdateRow replaces the value of one row under the write lock.
+func (c *ConcurrentDeltaEncodingOf[T]) UpdateRow(rowID int, newValue T) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.de.UpdateRow(rowID, newValue)
+}
+
+// Seal returns a read-only copy of the rows appended so far.
+func (c *ConcurrentDeltaEncodingOf[T]) Seal() *SealedOf[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.de.Seal()
+}
+
+// Len returns the number of encoded rows.
+func (c *ConcurrentDeltaEncodingOf[T]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.de.Len()
+}
+
+// ReconstructRow decodes a single row under the read lock.
+func (c *ConcurrentDeltaEncodingOf[T]) ReconstructRow(rowID int) (RowOf[T], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.de.ReconstructRow(rowID)
+}
+
+// ReconstructRange decodes the live rows with fromID <= ID <= toID under the
+// read lock.
+func (c *ConcurrentDeltaEncodingOf[T]) ReconstructRange(fromID, toID int) ([]RowOf[T], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.de.ReconstructRange(fromID, toID)
+}
+
+// ReconstructTable decodes every live row under the read lock.
+func (c *ConcurrentDeltaEncodingOf[T]) ReconstructTable() ([]RowOf[T], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.de.ReconstructTable()
+}
+
+// RowsInTimeRange returns the live rows with fromTS <= ts <= toTS.
+func (c *ConcurrentDeltaEncodingOf[T]) RowsInTimeRange(fromTS, toTS int64) []RowOf[T] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.de.RowsInTimeRange(fromTS, toTS)
+}
+
+// Aggregate computes count, sum, min and max over the live rows in the range.
+func (c *ConcurrentDeltaEncodingOf[T]) Aggregate(fromID, toID int) (Aggregates[T], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.de.Aggregate(fromID, toID)
+}
+
+// DecodeStats returns the decode path lengths recorded by ReconstructRow.
+func (c *ConcurrentDeltaEncodingOf[T]) DecodeStats() DecodeStats {
+	return c.de.DecodeStats()
+}
@@ -0,0 +1,70 @@
+package delta_encoding
+
+import "fmt"
+
+// CounterIncrease is the result of treating the value column as a monotonic
+// counter over a row range.
+type CounterIncrease[T Numeric] struct {
+	Increase T   // total increase, compensated for resets
+	Resets   int // number of value drops interpreted as counter resets
+}
+
+// Increase returns how much a monotonic counter grew between fromID and toID.
+// A negative delta means the counter was reset (e.g. the process restarted), so
+// the value after the drop is counted as growth from zero rather than as a loss.
+// Deleted rows are skipped, so deleting a bogus drop removes its reset.
+// time complexity: O(k + checkpointInterval) for k rows
+func (de *DeltaEncodingOf[T]) Increase(fromID, toID int) (CounterIncrease[T], error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return CounterIncrease[T]{}, err
+	}
+	result := CounterIncrease[T]{}
+	c := de.newCursor()
+	first := true
+	var prev T
+	for rowIndex := from; rowIndex < to; rowIndex++ {
+		if de.tombstones.get(rowIndex) {
+			continue
+		}
+		// The chain value, which null and non-finite rows leave unchanged.
+		c.seek(rowIndex)
+		switch {
+		case first:
+			first = false
+		case c.value < prev:
+			result.Resets++
+			result.Increase += c.value
+		default:
+			result.Increase += c.value - prev
+		}
+		prev = c.value
+	}
+	return result, nil
+}
+
+// Rate returns the reset-compensated per-ts-unit rate of a counter between
+// fromID and toID.
+func (de *DeltaEncodingOf[T]) Rate(fromID, toID int) (float64, error) {
+	increase, err := de.Increase(fromID, toID)
+	if err != nil {
+		return 0, err
+	}
+	fromIndex, toIndex, _ := de.indexRange(fromID, toID)
+	// The rate spans the first and last live rows, like the increase.
+	for fromIndex < toIndex && de.tombstones.get(fromIndex) {
+		fromIndex++
+	}
+	for toIndex > fromIndex && de.tombstones.get(toIndex-1) {
+		toIndex--
+	}
+	if fromIndex == toIndex {
+		return 0, fmt.Errorf("no rows in range [%d, %d]", fromID, toID)
+	}
+	c := de.newCursor()
+	from, to := c.seek(fromIndex), c.seek(toIndex-1)
+	if to.TS <= from.TS {
+		return 0, fmt.Errorf("rate needs rows with increasing ts, got %d..%d", from.TS, to.TS)
+	}
+	return float64(increase.Increase) / float64(to.TS-from.TS), nil
+}
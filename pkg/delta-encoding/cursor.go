@@ -0,0 +1,90 @@
+package delta_encoding
+
+// cursor decodes rows in ascending order, continuing from the previously decoded
+// row when possible and jumping to the nearest checkpoint otherwise.
+type cursor[T Numeric] struct {
+	de    *DeltaEncodingOf[T]
+	index int // index of the last decoded row, -1 before the first seek
+	value T
+	ts    int64
+	// tsDelta is the first-order ts delta of the last decoded row, needed to
+	// integrate second-order deltas in delta-of-delta mode.
+	tsDelta int64
+}
+
+func (de *DeltaEncodingOf[T]) newCursor() *cursor[T] {
+	return &cursor[T]{de: de, index: -1}
+}
+
+// seek decodes up to rowIndex and returns the row stored there.
+func (c *cursor[T]) seek(rowIndex int) RowOf[T] {
+	blockStart := (rowIndex / c.de.checkpointInterval) * c.de.checkpointInterval
+	if c.index < 0 || c.index < blockStart-1 || c.index > rowIndex {
+		checkpointIndex := rowIndex / c.de.checkpointInterval
+		c.value = c.de.checkpointValues[checkpointIndex]
+		c.ts = c.de.checkpointTs[checkpointIndex]
+		c.tsDelta = c.de.checkpointTsDeltas[checkpointIndex]
+		if c.de.logger != nil {
+			c.de.logger.Debug("jump to checkpoint", "block", checkpointIndex, "from", c.index+1, "to", rowIndex+1)
+		}
+		c.index = blockStart - 1
+	}
+	for c.index < rowIndex {
+		c.index++
+		c.value += c.de.deltaValueList[c.index]
+		if c.de.tsDeltaOfDelta {
+			c.tsDelta += c.de.deltaTsList[c.index]
+			c.ts += c.tsDelta
+		} else {
+			c.ts += c.de.deltaTsList[c.index]
+		}
+	}
+	return c.de.withSideValues(rowIndex, RowOf[T]{ID: c.de.idList[rowIndex], Value: c.value, TS: c.ts})
+}
+
+// withSideValues replaces the chain value of row rowIndex with the value kept
+// outside the chain, if it is null or not finite.
+func (de *DeltaEncodingOf[T]) withSideValues(rowIndex int, row RowOf[T]) RowOf[T] {
+	if de.nulls.get(rowIndex) {
+		row.Value = 0
+	} else if value, ok := de.nonFinite.get(rowIndex); ok {
+		row.Value = value
+	}
+	return row
+}
+
+// RowIterator streams reconstructed rows one at a time for callers that prefer
+// an explicit cursor over range-over-func:
+//
+//	it := de.Rows()
+//	for it.Next() {
+//		row := it.Value()
+//	}
+type RowIterator[T Numeric] struct {
+	c    *cursor[T]
+	next int
+	row  RowOf[T]
+}
+
+// Rows returns an iterator positioned before the first row.
+func (de *DeltaEncodingOf[T]) Rows() *RowIterator[T] {
+	return &RowIterator[T]{c: de.newCursor()}
+}
+
+// Next decodes the next row and reports whether there was one.
+func (it *RowIterator[T]) Next() bool {
+	for it.next < len(it.c.de.idList) {
+		rowIndex := it.next
+		it.next++
+		if !it.c.de.tombstones.get(rowIndex) {
+			it.row = it.c.seek(rowIndex)
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the row decoded by the last successful call to Next.
+func (it *RowIterator[T]) Value() RowOf[T] {
+	return it.row
+}
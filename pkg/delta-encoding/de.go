@@ -3,151 +3,380 @@ package delta_encoding
 import (
 	"encoding/binary"
 	"fmt"
+	"iter"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"unsafe"
 )
 
-type Row struct {
+// Numeric is the set of value types that can be delta-encoded.
+type Numeric interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+type RowOf[T Numeric] struct {
 	ID    int
-	Value int64
+	Value T
 	TS    int64
 }
 
-type DeltaEncoding struct {
-	idList []int
-	deltaValueList []int64
-	deltaTsList []int64
-	originalRows []Row
-	lastValue int64
-	lastTs int64
+// Row is a row with an int64 value, the common case for counters and byte sizes.
+type Row = RowOf[int64]
+
+type DeltaEncodingOf[T Numeric] struct {
+	idList             []int
+	deltaValueList     []T
+	deltaTsList        []int64
+	originalRows       []RowOf[T] // retained only when verification is enabled
+	verify             bool
+	timeIndex          *timeBucketIndex  // nil unless WithTimeBucketIndex is set
+	timePrecision      Precision         // 0 when ts is a unitless int64
+	reorder            *reorderBuffer[T] // nil unless WithReorderWindow is set
+	decodeStats        DecodeStats
+	statsMu            sync.Mutex // guards decodeStats, which lookups update
+	tombstones         bitmap     // deleted row indexes
+	deletedCount       int
+	idIndex            map[int]int      // id to row index, nil while ids are increasing
+	exemplars          sparse[Exemplar] // only rows with an attached exemplar
+	nonFinite          sparse[T]        // NaN and ±Inf values, kept out of the delta chain
+	nonFinitePolicy    NonFinitePolicy
+	nulls              bitmap  // rows whose value is null, the inverse of a validity bitmap
+	adaptiveTarget     float64 // 0 unless WithAdaptiveCheckpoints is set
+	lastValue          T       // last value in the delta chain, the last finite value for floats
+	lastTs             int64
+	tsUnordered        bool // a ts was appended below the one before it
 	checkpointInterval int
-    checkpointValues   []int64  // absolute values at checkpoints
-    checkpointTs       []int64  // absolute ts at checkpoints
+	checkpointValues   []T     // absolute values at checkpoints
+	checkpointTs       []int64 // absolute ts at checkpoints
+	checkpointTsDeltas []int64 // first-order ts delta at checkpoints, used in delta-of-delta mode
+	tsDeltaOfDelta     bool    // deltaTsList holds second-order deltas
+	lastTsDelta        int64
+	valueSampleRate    int   // 0 disables per-block value samples
+	blockSamples       [][]T // sorted value samples, one slice per checkpoint block
+	logger             *slog.Logger
+	compressor         Compressor       // nil writes uncompressed files
+	checksum           Checksum         // nil writes CRC32C
+	encodings          *ColumnEncodings // file column encodings chosen by Seal, nil for the in-memory ones
+}
+
+// DeltaEncoding encodes int64 values. Use DeltaEncodingOf[float64] for gauges
+// such as CPU utilization.
+type DeltaEncoding = DeltaEncodingOf[int64]
+
+// NewDE creates an empty DeltaEncoding configured by opts.
+// The default checkpoint interval is 4.
+func NewDE(opts ...Option) (*DeltaEncoding, error) {
+	return NewDEOf[int64](opts...)
+}
+
+// InitDE is like NewDE but panics if an option is invalid, which keeps call
+// sites with constant options short.
+func InitDE(opts ...Option) *DeltaEncoding {
+	return InitDEOf[int64](opts...)
 }
 
-func InitDE() (*DeltaEncoding) {
-	return &DeltaEncoding{
-		idList:         []int{},
-		deltaValueList: []int64{},
-		deltaTsList:    []int64{},
-		lastValue: 0,
-		lastTs: 0,
-		checkpointInterval: 4,
-    	checkpointValues:   []int64{},
-    	checkpointTs:       []int64{},
+// NewDEOf creates an empty encoding for values of type T.
+func NewDEOf[T Numeric](opts ...Option) (*DeltaEncodingOf[T], error) {
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	de := &DeltaEncodingOf[T]{
+		idList:             []int{},
+		deltaValueList:     []T{},
+		deltaTsList:        []int64{},
+		lastValue:          0,
+		lastTs:             0,
+		checkpointInterval: cfg.checkpointInterval,
+		checkpointValues:   []T{},
+		checkpointTs:       []int64{},
+		checkpointTsDeltas: []int64{},
+		tsDeltaOfDelta:     cfg.tsDeltaOfDelta,
+		timePrecision:      cfg.timePrecision,
+		adaptiveTarget:     cfg.adaptiveTarget,
+		valueSampleRate:    cfg.valueSampleRate,
+		logger:             cfg.logger,
+		verify:             cfg.verify,
+		compressor:         cfg.compressor,
+		checksum:           cfg.checksum,
+		nonFinitePolicy:    cfg.nonFinitePolicy,
+	}
+	if cfg.reorderWindow != nil {
+		de.reorder = &reorderBuffer[T]{window: *cfg.reorderWindow}
+	}
+	if cfg.timeBucketSize > 0 {
+		de.timeIndex = &timeBucketIndex{size: cfg.timeBucketSize}
+	}
+	return de, nil
+}
+
+// InitDEOf is like NewDEOf but panics if an option is invalid.
+func InitDEOf[T Numeric](opts ...Option) *DeltaEncodingOf[T] {
+	de, err := NewDEOf[T](opts...)
+	if err != nil {
+		panic(err)
 	}
+	return de
 }
 
 // AppendRow populates the Delta encoding for the given row.
 // time complexity: O(1)
-func (de *DeltaEncoding) AppendRow(row Row) {
+func (de *DeltaEncodingOf[T]) AppendRow(row RowOf[T]) {
+	de.appendRow(row, false)
+}
+
+// appendRow appends row, or a row with a null value when null is set.
+func (de *DeltaEncodingOf[T]) appendRow(row RowOf[T], null bool) {
+	value := de.lastValue
+	if null {
+		de.nulls.set(len(de.idList))
+	} else {
+		value = de.chainValue(len(de.idList), row.Value, de.lastValue)
+	}
 	if len(de.idList) == 0 {
 		de.deltaValueList = append(de.deltaValueList, 0)
 		de.deltaTsList = append(de.deltaTsList, 0)
 
-		de.checkpointValues = append(de.checkpointValues, row.Value)
+		de.checkpointValues = append(de.checkpointValues, value)
 		de.checkpointTs = append(de.checkpointTs, row.TS)
+		de.checkpointTsDeltas = append(de.checkpointTsDeltas, 0)
 	} else {
-		de.deltaValueList = append(de.deltaValueList, row.Value-de.lastValue)
-		de.deltaTsList = append(de.deltaTsList, row.TS-de.lastTs)
+		de.deltaValueList = append(de.deltaValueList, value-de.lastValue)
+		tsDelta := row.TS - de.lastTs
+		if tsDelta < 0 {
+			de.tsUnordered = true
+		}
+		if de.tsDeltaOfDelta {
+			de.deltaTsList = append(de.deltaTsList, tsDelta-de.lastTsDelta)
+		} else {
+			de.deltaTsList = append(de.deltaTsList, tsDelta)
+		}
+		de.lastTsDelta = tsDelta
 	}
+	de.trackID(row.ID)
 	de.idList = append(de.idList, row.ID)
-	de.lastValue = row.Value
+	de.lastValue = value
 	de.lastTs = row.TS
-	de.originalRows = append(de.originalRows, row)
+	if de.verify {
+		de.originalRows = append(de.originalRows, row)
+	}
+	if !null && isFinite(row.Value) {
+		de.sampleValue(len(de.idList)-1, row.Value)
+	}
+	if de.timeIndex != nil {
+		de.timeIndex.add(len(de.idList)-1, row.TS)
+	}
 
 	// Checkpoint
-	if len(de.idList) % de.checkpointInterval == 0 {
-		de.checkpointValues = append(de.checkpointValues, row.Value)
+	if len(de.idList)%de.checkpointInterval == 0 {
+		de.checkpointValues = append(de.checkpointValues, value)
 		de.checkpointTs = append(de.checkpointTs, row.TS)
+		de.checkpointTsDeltas = append(de.checkpointTsDeltas, de.lastTsDelta)
+		if de.logger != nil {
+			de.logger.Debug("checkpoint stored", "row", len(de.idList), "value", row.Value, "ts", row.TS)
+		}
 	}
 }
 
+// SetLogger sets the logger used for debug output such as checkpoint and skip
+// decisions. A nil logger, the default, disables logging.
+func (de *DeltaEncodingOf[T]) SetLogger(logger *slog.Logger) {
+	de.logger = logger
+}
+
 // VerifyDeltaEncodingCorrectness checks whether the delta-encoded data can be fully
 // and correctly reconstructed to match the original input rows.
 //
-// Note: This uses exact equality checks for all fields (id, value, ts).
-// Since value and ts are stored as int64, there's no tolerance for minor differences.
-// This is appropriate for strictly deterministic data like metrics or timestamps with fixed intervals.
+// Note: For integer values this uses exact equality checks for all fields (id, value, ts),
+// which is appropriate for strictly deterministic data like metrics or timestamps with fixed intervals.
+// Float values are compared with a small relative tolerance, since summing float deltas
+// can round differently from the original value.
 //
-// Returns true if all reconstructed rows exactly match the originals; false otherwise.
-func (de *DeltaEncoding) VerifyDeltaEncodingCorrectness() bool {
+// The original rows are only retained when the encoding was created with
+// WithVerification(true); otherwise an error is returned.
+//
+// Returns true if all reconstructed rows match the originals; false otherwise.
+func (de *DeltaEncodingOf[T]) VerifyDeltaEncodingCorrectness() (bool, error) {
+	if !de.verify {
+		return false, fmt.Errorf("original rows were not retained, create the encoding WithVerification(true)")
+	}
 	deRows, err := de.ReconstructTable()
 	if err != nil {
-		return false
+		return false, err
 	}
-	for ind := range(len(de.originalRows)){
-		if deRows[ind] != de.originalRows[ind] {
-			return false
+	live := []RowOf[T]{}
+	for ind, row := range de.originalRows {
+		if !de.tombstones.get(ind) {
+			live = append(live, row)
 		}
 	}
-	return true
+	if len(deRows) != len(live) {
+		return false, nil
+	}
+	for ind := range len(live) {
+		got, want := deRows[ind], live[ind]
+		if got.ID != want.ID || got.TS != want.TS || !valuesEqual(got.Value, want.Value) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
-func (de *DeltaEncoding) ReconstructTable() ([]Row, error) {
-	rows := []Row{}
-	for _, id := range(de.idList) {
-		row, err := de.ReconstructRow(id)
-		if err!= nil {
-			return nil, err
+// ReconstructTable decodes every live row in one pass. It is not a point
+// lookup, so it leaves DecodeStats and adaptive checkpointing alone.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) ReconstructTable() ([]RowOf[T], error) {
+	rows := []RowOf[T]{}
+	for rowIndex, row := range de.allRows() {
+		if !de.tombstones.get(rowIndex) {
+			rows = append(rows, row)
 		}
-		rows = append(rows, row)
 	}
 	return rows, nil
 }
 
-func (de *DeltaEncoding) ReconstructRow(rowID int) (Row, error) {
-	if rowID <= 0 || rowID > len(de.idList) {
-		return Row{}, fmt.Errorf("row with id %d does not exist", rowID)
+func (de *DeltaEncodingOf[T]) ReconstructRow(rowID int) (RowOf[T], error) {
+	rowIndex, ok := de.indexOf(rowID)
+	if !ok {
+		return RowOf[T]{}, fmt.Errorf("%w: id %d", ErrRowNotFound, rowID)
 	}
-	row := Row{}
-	row.ID = rowID
+	if de.tombstones.get(rowIndex) {
+		return RowOf[T]{}, fmt.Errorf("%w: id %d", ErrRowDeleted, rowID)
+	}
+	// Optimisation: Using checkpointing to avoid recalculation from the base value.
+	row := de.newCursor().seek(rowIndex)
+	de.recordWalk(rowIndex%de.checkpointInterval + 1)
+	return row, nil
+}
 
+// ReconstructRange reconstructs rows fromID..toID (inclusive) in one pass: it
+// starts from the checkpoint nearest to fromID once and then keeps adding deltas,
+// instead of re-walking from a checkpoint for every row. Deleted rows are skipped.
+// time complexity: O(k + checkpointInterval) for k rows
+func (de *DeltaEncodingOf[T]) ReconstructRange(fromID, toID int) ([]RowOf[T], error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.AppendSeq(make([]RowOf[T], 0, to-from), de.rangeRows(from, to)), nil
+}
 
-	// Optimisation: Using checkpointing to avoid recalculation from the base value.
-	checkpointIndex := (rowID-1)/de.checkpointInterval
-	row.Value = de.checkpointValues[checkpointIndex]
-	row.TS = de.checkpointTs[checkpointIndex]
+// RangeSeq is ReconstructRange as an iterator, decoding each row as it is
+// consumed, so a caller can stop early without decoding the rest of the range.
+// time complexity: O(k + checkpointInterval) for k rows consumed
+func (de *DeltaEncodingOf[T]) RangeSeq(fromID, toID int) (iter.Seq[RowOf[T]], error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+	return de.rangeRows(from, to), nil
+}
+
+// rangeRows iterates the live rows in [from, to) with one cursor.
+func (de *DeltaEncodingOf[T]) rangeRows(from, to int) iter.Seq[RowOf[T]] {
+	return func(yield func(RowOf[T]) bool) {
+		c := de.newCursor()
+		for rowIndex := from; rowIndex < to; rowIndex++ {
+			if !de.tombstones.get(rowIndex) && !yield(c.seek(rowIndex)) {
+				return
+			}
+		}
+	}
+}
+
+// isFloat reports whether T is a floating point type.
+func isFloat[T Numeric]() bool {
+	var v T = 1
+	return v/2 != 0
+}
 
-	rowIndex := rowID - 1
-	for ind := checkpointIndex * de.checkpointInterval; ind <= rowIndex; ind++ {
-		row.Value += de.deltaValueList[ind]
-		row.TS += de.deltaTsList[ind]
+// valuesEqual compares integers exactly and floats within a relative tolerance.
+func valuesEqual[T Numeric](a, b T) bool {
+	if !isFloat[T]() {
+		return a == b
 	}
+	x, y := float64(a), float64(b)
+	if x == y || math.IsNaN(x) && math.IsNaN(y) {
+		return true
+	}
+	return math.Abs(x-y) <= 1e-9*max(1, math.Abs(x), math.Abs(y))
+}
 
-	return row, nil
+// valueEncodedSize returns the stored size of one value: a varint for integers
+// and the fixed IEEE-754 width for floats.
+func valueEncodedSize[T Numeric](v T, buf []byte) int {
+	if isFloat[T]() {
+		return int(unsafe.Sizeof(v))
+	}
+	return binary.PutVarint(buf, int64(v))
 }
 
-func varintEncodedSizeGeneric[T ~int | ~int64](data []T) int {
+// varintEncodedSizeGeneric sizes data as signed varints. binary.PutVarint
+// zigzag-maps values before encoding (0, -1, 1, -2, ... become 0, 1, 2, 3, ...),
+// so small negative deltas such as dips cost 1-2 bytes, the same as small
+// positive ones, rather than the 10 bytes of a sign-extended uvarint.
+func varintEncodedSizeGeneric[T Numeric](data []T) int {
 	buf := make([]byte, binary.MaxVarintLen64)
 	total := 0
 	for _, v := range data {
-		n := binary.PutVarint(buf, int64(v))
-		total += n
+		total += valueEncodedSize(v, buf)
 	}
 	return total
 }
 
-func binaryEncodedSize(rows []Row) int {
+func binaryEncodedSize[T Numeric](rows []RowOf[T]) int {
 	total := 0
 	buf := make([]byte, binary.MaxVarintLen64)
 	for _, row := range rows {
 		// Estimate encoded size as if each field was varint-encoded separately.
 		total += binary.PutVarint(buf, int64(row.ID))
-		total += binary.PutVarint(buf, row.Value)
+		total += valueEncodedSize(row.Value, buf)
 		total += binary.PutVarint(buf, row.TS)
 	}
 	return total
 }
 
-func (de *DeltaEncoding) PrintStats() {
+// PrintStats writes the Stats breakdown to stdout.
+func (de *DeltaEncodingOf[T]) PrintStats() {
+	stats := de.Stats()
 	fmt.Printf("\n\nVarint Encoded Sizes:\n")
+	fmt.Printf("Total compressed size (varint): %d bytes\n", stats.CompressedBytes)
+	fmt.Printf("  ids %d, values %d, ts %d bytes\n", stats.IDBytes, stats.ValueBytes, stats.TSBytes)
+	fmt.Printf("Checkpoint overhead: %d bytes\n", stats.CheckpointBytes)
+	fmt.Printf("Original size (varint): %d bytes\n", stats.OriginalBytes)
+	fmt.Printf("Saved: %d bytes (%.2f%%)\n", stats.OriginalBytes-stats.CompressedBytes,
+		float64(stats.OriginalBytes-stats.CompressedBytes)*100.0/float64(stats.OriginalBytes))
+	fmt.Printf("Ratio including checkpoints: %.2fx\n", stats.Ratio())
+	fmt.Printf("TS column: delta %d bytes, delta-of-delta %d bytes, frame-of-reference %d bytes\n", stats.TSDeltaBytes, stats.TSDeltaOfDeltaBytes, stats.TSFORBytes)
+	fmt.Printf("Value column: delta %d bytes, frame-of-reference %d bytes\n", stats.ValueBytes, stats.ValueFORBytes)
+	if de.compressor != nil {
+		fmt.Printf("File: %d bytes, %d bytes with %s\n", stats.FileBytes, stats.CompressedFileBytes, de.compressor.Name())
+	} else {
+		fmt.Printf("File: %d bytes\n", stats.FileBytes)
+	}
+	if stats.PlainBlocks > 0 {
+		fmt.Printf("Plain blocks: %d of %d, deltas were larger than the values\n", stats.PlainBlocks, stats.Blocks)
+	}
+}
 
-	totalVarintSize := varintEncodedSizeGeneric(de.idList) +
-	varintEncodedSizeGeneric(de.deltaValueList) +
-	varintEncodedSizeGeneric(de.deltaTsList)
-	orignalSize := binaryEncodedSize(de.originalRows)
-
-	fmt.Printf("Total compressed size (varint): %d bytes\n", totalVarintSize)
-	fmt.Printf("Original size (varint): %d bytes\n", orignalSize)
-	fmt.Printf("Saved: %d bytes (%.2f%%)\n", orignalSize - totalVarintSize,
-  		float64(orignalSize - totalVarintSize)*100.0/float64(orignalSize))
+// TSEncodingSizes returns the varint size of the ts column encoded as plain
+// deltas and as delta-of-deltas, whichever mode the encoding was created in.
+func (de *DeltaEncodingOf[T]) TSEncodingSizes() (delta int, deltaOfDelta int) {
+	deltas := make([]int64, 0, len(de.idList))
+	dods := make([]int64, 0, len(de.idList))
+	var prevTs, prevDelta int64
+	c := de.newCursor()
+	for rowIndex := range len(de.idList) {
+		ts := c.seek(rowIndex).TS
+		d := int64(0)
+		if rowIndex > 0 {
+			d = ts - prevTs
+		}
+		deltas = append(deltas, d)
+		dods = append(dods, d-prevDelta)
+		prevTs, prevDelta = ts, d
+	}
+	return varintEncodedSizeGeneric(deltas), varintEncodedSizeGeneric(dods)
 }
@@ -1,13 +1,40 @@
 package delta_encoding
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/adler32"
+	"io"
+	"iter"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 )
 
+func requireCorrect[T Numeric](t *testing.T, de *DeltaEncodingOf[T]) {
+	t.Helper()
+	correct, err := de.VerifyDeltaEncodingCorrectness()
+	require.NoError(t, err)
+	require.True(t, correct)
+}
+
 func TestDeltaEncoding(t *testing.T) {
-	de := InitDE()
+	de := InitDE(WithVerification(true))
 
 	// Test data with various patterns:
 	// - Steady increase
@@ -98,20 +125,2970 @@ func TestDeltaEncoding(t *testing.T) {
 	})
 
 	t.Run("EmptyDeltaEncoding", func(t *testing.T) {
-		emptyDE := InitDE()
+		emptyDE := InitDE(WithVerification(true))
 		require.Empty(t, emptyDE.deltaValueList)
 		require.Empty(t, emptyDE.deltaTsList)
 		require.Empty(t, emptyDE.checkpointValues)
 		require.Empty(t, emptyDE.checkpointTs)
-		require.True(t, emptyDE.VerifyDeltaEncodingCorrectness())
+		requireCorrect(t, emptyDE)
 	})
 
 	t.Run("VerifyDeltaEncodingCorrectness", func(t *testing.T) {
 		// Test correctness of delta encoding
-		require.True(t, de.VerifyDeltaEncodingCorrectness())
+		requireCorrect(t, de)
 
 		// Test with corrupted data
 		de.deltaValueList[2] = 999999 // Corrupt a delta value
-		require.False(t, de.VerifyDeltaEncodingCorrectness())
+		correct, err := de.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.False(t, correct)
+	})
+}
+
+func TestApproxPercentile(t *testing.T) {
+	de := InitDE()
+	require.NoError(t, de.EnableValueSamples(1))
+	for i := 1; i <= 12; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * 10), TS: int64(1000 + i)})
+	}
+
+	t.Run("exact with full sampling", func(t *testing.T) {
+		median, err := de.ApproxMedian(1, 12)
+		require.NoError(t, err)
+		require.Equal(t, int64(60), median)
+
+		p100, err := de.ApproxPercentile(1, 12, 100)
+		require.NoError(t, err)
+		require.Equal(t, int64(120), p100)
+
+		p0, err := de.ApproxPercentile(1, 12, 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), p0)
+	})
+
+	t.Run("range widened to blocks", func(t *testing.T) {
+		// rows 5..6 live in the second block (rows 5..8)
+		median, err := de.ApproxMedian(5, 6)
+		require.NoError(t, err)
+		require.Equal(t, int64(60), median)
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		_, err := de.ApproxPercentile(0, 5, 50)
+		require.Error(t, err)
+		_, err = de.ApproxPercentile(1, 5, 101)
+		require.Error(t, err)
+		require.Error(t, de.EnableValueSamples(2))
+
+		plain := InitDE()
+		require.Error(t, plain.EnableValueSamples(0))
+		plain.AppendRow(Row{ID: 1, Value: 1, TS: 1})
+		_, err = plain.ApproxMedian(1, 1)
+		require.Error(t, err)
+	})
+}
+
+func TestSampling(t *testing.T) {
+	de := InitDE()
+	for i := 1; i <= 10; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * i), TS: int64(1000 + 2*i)})
+	}
+
+	t.Run("SampleScan", func(t *testing.T) {
+		rows, err := de.SampleScan(3)
+		require.NoError(t, err)
+		require.Equal(t, []Row{
+			{ID: 1, Value: 1, TS: 1002},
+			{ID: 4, Value: 16, TS: 1008},
+			{ID: 7, Value: 49, TS: 1014},
+			{ID: 10, Value: 100, TS: 1020},
+		}, rows)
+
+		rows, err = de.SampleScan(1)
+		require.NoError(t, err)
+		table, err := de.ReconstructTable()
+		require.NoError(t, err)
+		require.Equal(t, table, rows)
+
+		_, err = de.SampleScan(0)
+		require.Error(t, err)
+	})
+
+	t.Run("RandomSample", func(t *testing.T) {
+		rows, err := de.RandomSample(4, 42)
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		for i, row := range rows {
+			expected, err := de.ReconstructRow(row.ID)
+			require.NoError(t, err)
+			require.Equal(t, expected, row)
+			if i > 0 {
+				require.Less(t, rows[i-1].ID, row.ID)
+			}
+		}
+
+		again, err := de.RandomSample(4, 42)
+		require.NoError(t, err)
+		require.Equal(t, rows, again)
+
+		all, err := de.RandomSample(20, 1)
+		require.NoError(t, err)
+		require.Len(t, all, 10)
+		none, err := de.RandomSample(0, 1)
+		require.NoError(t, err)
+		require.Empty(t, none)
+
+		_, err = de.RandomSample(-1, 1)
+		require.Error(t, err)
+
+		// Samples are drawn from live rows only, so deletions never shrink them.
+		sparse := InitDE()
+		for i := 1; i <= 200; i++ {
+			sparse.AppendRow(Row{ID: i, Value: int64(i), TS: int64(i)})
+			if i%10 != 0 {
+				require.NoError(t, sparse.DeleteRow(i))
+			}
+		}
+		for seed := range int64(20) {
+			rows, err := sparse.RandomSample(15, seed)
+			require.NoError(t, err)
+			require.Len(t, rows, 15)
+			for _, row := range rows {
+				require.Zero(t, row.ID%10)
+			}
+		}
+		live, err := sparse.RandomSample(50, 1)
+		require.NoError(t, err)
+		require.Len(t, live, 20)
+		require.Equal(t, []int{0, 2, 64, 130}, bitmap{0b1010}.selectUnset([]int{0, 1, 62, 128}))
+	})
+}
+
+func TestScan(t *testing.T) {
+	de := InitDE()
+	for i := 1; i <= 10; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i), TS: int64(1000 + i)})
+	}
+
+	t.Run("unbounded", func(t *testing.T) {
+		result := de.Scan(ScanOptions{})
+		require.False(t, result.Partial)
+		require.Len(t, result.Rows, 10)
+		require.Equal(t, 30, result.DecodedBytes) // 1 byte per id, value delta and ts delta
+	})
+
+	t.Run("limit pushdown", func(t *testing.T) {
+		result := de.Scan(ScanOptions{Limit: 3})
+		require.False(t, result.Partial)
+		require.Equal(t, []Row{
+			{ID: 1, Value: 1, TS: 1001},
+			{ID: 2, Value: 2, TS: 1002},
+			{ID: 3, Value: 3, TS: 1003},
+		}, result.Rows)
+		require.Equal(t, 9, result.DecodedBytes)
+	})
+
+	t.Run("decode budget", func(t *testing.T) {
+		result := de.Scan(ScanOptions{MaxDecodedBytes: 14})
+		require.True(t, result.Partial)
+		require.Len(t, result.Rows, 4)
+		require.Equal(t, 12, result.DecodedBytes)
+	})
+}
+
+func TestAllIterator(t *testing.T) {
+	de := InitDE()
+	for i := 1; i <= 6; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(10 * i), TS: int64(1000 + i)})
+	}
+
+	table, err := de.ReconstructTable()
+	require.NoError(t, err)
+	require.Equal(t, table, slices.Collect(de.All()))
+
+	// Early termination stops decoding.
+	seen := 0
+	for row := range de.All() {
+		seen++
+		if row.ID == 2 {
+			break
+		}
+	}
+	require.Equal(t, 2, seen)
+
+	require.Empty(t, slices.Collect(InitDE().All()))
+}
+
+func TestSeqVariants(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(4))
+	for i := 1; i <= 20; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * i), TS: int64(1000 + 2*i)})
+	}
+	require.NoError(t, de.DeleteRow(7))
+
+	// Every Seq yields what its slice counterpart returns, and stops early.
+	check := func(t *testing.T, want []Row, seq iter.Seq[Row]) {
+		require.Equal(t, want, slices.AppendSeq([]Row{}, seq))
+		seen := 0
+		for range seq {
+			if seen++; seen == 2 {
+				break
+			}
+		}
+		require.Equal(t, min(2, len(want)), seen)
+	}
+
+	t.Run("ScanSeq", func(t *testing.T) {
+		for _, opts := range []ScanOptions{{}, {Limit: 5}, {MaxDecodedBytes: 20}} {
+			check(t, de.Scan(opts).Rows, de.ScanSeq(opts))
+		}
+	})
+
+	t.Run("RangeSeq", func(t *testing.T) {
+		want, err := de.ReconstructRange(5, 13)
+		require.NoError(t, err)
+		seq, err := de.RangeSeq(5, 13)
+		require.NoError(t, err)
+		check(t, want, seq)
+		_, err = de.RangeSeq(13, 5)
+		require.Error(t, err)
+		seq, err = de.Seal().RangeSeq(5, 13)
+		require.NoError(t, err)
+		check(t, want, seq)
+	})
+
+	t.Run("RowsInTimeRangeSeq", func(t *testing.T) {
+		check(t, de.RowsInTimeRange(1009, 1025), de.RowsInTimeRangeSeq(1009, 1025))
+		check(t, []Row{}, de.RowsInTimeRangeSeq(1025, 1009))
+		check(t, de.RowsInTimeRange(1009, 1025), de.Seal().RowsInTimeRangeSeq(1009, 1025))
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		want, err := de.SampleScan(3)
+		require.NoError(t, err)
+		seq, err := de.SampleScanSeq(3)
+		require.NoError(t, err)
+		check(t, want, seq)
+		_, err = de.SampleScanSeq(0)
+		require.Error(t, err)
+
+		want, err = de.RandomSample(8, 3)
+		require.NoError(t, err)
+		seq, err = de.RandomSampleSeq(8, 3)
+		require.NoError(t, err)
+		check(t, want, seq)
+		_, err = de.RandomSampleSeq(-1, 3)
+		require.Error(t, err)
+	})
+}
+
+func TestAppendRowsContext(t *testing.T) {
+	rows := []Row{
+		{ID: 1, Value: 10, TS: 1000},
+		{ID: 2, Value: 20, TS: 1002},
+		{ID: 3, Value: 30, TS: 1004},
+	}
+
+	t.Run("all rows accepted", func(t *testing.T) {
+		de := InitDE(WithVerification(true))
+		accepted, err := de.AppendRowsContext(context.Background(), rows)
+		require.NoError(t, err)
+		require.Equal(t, 3, accepted)
+		requireCorrect(t, de)
+	})
+
+	t.Run("cancelled mid-batch commits the prefix", func(t *testing.T) {
+		de := InitDE(WithVerification(true))
+		ctx, cancel := context.WithCancel(context.Background())
+		accepted, err := de.AppendRowsContext(ctx, rows[:2])
+		require.NoError(t, err)
+		cancel()
+
+		n, err := de.AppendRowsContext(ctx, rows[accepted:])
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 0, n)
+
+		// Resuming with a live context continues exactly where it stopped.
+		n, err = de.AppendRowsContext(context.Background(), rows[accepted:])
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		requireCorrect(t, de)
+	})
+}
+
+func TestBlock(t *testing.T) {
+	de := InitDE()
+	for i := 1; i <= 6; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(10 * i), TS: int64(1000 + i)})
+	}
+	require.Equal(t, 6, de.Len())
+	require.Equal(t, 4, de.CheckpointInterval())
+	require.Equal(t, 2, de.NumBlocks())
+
+	block, err := de.Block(1)
+	require.NoError(t, err)
+	require.Equal(t, BlockSummary{
+		Index:           1,
+		FirstRowID:      5,
+		LastRowID:       6,
+		CheckpointValue: 40,
+		CheckpointTS:    1004,
+		ValueDeltas:     []int64{10, 10},
+		TSDeltas:        []int64{1, 1},
+	}, block)
+
+	_, err = de.Block(2)
+	require.Error(t, err)
+	_, err = InitDE().Block(0)
+	require.Error(t, err)
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	de := InitDE()
+	de.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	for i := 1; i <= 5; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i), TS: int64(i)})
+	}
+	_, err := de.ReconstructRow(5)
+	require.NoError(t, err)
+	de.Scan(ScanOptions{MaxDecodedBytes: 1})
+
+	require.Contains(t, buf.String(), "checkpoint stored")
+	require.Contains(t, buf.String(), "scan stopped by decode budget")
+
+	// The default logger is quiet.
+	quiet := InitDE()
+	quiet.AppendRow(Row{ID: 1, Value: 1, TS: 1})
+	require.Nil(t, quiet.logger)
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("WithCheckpointInterval", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(2), WithVerification(true))
+		for i := 1; i <= 5; i++ {
+			de.AppendRow(Row{ID: i, Value: int64(10 * i), TS: int64(1000 + i)})
+		}
+		require.Equal(t, 2, de.CheckpointInterval())
+		require.Equal(t, []int64{10, 20, 40}, de.checkpointValues)
+		requireCorrect(t, de)
+
+		every := InitDE(WithCheckpointInterval(1), WithVerification(true))
+		every.AppendRow(Row{ID: 1, Value: 5, TS: 1})
+		every.AppendRow(Row{ID: 2, Value: 7, TS: 2})
+		requireCorrect(t, every)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewDE(WithCheckpointInterval(0))
+		require.Error(t, err)
+		_, err = NewDE(WithValueSamples(-1))
+		require.Error(t, err)
+		require.Panics(t, func() { InitDE(WithCheckpointInterval(-3)) })
+	})
+
+	t.Run("WithValueSamples", func(t *testing.T) {
+		de, err := NewDE(WithValueSamples(1), WithCheckpointInterval(8))
+		require.NoError(t, err)
+		for i := 1; i <= 8; i++ {
+			de.AppendRow(Row{ID: i, Value: int64(i), TS: int64(i)})
+		}
+		median, err := de.ApproxMedian(1, 8)
+		require.NoError(t, err)
+		require.Equal(t, int64(4), median)
+	})
+}
+
+func TestFloatValues(t *testing.T) {
+	de := InitDEOf[float64](WithVerification(true))
+	cpu := []float64{12.5, 13.1, 12.9, 45.7, 44.2, 0.3, 99.99, 98.1, 12.5}
+	for i, v := range cpu {
+		de.AppendRow(RowOf[float64]{ID: i + 1, Value: v, TS: int64(1000 + 10*i)})
+	}
+
+	requireCorrect(t, de)
+	for i, v := range cpu {
+		row, err := de.ReconstructRow(i + 1)
+		require.NoError(t, err)
+		require.InDelta(t, v, row.Value, 1e-9)
+		require.Equal(t, int64(1000+10*i), row.TS)
+	}
+
+	// Float deltas are stored at their fixed width.
+	result := de.Scan(ScanOptions{Limit: 2})
+	require.Equal(t, 2*(1+8+1), result.DecodedBytes)
+
+	small := InitDEOf[float32](WithCheckpointInterval(2), WithVerification(true))
+	small.AppendRow(RowOf[float32]{ID: 1, Value: 0.5, TS: 1})
+	small.AppendRow(RowOf[float32]{ID: 2, Value: 0.75, TS: 2})
+	small.AppendRow(RowOf[float32]{ID: 3, Value: 0.25, TS: 3})
+	requireCorrect(t, small)
+	row, err := small.ReconstructRow(3)
+	require.NoError(t, err)
+	require.Equal(t, float32(0.25), row.Value)
+}
+
+func TestVerificationOptIn(t *testing.T) {
+	de := InitDE()
+	de.AppendRow(Row{ID: 1, Value: 10, TS: 1000})
+	de.AppendRow(Row{ID: 2, Value: 12, TS: 1002})
+	require.Empty(t, de.originalRows)
+
+	_, err := de.VerifyDeltaEncodingCorrectness()
+	require.Error(t, err)
+
+	verified := InitDE(WithVerification(true))
+	verified.AppendRow(Row{ID: 1, Value: 10, TS: 1000})
+	require.Len(t, verified.originalRows, 1)
+	requireCorrect(t, verified)
+}
+
+func TestCountInTimeRange(t *testing.T) {
+	indexed := InitDE(WithTimeBucketIndex(60))
+	plain := InitDE()
+	// 3 rows per minute for 5 minutes, starting at ts 0.
+	for i := range 15 {
+		row := Row{ID: i + 1, Value: int64(i), TS: int64(i * 20)}
+		indexed.AppendRow(row)
+		plain.AppendRow(row)
+	}
+
+	cases := []struct{ from, to int64 }{
+		{0, 299},   // everything, aligned
+		{60, 179},  // two whole buckets
+		{30, 200},  // partial buckets at both ends
+		{100, 100}, // single ts
+		{101, 119}, // no rows
+		{-50, 10},  // before the first row
+		{250, 10_000},
+		{200, 100}, // inverted
+	}
+	for _, tc := range cases {
+		expected := 0
+		for i := range 15 {
+			if ts := int64(i * 20); ts >= tc.from && ts <= tc.to {
+				expected++
+			}
+		}
+		require.Equal(t, expected, indexed.CountInTimeRange(tc.from, tc.to), "indexed [%d, %d]", tc.from, tc.to)
+		require.Equal(t, expected, plain.CountInTimeRange(tc.from, tc.to), "scan [%d, %d]", tc.from, tc.to)
+	}
+	require.Equal(t, []int{3, 3, 3, 3, 3}, indexed.timeIndex.counts)
+
+	t.Run("out of order ts falls back to scanning", func(t *testing.T) {
+		de := InitDE(WithTimeBucketIndex(10))
+		de.AppendRow(Row{ID: 1, Value: 1, TS: 25})
+		de.AppendRow(Row{ID: 2, Value: 1, TS: 5})
+		require.True(t, de.timeIndex.broken)
+		require.Equal(t, 2, de.CountInTimeRange(0, 30))
+	})
+
+	_, err := NewDE(WithTimeBucketIndex(0))
+	require.Error(t, err)
+}
+
+func TestTSDeltaOfDelta(t *testing.T) {
+	dod := InitDE(WithTSDeltaOfDelta(), WithCheckpointInterval(3), WithVerification(true))
+	plain := InitDE(WithCheckpointInterval(3))
+	// Regular 10s interval with one late sample and one gap.
+	ts := []int64{1000, 1010, 1020, 1030, 1041, 1050, 1060, 1090, 1100, 1110}
+	for i, v := range ts {
+		row := Row{ID: i + 1, Value: int64(i % 3), TS: v}
+		dod.AppendRow(row)
+		plain.AppendRow(row)
+	}
+
+	requireCorrect(t, dod)
+	for i, v := range ts {
+		row, err := dod.ReconstructRow(i + 1)
+		require.NoError(t, err)
+		require.Equal(t, v, row.TS)
+	}
+	require.Equal(t, []int64{0, 10, 0, 0, 1, -2, 1, 20, -20, 0}, dod.deltaTsList)
+
+	scanned := dod.Scan(ScanOptions{})
+	table, err := plain.ReconstructTable()
+	require.NoError(t, err)
+	require.Equal(t, table, scanned.Rows)
+
+	deltaSize, dodSize := dod.TSEncodingSizes()
+	require.Equal(t, 10, deltaSize)
+	require.Equal(t, 10, dodSize)
+	plainDelta, plainDod := plain.TSEncodingSizes()
+	require.Equal(t, deltaSize, plainDelta)
+	require.Equal(t, dodSize, plainDod)
+}
+
+func TestTSDeltaOfDeltaSizes(t *testing.T) {
+	de := InitDE(WithTSDeltaOfDelta())
+	for i := range 100 {
+		de.AppendRow(Row{ID: i + 1, Value: 1, TS: int64(i) * 60_000})
+	}
+	deltaSize, dodSize := de.TSEncodingSizes()
+	// 60000 needs 3 varint bytes; all but one second-order delta are zero.
+	require.Equal(t, 1+99*3, deltaSize)
+	require.Equal(t, 1+3+98, dodSize)
+	require.Equal(t, dodSize, varintEncodedSizeGeneric(de.deltaTsList))
+}
+
+func TestNegativeDeltaSizes(t *testing.T) {
+	// Signed varints are zigzag encoded, so magnitude decides the size, not sign.
+	require.Equal(t, 1, varintEncodedSizeGeneric([]int64{-1}))
+	require.Equal(t, 1, varintEncodedSizeGeneric([]int64{-64}))
+	require.Equal(t, 2, varintEncodedSizeGeneric([]int64{-65}))
+	require.Equal(t, 2, varintEncodedSizeGeneric([]int64{-8192}))
+	require.Equal(t, varintEncodedSizeGeneric([]int64{40}), varintEncodedSizeGeneric([]int64{-40}))
+
+	de := InitDE()
+	de.AppendRow(Row{ID: 1, Value: 100, TS: 1000})
+	de.AppendRow(Row{ID: 2, Value: 60, TS: 1002}) // -40 dip
+	require.Equal(t, 2, varintEncodedSizeGeneric(de.deltaValueList))
+}
+
+func TestTimePrecision(t *testing.T) {
+	t.Run("Convert", func(t *testing.T) {
+		ts, err := Second.Convert(1_700_000_000, Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, int64(1_700_000_000_000), ts)
+
+		ts, err = Nanosecond.Convert(1_700_000_000_999_999_999, Second)
+		require.NoError(t, err)
+		require.Equal(t, int64(1_700_000_000), ts)
+
+		ts, err = Millisecond.Convert(-1, Second)
+		require.NoError(t, err)
+		require.Equal(t, int64(-1), ts)
+
+		_, err = Second.Convert(math.MaxInt64/10, Nanosecond)
+		require.Error(t, err)
+		_, err = Precision(7).Convert(1, Second)
+		require.Error(t, err)
+	})
+
+	t.Run("mixed precision ingest", func(t *testing.T) {
+		de := InitDE(WithTimePrecision(Millisecond))
+		require.Equal(t, Millisecond, de.TimePrecision())
+		require.NoError(t, de.AppendRowIn(Row{ID: 1, Value: 1, TS: 1_700_000_000}, Second))
+		require.NoError(t, de.AppendRowIn(Row{ID: 2, Value: 2, TS: 1_700_000_000_500}, Millisecond))
+		require.NoError(t, de.AppendRowIn(Row{ID: 3, Value: 3, TS: 1_700_000_001_250_000}, Microsecond))
+
+		rows, err := de.ReconstructTable()
+		require.NoError(t, err)
+		require.Equal(t, []Row{
+			{ID: 1, Value: 1, TS: 1_700_000_000_000},
+			{ID: 2, Value: 2, TS: 1_700_000_000_500},
+			{ID: 3, Value: 3, TS: 1_700_000_001_250},
+		}, rows)
+
+		count, err := de.CountInTimeRangeIn(1_700_000_000, 1_700_000_001, Second)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		// Only whole stored units inside the range count: the first row's
+		// millisecond starts before the range and the last one ends after it.
+		count, err = de.CountInTimeRangeIn(1_700_000_000_000_000_001, 1_700_000_001_250_000_000, Nanosecond)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		count, err = de.CountInTimeRangeIn(1_700_000_000_000_000_000, 1_700_000_001_250_999_999, Nanosecond)
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		// Row queries convert their bounds the same way.
+		inRange, err := de.RowsInTimeRangeIn(1_700_000_000_000_000_001, 1_700_000_001_250_000_000, Nanosecond)
+		require.NoError(t, err)
+		require.Equal(t, rows[1:2], inRange)
+		inRange, err = de.RowsInTimeRangeIn(1_700_000_000, 1_700_000_001, Second)
+		require.NoError(t, err)
+		require.Equal(t, rows[:2], inRange)
+		seq, err := de.Seal().RowsInTimeRangeSeqIn(1_700_000_000_000_000, 1_700_000_001_250_999, Microsecond)
+		require.NoError(t, err)
+		require.Equal(t, rows, slices.Collect(seq))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		require.Error(t, InitDE().AppendRowIn(Row{ID: 1}, Second))
+		_, err := InitDE().CountInTimeRangeIn(0, 1, Second)
+		require.Error(t, err)
+		_, err = InitDE().RowsInTimeRangeIn(0, 1, Second)
+		require.Error(t, err)
+		_, err = InitDE(WithTimePrecision(Nanosecond)).RowsInTimeRangeSeqIn(0, math.MaxInt64/10, Second)
+		require.Error(t, err)
+		_, err = NewDE(WithTimePrecision(3))
+		require.Error(t, err)
+	})
+}
+
+func TestReconstructRange(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3))
+	for i := 1; i <= 10; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * i), TS: int64(1000 + i)})
+	}
+
+	rows, err := de.ReconstructRange(2, 7)
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+	for i, row := range rows {
+		expected, err := de.ReconstructRow(i + 2)
+		require.NoError(t, err)
+		require.Equal(t, expected, row)
+	}
+
+	rows, err = de.ReconstructRange(10, 10)
+	require.NoError(t, err)
+	require.Equal(t, []Row{{ID: 10, Value: 100, TS: 1010}}, rows)
+
+	for _, r := range [][2]int{{0, 3}, {3, 11}, {5, 4}} {
+		_, err = de.ReconstructRange(r[0], r[1])
+		require.Error(t, err)
+	}
+}
+
+func TestRowIterator(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3))
+	for i := 1; i <= 7; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(100 - i), TS: int64(1000 + 2*i)})
+	}
+
+	rows := []Row{}
+	it := de.Rows()
+	for it.Next() {
+		rows = append(rows, it.Value())
+	}
+	require.False(t, it.Next())
+
+	table, err := de.ReconstructTable()
+	require.NoError(t, err)
+	require.Equal(t, table, rows)
+
+	require.False(t, InitDE().Rows().Next())
+}
+
+func TestAppendRows(t *testing.T) {
+	rows := make([]Row, 1000)
+	for i := range rows {
+		rows[i] = Row{ID: i + 1, Value: int64(i * 3 % 17), TS: int64(1000 + i)}
+	}
+
+	de := InitDE(WithVerification(true))
+	de.AppendRows(rows[:10])
+	de.AppendRows(rows[10:])
+	requireCorrect(t, de)
+	require.Equal(t, 1000, de.Len())
+	require.GreaterOrEqual(t, cap(de.idList), 1000)
+
+	rowByRow := InitDE()
+	for _, row := range rows {
+		rowByRow.AppendRow(row)
+	}
+	require.Equal(t, rowByRow.deltaValueList, de.deltaValueList)
+	require.Equal(t, rowByRow.checkpointValues, de.checkpointValues)
+
+	allocs := testing.AllocsPerRun(5, func() {
+		InitDE().AppendRows(rows)
+	})
+	require.Less(t, allocs, 20.0)
+}
+
+func BenchmarkAppendRows(b *testing.B) {
+	rows := make([]Row, 100_000)
+	for i := range rows {
+		rows[i] = Row{ID: i + 1, Value: int64(i % 97), TS: int64(i)}
+	}
+	b.Run("AppendRow", func(b *testing.B) {
+		for range b.N {
+			de := InitDE()
+			for _, row := range rows {
+				de.AppendRow(row)
+			}
+		}
+	})
+	b.Run("AppendRows", func(b *testing.B) {
+		for range b.N {
+			InitDE().AppendRows(rows)
+		}
+	})
+}
+
+func TestCounterResets(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3))
+	// Counter grows, restarts at row 5 (value 4), grows again, restarts at row 8.
+	values := []int64{100, 110, 125, 130, 4, 10, 30, 2, 7}
+	for i, v := range values {
+		de.AppendRow(Row{ID: i + 1, Value: v, TS: int64(10 * i)})
+	}
+
+	increase, err := de.Increase(1, 9)
+	require.NoError(t, err)
+	// 30 before the first reset, 4+26 between resets, 2+5 after the second.
+	require.Equal(t, CounterIncrease[int64]{Increase: 67, Resets: 2}, increase)
+
+	increase, err = de.Increase(2, 4)
+	require.NoError(t, err)
+	require.Equal(t, CounterIncrease[int64]{Increase: 20}, increase)
+
+	increase, err = de.Increase(6, 6)
+	require.NoError(t, err)
+	require.Equal(t, CounterIncrease[int64]{}, increase)
+
+	rate, err := de.Rate(1, 9)
+	require.NoError(t, err)
+	require.InDelta(t, 67.0/80.0, rate, 1e-12)
+
+	_, err = de.Rate(3, 3)
+	require.Error(t, err)
+	_, err = de.Increase(0, 9)
+	require.Error(t, err)
+
+	// A deleted glitch no longer counts as a reset, and deleted endpoints do
+	// not stretch the rate.
+	glitch := InitDE(WithCheckpointInterval(2))
+	for i, v := range []int64{90, 100, 110, 5, 120, 130, 0} {
+		glitch.AppendRow(Row{ID: i + 1, Value: v, TS: int64(10 * i)})
+	}
+	increase, err = glitch.Increase(2, 6)
+	require.NoError(t, err)
+	require.Equal(t, CounterIncrease[int64]{Increase: 140, Resets: 1}, increase)
+	require.NoError(t, glitch.DeleteRow(4))
+	increase, err = glitch.Increase(2, 6)
+	require.NoError(t, err)
+	require.Equal(t, CounterIncrease[int64]{Increase: 30}, increase)
+	require.NoError(t, glitch.DeleteRow(1))
+	require.NoError(t, glitch.DeleteRow(7))
+	rate, err = glitch.Rate(1, 7)
+	require.NoError(t, err)
+	require.InDelta(t, 30.0/40.0, rate, 1e-12)
+	require.NoError(t, glitch.DeleteRow(2))
+	require.NoError(t, glitch.DeleteRow(3))
+	require.NoError(t, glitch.DeleteRow(5))
+	require.NoError(t, glitch.DeleteRow(6))
+	_, err = glitch.Rate(1, 7)
+	require.Error(t, err)
+}
+
+func TestReorderWindow(t *testing.T) {
+	de := InitDE(WithReorderWindow(10))
+	arrivals := []int64{100, 104, 102, 110, 108, 115, 121, 119, 140}
+	for i, ts := range arrivals {
+		require.NoError(t, de.Ingest(Row{ID: i + 1, Value: ts * 2, TS: ts}))
+	}
+	// Rows within 10 of the newest ts (140) are still buffered.
+	require.Equal(t, 1, de.Buffered())
+	require.Equal(t, 8, de.Len())
+
+	// 125 is late but newer than everything committed, so it still fits.
+	require.NoError(t, de.Ingest(Row{ID: 10, Value: 250, TS: 125}))
+	require.Error(t, de.Ingest(Row{ID: 11, Value: 0, TS: 101}))
+
+	de.Flush()
+	require.Equal(t, 0, de.Buffered())
+
+	timestamps := []int64{}
+	for row := range de.All() {
+		require.Equal(t, row.TS*2, row.Value)
+		timestamps = append(timestamps, row.TS)
+	}
+	require.Equal(t, []int64{100, 102, 104, 108, 110, 115, 119, 121, 125, 140}, timestamps)
+
+	t.Run("without window Ingest appends directly", func(t *testing.T) {
+		plain := InitDE()
+		require.NoError(t, plain.Ingest(Row{ID: 1, Value: 1, TS: 5}))
+		require.Equal(t, 1, plain.Len())
+		require.Equal(t, 0, plain.Buffered())
+		plain.Flush()
+	})
+
+	_, err := NewDE(WithReorderWindow(-1))
+	require.Error(t, err)
+}
+
+func TestAdaptiveCheckpoints(t *testing.T) {
+	rows := make([]Row, 64)
+	for i := range rows {
+		rows[i] = Row{ID: i + 1, Value: int64(i * 7 % 13), TS: int64(1000 + 3*i)}
+	}
+
+	t.Run("decode stats and suggestion", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(8))
+		de.AppendRows(rows)
+		require.Equal(t, 8, de.SuggestCheckpointInterval(4.5)) // uniform access assumed
+
+		for _, id := range []int{8, 16, 24, 32} { // always the last row of a block
+			_, err := de.ReconstructRow(id)
+			require.NoError(t, err)
+		}
+		require.Equal(t, DecodeStats{Lookups: 4, DeltasWalked: 32, MaxWalk: 8}, de.DecodeStats())
+		require.Equal(t, 2, de.SuggestCheckpointInterval(2))
+
+		de.ResetDecodeStats()
+		require.Equal(t, DecodeStats{}, de.DecodeStats())
+	})
+
+	t.Run("Recheckpoint keeps rows intact", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(8), WithTSDeltaOfDelta(), WithValueSamples(1), WithVerification(true))
+		de.AppendRows(rows)
+		for _, interval := range []int{1, 3, 5, 64, 100} {
+			require.NoError(t, de.Recheckpoint(interval))
+			require.Equal(t, interval, de.CheckpointInterval())
+			requireCorrect(t, de)
+			median, err := de.ApproxMedian(1, 64)
+			require.NoError(t, err)
+			require.Equal(t, int64(6), median)
+		}
+		require.Error(t, de.Recheckpoint(0))
+	})
+
+	t.Run("whole-table reads are not lookups", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(16), WithAdaptiveCheckpoints(1), WithVerification(true))
+		for i := range 2 * adaptEvery {
+			de.AppendRow(Row{ID: i + 1, Value: int64(i % 5), TS: int64(i)})
+		}
+		de.Stats()
+		_, err := de.ReconstructTable()
+		require.NoError(t, err)
+		_, err = de.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.Equal(t, 16, de.CheckpointInterval())
+		require.Equal(t, DecodeStats{}, de.DecodeStats())
+	})
+
+	t.Run("adaptive mode shrinks the interval for a skewed workload", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(16), WithAdaptiveCheckpoints(2), WithVerification(true))
+		de.AppendRows(rows)
+		for range adaptEvery {
+			_, err := de.ReconstructRow(16)
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2, de.CheckpointInterval())
+		require.Equal(t, DecodeStats{}, de.DecodeStats())
+		requireCorrect(t, de)
+	})
+
+	_, err := NewDE(WithAdaptiveCheckpoints(0.5))
+	require.Error(t, err)
+}
+
+func TestQueryStats(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(8))
+	for i := range 64 {
+		de.AppendRow(Row{ID: i + 1, Value: int64(i), TS: int64(1000 + i)})
+	}
+	require.NoError(t, de.DeleteRow(20))
+
+	stats, err := de.QueryStats("", 11, 30)
+	require.NoError(t, err)
+	require.Equal(t, QueryStats{RowsScanned: 22, RowsReturned: 19, BlocksScanned: 3, BlocksPruned: 5, BytesDecoded: 22 * 24}, stats)
+	rows, err := de.ReconstructRange(11, 30)
+	require.NoError(t, err)
+	require.Len(t, rows, stats.RowsReturned)
+
+	stats, err = de.QueryStats(ColumnID, 11, 30)
+	require.NoError(t, err)
+	require.Equal(t, QueryStats{RowsScanned: 20, RowsReturned: 19, BlocksScanned: 3, BlocksPruned: 5, BytesDecoded: 20 * 8}, stats)
+	stats, err = de.QueryStats(ColumnValue, 9, 9)
+	require.NoError(t, err)
+	require.Equal(t, QueryStats{RowsScanned: 1, RowsReturned: 1, BlocksScanned: 1, BlocksPruned: 7, BytesDecoded: 8}, stats)
+
+	_, err = de.QueryStats("bogus", 1, 2)
+	require.Error(t, err)
+	_, err = de.QueryStats("", 5, 100)
+	require.Error(t, err)
+}
+
+func TestDeleteRow(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3), WithVerification(true), WithTimeBucketIndex(10))
+	for i := 1; i <= 9; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * 10), TS: int64(i * 5)})
+	}
+
+	require.NoError(t, de.DeleteRow(3)) // last row of a block, also a checkpoint
+	require.NoError(t, de.DeleteRow(5))
+	require.Error(t, de.DeleteRow(5))
+	require.Error(t, de.DeleteRow(10))
+	require.True(t, de.IsDeleted(3))
+	require.False(t, de.IsDeleted(4))
+	require.Equal(t, 2, de.Deleted())
+	require.Equal(t, 9, de.Len())
+
+	_, err := de.ReconstructRow(3)
+	require.Error(t, err)
+
+	// Rows after a deleted one still decode from the untouched deltas.
+	row, err := de.ReconstructRow(4)
+	require.NoError(t, err)
+	require.Equal(t, Row{ID: 4, Value: 40, TS: 20}, row)
+
+	table, err := de.ReconstructTable()
+	require.NoError(t, err)
+	ids := []int{}
+	for _, row := range table {
+		ids = append(ids, row.ID)
+	}
+	require.Equal(t, []int{1, 2, 4, 6, 7, 8, 9}, ids)
+	require.Equal(t, table, slices.Collect(de.All()))
+	requireCorrect(t, de)
+
+	rows, err := de.ReconstructRange(2, 6)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	it := de.Rows()
+	count := 0
+	for it.Next() {
+		require.NotEqual(t, 5, it.Value().ID)
+		count++
+	}
+	require.Equal(t, 7, count)
+	require.Len(t, de.Scan(ScanOptions{}).Rows, 7)
+
+	// ts 5..25 holds rows 1..5, of which 3 and 5 are deleted.
+	require.Equal(t, 3, de.CountInTimeRange(5, 25))
+	require.Equal(t, 1, de.CountInTimeRange(10, 19))
+	require.Equal(t, 2, de.CountInTimeRange(30, 39))
+
+	require.NoError(t, de.Recheckpoint(2))
+	requireCorrect(t, de)
+}
+
+func TestUpdateRow(t *testing.T) {
+	newDE := func() *DeltaEncoding {
+		de := InitDE(WithCheckpointInterval(3), WithVerification(true), WithValueSamples(1))
+		for i := 1; i <= 8; i++ {
+			de.AppendRow(Row{ID: i, Value: int64(i * 10), TS: int64(1000 + i)})
+		}
+		return de
+	}
+
+	for rowID := 1; rowID <= 8; rowID++ {
+		de := newDE()
+		require.NoError(t, de.UpdateRow(rowID, 999))
+		requireCorrect(t, de)
+		for i := 1; i <= 8; i++ {
+			row, err := de.ReconstructRow(i)
+			require.NoError(t, err)
+			expected := int64(i * 10)
+			if i == rowID {
+				expected = 999
+			}
+			require.Equal(t, expected, row.Value, "row %d after updating row %d", i, rowID)
+		}
+		p100, err := de.ApproxPercentile(1, 8, 100)
+		require.NoError(t, err)
+		require.Equal(t, int64(999), p100)
+	}
+
+	t.Run("appends after updating the last row", func(t *testing.T) {
+		de := newDE()
+		require.NoError(t, de.UpdateRow(8, 5))
+		de.AppendRow(Row{ID: 9, Value: 7, TS: 1009})
+		requireCorrect(t, de)
+		row, err := de.ReconstructRow(9)
+		require.NoError(t, err)
+		require.Equal(t, int64(7), row.Value)
+	})
+
+	t.Run("a null row keeps other samples", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(4), WithValueSamples(1))
+		de.AppendRow(Row{ID: 1, Value: 0, TS: 1})
+		de.AppendNull(2, 2)
+		require.Equal(t, []int64{0}, de.blockSamples[0])
+		require.NoError(t, de.UpdateRow(2, 7))
+		require.Equal(t, []int64{0, 7}, de.blockSamples[0])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		de := newDE()
+		require.Error(t, de.UpdateRow(0, 1))
+		require.Error(t, de.UpdateRow(9, 1))
+		require.NoError(t, de.DeleteRow(2))
+		require.Error(t, de.UpdateRow(2, 1))
+	})
+}
+
+func TestAggregates(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(4))
+	values := []int64{10, 20, 30, 30, 20, 50, 10, 15, 10, 10}
+	for i, v := range values {
+		de.AppendRow(Row{ID: i + 1, Value: v, TS: int64(1000 + 2*i)})
+	}
+
+	agg, err := de.Aggregate(1, 10)
+	require.NoError(t, err)
+	require.Equal(t, Aggregates[int64]{Count: 10, Sum: 205, Min: 10, Max: 50}, agg)
+
+	sum, err := de.SumValues(3, 6)
+	require.NoError(t, err)
+	require.Equal(t, int64(130), sum)
+
+	lo, hi, err := de.MinMax(5, 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), lo)
+	require.Equal(t, int64(50), hi)
+
+	require.NoError(t, de.DeleteRow(6))
+	lo, hi, err = de.MinMax(5, 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), lo)
+	require.Equal(t, int64(20), hi)
+
+	_, _, err = de.MinMax(6, 6)
+	require.Error(t, err)
+	_, err = de.SumValues(0, 3)
+	require.Error(t, err)
+
+	floats := InitDEOf[float64]()
+	floats.AppendRow(RowOf[float64]{ID: 1, Value: 0.5, TS: 1})
+	floats.AppendRow(RowOf[float64]{ID: 2, Value: 1.25, TS: 2})
+	fsum, err := floats.SumValues(1, 2)
+	require.NoError(t, err)
+	require.InDelta(t, 1.75, fsum, 1e-12)
+}
+
+func TestRowsInTimeRange(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3))
+	timestamps := []int64{100, 100, 105, 110, 110, 110, 120, 130, 130, 150}
+	for i, ts := range timestamps {
+		de.AppendRow(Row{ID: i + 1, Value: int64(i), TS: ts})
+	}
+
+	ids := func(rows []Row) []int {
+		out := []int{}
+		for _, row := range rows {
+			out = append(out, row.ID)
+		}
+		return out
+	}
+	require.Equal(t, []int{4, 5, 6, 7}, ids(de.RowsInTimeRange(110, 120)))
+	require.Equal(t, []int{1, 2}, ids(de.RowsInTimeRange(0, 100)))
+	require.Equal(t, []int{8, 9, 10}, ids(de.RowsInTimeRange(125, 1000)))
+	require.Equal(t, []int{3}, ids(de.RowsInTimeRange(101, 109)))
+	require.Empty(t, de.RowsInTimeRange(151, 200))
+	require.Empty(t, de.RowsInTimeRange(140, 120))
+	require.Empty(t, InitDE().RowsInTimeRange(0, 10))
+
+	require.NoError(t, de.DeleteRow(5))
+	require.Equal(t, []int{4, 6}, ids(de.RowsInTimeRange(110, 110)))
+
+	// Out-of-order ts cannot be binary searched, so every row is scanned.
+	unordered := InitDE(WithCheckpointInterval(2))
+	for i, ts := range []int64{100, 200, 300, 150, 400, 120, 500} {
+		unordered.AppendRow(Row{ID: i + 1, Value: int64(i), TS: ts})
+	}
+	require.Equal(t, []int{1, 4, 6}, ids(unordered.RowsInTimeRange(100, 150)))
+	require.Equal(t, []int{2, 3, 5}, ids(unordered.RowsInTimeRange(200, 450)))
+	require.Equal(t, unordered.CountInTimeRange(110, 160), len(unordered.RowsInTimeRange(110, 160)))
+	require.Equal(t, []int{1, 4, 6}, ids(unordered.Seal().RowsInTimeRange(100, 150)))
+	merged, err := unordered.Merge(InitDE())
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 4, 6}, ids(merged.RowsInTimeRange(100, 150)))
+}
+
+func TestSeal(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3), WithTimeBucketIndex(10), WithValueSamples(1), WithVerification(true))
+	for i := 1; i <= 10; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * i), TS: int64(i * 5)})
+	}
+	require.NoError(t, de.DeleteRow(4))
+
+	sealed := de.Seal()
+	want, err := de.ReconstructTable()
+	require.NoError(t, err)
+	got, err := sealed.ReconstructTable()
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	require.Equal(t, 10, sealed.Len())
+	require.Equal(t, 4, sealed.NumBlocks())
+	require.True(t, sealed.IsDeleted(4))
+	require.Equal(t, de.CountInTimeRange(10, 40), sealed.CountInTimeRange(10, 40))
+	require.Equal(t, de.RowsInTimeRange(10, 40), sealed.RowsInTimeRange(10, 40))
+
+	agg, err := sealed.Aggregate(1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 9, agg.Count)
+
+	require.Equal(t, len(sealed.de.idList), cap(sealed.de.idList))
+	require.Equal(t, len(sealed.de.deltaValueList), cap(sealed.de.deltaValueList))
+	require.Nil(t, sealed.de.originalRows)
+
+	// Appending to the source does not change the sealed block.
+	de.AppendRow(Row{ID: 11, Value: 1, TS: 60})
+	require.Equal(t, 10, sealed.Len())
+}
+
+func TestConcurrentDeltaEncoding(t *testing.T) {
+	_, err := NewConcurrentDE(WithAdaptiveCheckpoints(2))
+	require.Error(t, err)
+
+	cde, err := NewConcurrentDE(WithCheckpointInterval(8))
+	require.NoError(t, err)
+
+	const total = 2000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= total; i++ {
+			cde.AppendRow(Row{ID: i, Value: int64(i * 3), TS: int64(i)})
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cde.Len() < total {
+				n := cde.Len()
+				if n == 0 {
+					continue
+				}
+				row, err := cde.ReconstructRow(n)
+				if err != nil || row.Value != int64(n*3) {
+					t.Errorf("row %d: got %v, %v", n, row, err)
+					return
+				}
+				rows, err := cde.ReconstructRange(1, n)
+				if err != nil || len(rows) != n {
+					t.Errorf("range [1, %d]: got %d rows, %v", n, len(rows), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	rows, err := cde.ReconstructTable()
+	require.NoError(t, err)
+	require.Len(t, rows, total)
+	require.Equal(t, int64(total*3), rows[total-1].Value)
+}
+
+func TestExemplars(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3))
+	for i := 1; i <= 200; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i), TS: int64(i)})
+	}
+
+	labels := map[string]string{"route": "/checkout"}
+	require.NoError(t, de.AttachExemplar(150, Exemplar{TraceID: "c"}))
+	require.NoError(t, de.AttachExemplar(5, Exemplar{TraceID: "a", Labels: labels}))
+	require.NoError(t, de.AttachExemplar(70, Exemplar{TraceID: "b"}))
+	require.Error(t, de.AttachExemplar(201, Exemplar{TraceID: "x"}))
+	labels["route"] = "/changed"
+
+	ex, ok := de.Exemplar(5)
+	require.True(t, ok)
+	require.Equal(t, Exemplar{TraceID: "a", Labels: map[string]string{"route": "/checkout"}}, ex)
+	_, ok = de.Exemplar(6)
+	require.False(t, ok)
+	_, ok = de.Exemplar(0)
+	require.False(t, ok)
+
+	// Replacing keeps a single exemplar per row.
+	require.NoError(t, de.AttachExemplar(70, Exemplar{TraceID: "b2"}))
+	ex, ok = de.Exemplar(70)
+	require.True(t, ok)
+	require.Equal(t, "b2", ex.TraceID)
+
+	rows, exemplars, err := de.ReconstructRangeWithExemplars(60, 160)
+	require.NoError(t, err)
+	require.Len(t, rows, 101)
+	require.Equal(t, []RowExemplar{
+		{RowID: 70, Exemplar: Exemplar{TraceID: "b2"}},
+		{RowID: 150, Exemplar: Exemplar{TraceID: "c"}},
+	}, exemplars)
+
+	require.NoError(t, de.DeleteRow(150))
+	exemplars, err = de.ExemplarsInRange(1, 200)
+	require.NoError(t, err)
+	require.Len(t, exemplars, 2)
+	_, err = de.ExemplarsInRange(10, 5)
+	require.Error(t, err)
+
+	sealed := de.Seal()
+	ex, ok = sealed.Exemplar(70)
+	require.True(t, ok)
+	require.Equal(t, "b2", ex.TraceID)
+}
+
+func TestStats(t *testing.T) {
+	// An empty file is the magic, version, header and trailer sections.
+	require.Equal(t, EncodingStats{FileBytes: 22, CompressedFileBytes: 22}, InitDE().Stats())
+	require.Zero(t, InitDE().Stats().Ratio())
+
+	de := InitDE(WithCheckpointInterval(2))
+	for i := 1; i <= 5; i++ {
+		de.AppendRow(Row{ID: i, Value: 1000 + int64(i), TS: 100 + int64(i)})
+	}
+	require.NoError(t, de.DeleteRow(5))
+
+	stats := de.Stats()
+	require.Equal(t, 5, stats.Rows)
+	require.Equal(t, 1, stats.Deleted)
+	require.Equal(t, 5, stats.IDBytes)
+	require.Equal(t, 5, stats.ValueBytes)
+	require.Equal(t, 5, stats.TSBytes)
+	require.Equal(t, 15, stats.CompressedBytes)
+	// Three checkpoints, each a 2-byte value and 2-byte ts.
+	require.Equal(t, 12, stats.CheckpointBytes)
+	require.Equal(t, 27, stats.TotalBytes())
+	// Four live rows: 1-byte id, 2-byte value, 2-byte ts.
+	require.Equal(t, 20, stats.OriginalBytes)
+	require.InDelta(t, 20.0/27.0, stats.Ratio(), 1e-9)
+	require.Equal(t, 5, stats.TSDeltaBytes)
+	require.Equal(t, 5, stats.TSDeltaOfDeltaBytes)
+}
+
+func TestWriteToFile(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(3), WithTSDeltaOfDelta())
+	for i := 1; i <= 10; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(i * 7), TS: int64(100 + i)})
+	}
+
+	path := filepath.Join(t.TempDir(), "de.bin")
+	require.NoError(t, de.WriteToFile(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	require.Equal(t, "DENC", string(data[:4]))
+	require.Equal(t, byte(fileVersion), data[4])
+	require.Equal(t, byte(5), data[5]) // header section length
+	require.Equal(t, byte(flagTSDeltaOfDelta), data[6])
+	require.Equal(t, byte(reflect.Int64), data[7])
+	require.Equal(t, byte(10), data[8]) // row count
+	require.Equal(t, byte(3), data[9])  // checkpoint interval
+
+	var buf bytes.Buffer
+	n, err := de.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), n)
+	require.Equal(t, data, buf.Bytes())
+
+	require.Error(t, de.WriteToFile(filepath.Join(t.TempDir(), "missing", "de.bin")))
+
+	// Replacing a file keeps its mode, and a failed write leaves it intact
+	// with no temporary file behind.
+	require.NoError(t, os.Chmod(path, 0o600))
+	require.NoError(t, de.WriteToFile(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	broken := InitDE(WithCompression(failCompressor{}))
+	broken.AppendRow(Row{ID: 1, Value: 1, TS: 1})
+	require.Error(t, broken.WriteToFile(path))
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, data, after)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+// failCompressor fails every compression.
+type failCompressor struct{}
+
+func (failCompressor) Name() string                      { return "fail" }
+func (failCompressor) Compress([]byte) ([]byte, error)   { return nil, errors.New("compress failed") }
+func (failCompressor) Decompress([]byte) ([]byte, error) { return nil, errors.New("decompress failed") }
+
+func TestLoadFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("round trip", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(3), WithTSDeltaOfDelta(), WithTimePrecision(Millisecond))
+		for i := 1; i <= 20; i++ {
+			de.AppendRow(Row{ID: i, Value: int64(i*i) - 50, TS: int64(1000 + i*10 + i%3)})
+		}
+		require.NoError(t, de.DeleteRow(7))
+		require.NoError(t, de.AttachExemplar(12, Exemplar{TraceID: "abc", Labels: map[string]string{"pod": "a", "zone": "b"}}))
+
+		path := filepath.Join(dir, "int.bin")
+		require.NoError(t, de.WriteToFile(path))
+		loaded, err := LoadFromFile(path, WithVerification(true), WithTimeBucketIndex(50))
+		require.NoError(t, err)
+
+		want, err := de.ReconstructTable()
+		require.NoError(t, err)
+		got, err := loaded.ReconstructTable()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Equal(t, 3, loaded.CheckpointInterval())
+		require.Equal(t, Millisecond, loaded.TimePrecision())
+		require.True(t, loaded.IsDeleted(7))
+		require.Equal(t, de.CountInTimeRange(1050, 1150), loaded.CountInTimeRange(1050, 1150))
+		ex, ok := loaded.Exemplar(12)
+		require.True(t, ok)
+		require.Equal(t, "abc", ex.TraceID)
+		require.Equal(t, "b", ex.Labels["zone"])
+
+		// Appends continue from the loaded state, and the file is stable.
+		de.AppendRow(Row{ID: 21, Value: 999, TS: 1300})
+		loaded.AppendRow(Row{ID: 21, Value: 999, TS: 1300})
+		requireCorrect(t, loaded)
+		row, err := loaded.ReconstructRow(21)
+		require.NoError(t, err)
+		require.Equal(t, Row{ID: 21, Value: 999, TS: 1300}, row)
+
+		var a, b bytes.Buffer
+		_, err = de.WriteTo(&a)
+		require.NoError(t, err)
+		_, err = loaded.WriteTo(&b)
+		require.NoError(t, err)
+		require.Equal(t, a.Bytes(), b.Bytes())
+	})
+
+	t.Run("floats and empty", func(t *testing.T) {
+		de := InitDEOf[float64]()
+		for i, v := range []float64{0.5, 0.25, -3.125, 1e10, 0.1} {
+			de.AppendRow(RowOf[float64]{ID: i + 1, Value: v, TS: int64(i)})
+		}
+		path := filepath.Join(dir, "float.bin")
+		require.NoError(t, de.WriteToFile(path))
+		loaded, err := LoadFromFileOf[float64](path)
+		require.NoError(t, err)
+		want, _ := de.ReconstructTable()
+		got, _ := loaded.ReconstructTable()
+		require.Equal(t, want, got)
+
+		_, err = LoadFromFile(path)
+		require.ErrorContains(t, err, "holds float64 values")
+
+		empty := filepath.Join(dir, "empty.bin")
+		require.NoError(t, InitDE().WriteToFile(empty))
+		loadedEmpty, err := LoadFromFile(empty)
+		require.NoError(t, err)
+		require.Zero(t, loadedEmpty.Len())
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		de := InitDE()
+		for i := 1; i <= 10; i++ {
+			de.AppendRow(Row{ID: i, Value: int64(i), TS: int64(i)})
+		}
+		var buf bytes.Buffer
+		_, err := de.WriteTo(&buf)
+		require.NoError(t, err)
+		data := buf.Bytes()
+
+		write := func(name string, data []byte) string {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+			return path
+		}
+
+		_, err = LoadFromFile(filepath.Join(dir, "missing.bin"))
+		require.Error(t, err)
+		_, err = LoadFromFile(write("magic.bin", []byte("NOPE\x01\x00\x06")))
+		require.ErrorContains(t, err, "bad magic")
+
+		future := slices.Clone(data)
+		future[4] = fileVersion + 1
+		_, err = LoadFromFile(write("version.bin", future))
+		require.ErrorContains(t, err, "unsupported encoding file version")
+
+		var corrupt *CorruptionError
+		_, err = LoadFromFile(write("truncated.bin", data[:len(data)-3]))
+		require.ErrorAs(t, err, &corrupt)
+		require.Equal(t, CorruptionError{Section: "trailer", Truncated: true}, *corrupt)
+
+		// Flip a bit in the ts delta of row 6, which lives in block 1.
+		block1 := 5 + 1 + 5 + 4 // magic and version, then the header section
+		block1 += 1 + int(data[block1]) + 4
+		flipped := slices.Clone(data)
+		flipped[block1+1+3+4+5] ^= 0x10
+		_, err = LoadFromFile(write("flipped.bin", flipped))
+		require.ErrorAs(t, err, &corrupt)
+		require.Equal(t, "block", corrupt.Section)
+		require.Equal(t, 1, corrupt.Block)
+		require.NotEqual(t, corrupt.Stored, corrupt.Computed)
+		require.ErrorContains(t, err, "block 1 checksum mismatch")
+		require.ErrorIs(t, err, ErrCorruptBlock)
+		_, err = LoadFromFile(write("trailing.bin", append(slices.Clone(data), 0)))
+		require.ErrorContains(t, err, "trailing bytes")
+		require.ErrorIs(t, err, ErrCorruptBlock)
+	})
+}
+
+func TestCompression(t *testing.T) {
+	_, err := FlateCompression(42)
+	require.Error(t, err)
+	_, err = NewDE(WithCompression(nil))
+	require.Error(t, err)
+
+	compressor, err := FlateCompression(flate.BestCompression)
+	require.NoError(t, err)
+	de := InitDE(WithCheckpointInterval(256), WithCompression(compressor))
+	for i := 1; i <= 5000; i++ {
+		de.AppendRow(Row{ID: i, Value: int64(1000 + i%7), TS: int64(i * 15)})
+	}
+	require.NoError(t, de.AttachExemplar(10, Exemplar{TraceID: "t"}))
+
+	stats := de.Stats()
+	require.Less(t, stats.CompressedFileBytes, stats.FileBytes/2)
+
+	path := filepath.Join(t.TempDir(), "de.bin")
+	require.NoError(t, de.WriteToFile(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(stats.CompressedFileBytes), info.Size())
+
+	_, err = LoadFromFile(path)
+	require.ErrorContains(t, err, `compressed with "flate"`)
+	loaded, err := LoadFromFile(path, WithCompression(compressor))
+	require.NoError(t, err)
+	want, _ := de.ReconstructTable()
+	got, _ := loaded.ReconstructTable()
+	require.Equal(t, want, got)
+	_, ok := loaded.Exemplar(10)
+	require.True(t, ok)
+
+	// Blocks too small to shrink are stored raw.
+	small := InitDE(WithCompression(compressor))
+	small.AppendRow(Row{ID: 1, Value: 1, TS: 1})
+	var buf bytes.Buffer
+	_, err = small.WriteTo(&buf)
+	require.NoError(t, err)
+	loadedSmall, err := decode[int64](buf.Bytes(), WithCompression(compressor))
+	require.NoError(t, err)
+	row, err := loadedSmall.ReconstructRow(1)
+	require.NoError(t, err)
+	require.Equal(t, Row{ID: 1, Value: 1, TS: 1}, row)
+}
+
+type adler32Checksum struct{}
+
+func (adler32Checksum) Name() string { return "adler32" }
+func (adler32Checksum) Size() int    { return 4 }
+func (adler32Checksum) Append(dst, payload []byte) []byte {
+	return binary.LittleEndian.AppendUint32(dst, adler32.Checksum(payload))
+}
+
+func TestChecksums(t *testing.T) {
+	for input, want := range map[string]uint64{
+		"":    0xef46db3751d8e999,
+		"a":   0xd24ec4f1a98c6e5b,
+		"abc": 0x44bc2cf5ad770999,
+		"Nobody inspects the spammish repetition": 0xfbcea83c8a378bf1,
+	} {
+		require.Equal(t, want, xxHash64Sum([]byte(input)), input)
+	}
+	_, err := NewDE(WithChecksum(nil))
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	var defaultFile []byte
+	for _, c := range []Checksum{CRC32C, XXHash64, SHA256} {
+		de := InitDE(WithCheckpointInterval(3), WithChecksum(c))
+		for i := 1; i <= 10; i++ {
+			de.AppendRow(Row{ID: i, Value: int64(i * 7), TS: int64(100 + i)})
+		}
+		var buf bytes.Buffer
+		_, err := de.WriteTo(&buf)
+		require.NoError(t, err)
+		data := buf.Bytes()
+		if c == CRC32C {
+			// The default checksum leaves the layout unchanged.
+			require.Zero(t, data[6]&flagChecksum)
+			defaultFile = data
+		} else {
+			require.NotZero(t, data[6]&flagChecksum)
+			// Four blocks and the trailer carry the wider sums.
+			require.Equal(t, len(defaultFile)+1+len(c.Name())+5*(c.Size()-4), len(data))
+		}
+
+		// Built-in checksums load without options,
+		path := filepath.Join(dir, c.Name()+".bin")
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		loaded, err := LoadFromFile(path)
+		require.NoError(t, err, c.Name())
+		require.Equal(t, reconstructTable(t, de), reconstructTable(t, loaded))
+		// and are kept for writing the loaded encoding back.
+		require.Equal(t, c.Name(), loaded.fileChecksum().Name())
+
+		flipped := slices.Clone(data)
+		flipped[len(flipped)-c.Size()-1] ^= 0x01
+		require.NoError(t, os.WriteFile(path, flipped, 0o644))
+		_, err = LoadFromFile(path)
+		var corrupt *CorruptionError
+		require.ErrorAs(t, err, &corrupt)
+		require.Equal(t, "trailer", corrupt.Section)
+		require.Len(t, corrupt.Stored, c.Size())
+		require.NotEqual(t, corrupt.Stored, corrupt.Computed)
+	}
+
+	// A custom checksum is needed to load the files it wrote.
+	de := InitDE(WithChecksum(adler32Checksum{}))
+	de.AppendRow(Row{ID: 1, Value: 5, TS: 10})
+	path := filepath.Join(dir, "adler32.bin")
+	require.NoError(t, de.WriteToFile(path))
+	_, err = LoadFromFile(path)
+	require.ErrorContains(t, err, `checksummed with "adler32"`)
+	loaded, err := LoadFromFile(path, WithChecksum(adler32Checksum{}))
+	require.NoError(t, err)
+	require.Equal(t, 1, loaded.Len())
+}
+
+func TestBlockFile(t *testing.T) {
+	compressor, err := FlateCompression(flate.BestSpeed)
+	require.NoError(t, err)
+	for _, opts := range [][]Option{
+		{WithCheckpointInterval(8)},
+		{WithCheckpointInterval(8), WithTSDeltaOfDelta(), WithCompression(compressor), WithChecksum(SHA256)},
+	} {
+		de := InitDE(opts...)
+		for i := range 100 {
+			if i == 30 {
+				de.AppendNull(i+1, int64(i)*10)
+				continue
+			}
+			de.AppendRow(Row{ID: i + 1, Value: int64(i * i % 97), TS: int64(i) * 10})
+		}
+		require.NoError(t, de.DeleteRow(27))
+		require.NoError(t, de.AttachExemplar(40, Exemplar{TraceID: "abc"}))
+		path := filepath.Join(t.TempDir(), "de.bin")
+		require.NoError(t, de.WriteToFile(path))
+
+		f, err := OpenBlockFile(path, opts...)
+		require.NoError(t, err)
+		require.Equal(t, 100, f.Len())
+		require.Equal(t, 8, f.CheckpointInterval())
+		require.Equal(t, 13, f.NumBlocks())
+
+		// Blocks 3-5 hold rows 25-48.
+		part, err := f.LoadBlocks(3, 6)
+		require.NoError(t, err)
+		want := []Row{}
+		for _, row := range reconstructTable(t, de) {
+			if row.ID > 24 && row.ID <= 48 {
+				want = append(want, row)
+			}
+		}
+		require.Equal(t, want, reconstructTable(t, part))
+		require.Equal(t, 23, part.Len()-part.Stats().Deleted)
+		require.True(t, part.IsNull(31))
+		ex, ok := part.Exemplar(40)
+		require.True(t, ok)
+		require.Equal(t, "abc", ex.TraceID)
+
+		// The last block is partial, and all blocks together are the file.
+		last, err := f.LoadBlocks(12, 13)
+		require.NoError(t, err)
+		require.Equal(t, 4, last.Len())
+		all, err := f.LoadBlocks(0, f.NumBlocks())
+		require.NoError(t, err)
+		require.Equal(t, reconstructTable(t, de), reconstructTable(t, all))
+		empty, err := f.LoadBlocks(5, 5)
+		require.NoError(t, err)
+		require.Zero(t, empty.Len())
+		_, err = f.LoadBlocks(12, 14)
+		require.ErrorContains(t, err, "out of range")
+
+		// A corrupt block only fails the loads that read it.
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[f.sections[1].offset+2] ^= 0x01
+		require.NoError(t, f.Close())
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		f, err = OpenBlockFile(path, opts...)
+		require.NoError(t, err)
+		_, err = f.LoadBlocks(2, 13)
+		require.NoError(t, err)
+		_, err = f.LoadBlocks(0, 2)
+		var corrupt *CorruptionError
+		require.ErrorAs(t, err, &corrupt)
+		require.Equal(t, "block", corrupt.Section)
+		require.Equal(t, 1, corrupt.Block)
+		require.NoError(t, f.Close())
+	}
+
+	// The page cache keeps the two most recently used pages decoded.
+	de := InitDE(WithCheckpointInterval(8))
+	for i := range 40 {
+		de.AppendRow(Row{ID: i + 1, Value: int64(i), TS: int64(i)})
+	}
+	var pageBuf bytes.Buffer
+	_, err = de.WriteTo(&pageBuf)
+	require.NoError(t, err)
+	f, err := NewBlockFileOf[int64](bytes.NewReader(pageBuf.Bytes()), int64(pageBuf.Len()), WithPageCache(2))
+	require.NoError(t, err)
+	first, err := f.Page(1)
+	require.NoError(t, err)
+	require.Equal(t, 8, first.Len())
+	row, err := first.ReconstructRow(9)
+	require.NoError(t, err)
+	require.Equal(t, Row{ID: 9, Value: 8, TS: 8}, row)
+	again, err := f.Page(1)
+	require.NoError(t, err)
+	require.Same(t, first, again)
+	_, err = f.Page(3)
+	require.NoError(t, err)
+	_, err = f.Page(1)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 3}, f.CachedPages())
+	_, err = f.Page(4)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 1}, f.CachedPages())
+	_, err = f.Page(5)
+	require.ErrorContains(t, err, "out of range")
+	require.Equal(t, []int{4, 1}, f.CachedPages())
+	reread, err := f.Page(3)
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 4}, f.CachedPages())
+	require.Equal(t, 8, reread.Len())
+	_, err = NewBlockFileOf[int64](bytes.NewReader(pageBuf.Bytes()), int64(pageBuf.Len()), WithPageCache(0))
+	require.ErrorContains(t, err, "page cache")
+	uncached, err := NewBlockFileOf[int64](bytes.NewReader(pageBuf.Bytes()), int64(pageBuf.Len()))
+	require.NoError(t, err)
+	_, err = uncached.Page(0)
+	require.NoError(t, err)
+	require.Empty(t, uncached.CachedPages())
+
+	_, err = NewBlockFileOf[int64](bytes.NewReader([]byte("DENC")), 4)
+	require.Error(t, err)
+	var buf bytes.Buffer
+	_, err = InitDE().WriteTo(&buf)
+	require.NoError(t, err)
+	_, err = NewBlockFileOf[int64](bytes.NewReader(buf.Bytes()[:buf.Len()-1]), int64(buf.Len()-1))
+	require.ErrorIs(t, err, ErrCorruptBlock)
+	f, err = NewBlockFileOf[int64](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Zero(t, f.NumBlocks())
+	_, err = NewBlockFileOf[float64](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.ErrorContains(t, err, "holds int64 values")
+}
+
+// failWriter fails every write after the first n bytes.
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return 0, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestStreamEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc, err := NewStreamEncoder(&buf, WithCheckpointInterval(8), WithTSDeltaOfDelta())
+	require.NoError(t, err)
+	// Nothing is written until a block is complete.
+	for i := range 7 {
+		require.NoError(t, enc.Write(Row{ID: i + 1, Value: int64(i * 3), TS: int64(i) * 10}))
+	}
+	require.Zero(t, buf.Len())
+	require.NoError(t, enc.Write(Row{ID: 8, Value: 21, TS: 70}))
+	require.Equal(t, "DSTR", buf.String()[:4])
+	require.Equal(t, byte(streamVersion), buf.Bytes()[4])
+	afterBlock := buf.Len()
+
+	// Flush cuts the current block short, and a second Flush has nothing to do.
+	require.NoError(t, enc.Write(Row{ID: 9, Value: 24, TS: 80}))
+	require.Equal(t, afterBlock, buf.Len())
+	require.NoError(t, enc.Flush())
+	require.Greater(t, buf.Len(), afterBlock)
+	flushed := buf.Len()
+	require.NoError(t, enc.Flush())
+	require.Equal(t, flushed, buf.Len())
+
+	// An empty stream is just the header.
+	buf.Reset()
+	enc, err = NewStreamEncoder(&buf)
+	require.NoError(t, err)
+	require.NoError(t, enc.Flush())
+	require.Equal(t, 4+1+1+4+4, buf.Len())
+
+	// Flush flushes buffered writers too.
+	buf.Reset()
+	w := bufio.NewWriter(&buf)
+	enc, err = NewStreamEncoder(w)
+	require.NoError(t, err)
+	require.NoError(t, enc.Write(Row{ID: 1, Value: 5, TS: 10}))
+	require.NoError(t, enc.Flush())
+	require.Zero(t, w.Buffered())
+	require.Positive(t, buf.Len())
+
+	// A stream is about as small as the file of the same rows.
+	buf.Reset()
+	de := InitDE(WithCheckpointInterval(32))
+	enc, err = NewStreamEncoder(&buf, WithCheckpointInterval(32))
+	require.NoError(t, err)
+	for i := range 1000 {
+		row := Row{ID: i + 1, Value: 1000 + int64(i%13), TS: int64(i) * 15}
+		de.AppendRow(row)
+		require.NoError(t, enc.Write(row))
+	}
+	require.NoError(t, enc.Flush())
+	require.InDelta(t, de.Stats().FileBytes, buf.Len(), float64(de.Stats().FileBytes)/10)
+
+	// Write errors stick.
+	enc, err = NewStreamEncoder(&failWriter{n: 20}, WithCheckpointInterval(2))
+	require.NoError(t, err)
+	require.NoError(t, enc.Write(Row{ID: 1, Value: 1, TS: 1}))
+	require.ErrorContains(t, enc.Write(Row{ID: 2, Value: 2, TS: 2}), "disk full")
+	require.ErrorContains(t, enc.Write(Row{ID: 3, Value: 3, TS: 3}), "disk full")
+	require.ErrorContains(t, enc.Flush(), "disk full")
+
+	_, err = NewStreamEncoder(&buf, WithCheckpointInterval(0))
+	require.Error(t, err)
+}
+
+func TestStreamDecoder(t *testing.T) {
+	// decodeAll reads rows until Next fails and returns them with the error.
+	decodeAll := func(d *StreamDecoder) ([]Row, error) {
+		rows := []Row{}
+		for {
+			row, err := d.Next()
+			if err != nil {
+				return rows, err
+			}
+			rows = append(rows, row)
+		}
+	}
+	rows := make([]Row, 30)
+	for i := range rows {
+		rows[i] = Row{ID: 2*i + 1, Value: int64(i * i % 17), TS: 1000 + int64(i)*15 + int64(i%3)}
+	}
+	var buf bytes.Buffer
+	enc, err := NewStreamEncoder(&buf, WithCheckpointInterval(8), WithTSDeltaOfDelta())
+	require.NoError(t, err)
+	for i, row := range rows {
+		require.NoError(t, enc.Write(row))
+		if i == 10 {
+			require.NoError(t, enc.Flush()) // cuts the second block at 3 rows
+		}
+	}
+	require.NoError(t, enc.Flush())
+	stream := bytes.Clone(buf.Bytes())
+
+	t.Run("round trip", func(t *testing.T) {
+		d, err := NewStreamDecoder(bytes.NewReader(stream))
+		require.NoError(t, err)
+		decoded, err := decodeAll(d)
+		require.Equal(t, io.EOF, err)
+		require.Equal(t, rows, decoded)
+		_, err = d.Next()
+		require.Equal(t, io.EOF, err)
+
+		// An empty stream is just the header.
+		buf.Reset()
+		enc, err := NewStreamEncoder(&buf)
+		require.NoError(t, err)
+		require.NoError(t, enc.Flush())
+		d, err = NewStreamDecoder(&buf)
+		require.NoError(t, err)
+		_, err = d.Next()
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("pipelined through a pipe", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		go func() {
+			enc, err := NewStreamEncoder(pw, WithCheckpointInterval(4))
+			for _, row := range rows {
+				if err == nil {
+					err = enc.Write(row)
+				}
+			}
+			if err == nil {
+				err = enc.Flush()
+			}
+			pw.CloseWithError(err)
+		}()
+		d, err := NewStreamDecoder(pr)
+		require.NoError(t, err)
+		decoded, err := decodeAll(d)
+		require.Equal(t, io.EOF, err)
+		require.Equal(t, rows, decoded)
+	})
+
+	t.Run("compressed floats with sha256", func(t *testing.T) {
+		flateCompressor, err := FlateCompression(6)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		enc, err := NewStreamEncoderOf[float64](&buf, WithCheckpointInterval(4), WithCompression(flateCompressor), WithChecksum(SHA256))
+		require.NoError(t, err)
+		values := []float64{1.5, 2, 2.25, math.NaN(), 3, math.Inf(1), -4.5, 0, 0.75}
+		for i, v := range values {
+			require.NoError(t, enc.Write(RowOf[float64]{ID: i + 1, Value: v, TS: int64(i)}))
+		}
+		require.NoError(t, enc.Flush())
+
+		_, err = NewStreamDecoderOf[float64](bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err) // nothing is read yet
+		d, err := NewStreamDecoderOf[float64](bytes.NewReader(buf.Bytes()), WithCompression(flateCompressor))
+		require.NoError(t, err)
+		for i, v := range values {
+			row, err := d.Next()
+			require.NoError(t, err)
+			require.Equal(t, i+1, row.ID)
+			require.Equal(t, int64(i), row.TS)
+			if math.IsNaN(v) {
+				require.True(t, math.IsNaN(row.Value))
+			} else {
+				require.Equal(t, v, row.Value)
+			}
+		}
+		_, err = d.Next()
+		require.Equal(t, io.EOF, err)
+
+		d, err = NewStreamDecoderOf[float64](bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		_, err = d.Next()
+		require.ErrorContains(t, err, "WithCompression")
+		wrongType, err := NewStreamDecoder(bytes.NewReader(buf.Bytes()), WithCompression(flateCompressor))
+		require.NoError(t, err)
+		_, err = wrongType.Next()
+		require.ErrorContains(t, err, "float64 values")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		// Every cut either ends cleanly after a section with a prefix of the
+		// rows or is reported as a truncated section.
+		clean := 0
+		for cut := range len(stream) {
+			d, err := NewStreamDecoder(bytes.NewReader(stream[:cut]))
+			require.NoError(t, err)
+			decoded, err := decodeAll(d)
+			require.Equal(t, rows[:len(decoded)], decoded)
+			if err == io.EOF {
+				clean++
+				continue
+			}
+			var corrupt *CorruptionError
+			require.ErrorAs(t, err, &corrupt, "cut at %d", cut)
+			require.True(t, corrupt.Truncated)
+			require.ErrorIs(t, err, ErrCorruptBlock)
+		}
+		require.Equal(t, 5, clean) // after the header and each block but the last
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		corrupted := bytes.Clone(stream)
+		corrupted[len(corrupted)-6] ^= 0xff // in the last block
+		d, err := NewStreamDecoder(bytes.NewReader(corrupted))
+		require.NoError(t, err)
+		decoded, err := decodeAll(d)
+		require.Equal(t, rows[:27], decoded)
+		var corrupt *CorruptionError
+		require.ErrorAs(t, err, &corrupt)
+		require.Equal(t, "block", corrupt.Section)
+		require.Equal(t, 4, corrupt.Block)
+		require.NotEqual(t, corrupt.Stored, corrupt.Computed)
+
+		d, err = NewStreamDecoder(strings.NewReader("DENC" + string(stream[4:])))
+		require.NoError(t, err)
+		_, err = d.Next()
+		require.ErrorContains(t, err, "bad magic bytes")
+	})
+}
+
+func TestArbitraryRowIDs(t *testing.T) {
+	t.Run("increasing ids with gaps", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(2), WithVerification(true))
+		ids := []int{3, 4, 10, 11, 12, 40, 41, 100}
+		for i, id := range ids {
+			de.AppendRow(Row{ID: id, Value: int64(i * 10), TS: int64(i)})
+		}
+		requireCorrect(t, de)
+
+		row, err := de.ReconstructRow(40)
+		require.NoError(t, err)
+		require.Equal(t, Row{ID: 40, Value: 50, TS: 5}, row)
+		_, err = de.ReconstructRow(5)
+		require.Error(t, err)
+		_, err = de.ReconstructRow(1)
+		require.Error(t, err)
+
+		rows, err := de.ReconstructRange(5, 40)
+		require.NoError(t, err)
+		require.Equal(t, []int{10, 11, 12, 40}, rowIDs(rows))
+		rows, err = de.ReconstructRange(13, 39)
+		require.NoError(t, err)
+		require.Empty(t, rows)
+		_, err = de.ReconstructRange(1, 40)
+		require.Error(t, err)
+		_, err = de.ReconstructRange(3, 101)
+		require.Error(t, err)
+
+		agg, err := de.Aggregate(10, 12)
+		require.NoError(t, err)
+		require.Equal(t, Aggregates[int64]{Count: 3, Sum: 90, Min: 20, Max: 40}, agg)
+
+		require.NoError(t, de.UpdateRow(11, 31))
+		require.NoError(t, de.DeleteRow(12))
+		require.True(t, de.IsDeleted(12))
+		require.False(t, de.IsDeleted(13))
+		require.NoError(t, de.AttachExemplar(41, Exemplar{TraceID: "t"}))
+		exemplars, err := de.ExemplarsInRange(3, 100)
+		require.NoError(t, err)
+		require.Equal(t, []RowExemplar{{RowID: 41, Exemplar: Exemplar{TraceID: "t"}}}, exemplars)
+		requireCorrect(t, de)
+
+		block, err := de.Block(1)
+		require.NoError(t, err)
+		require.Equal(t, 10, block.FirstRowID)
+		require.Equal(t, 11, block.LastRowID)
+
+		path := filepath.Join(t.TempDir(), "gaps.bin")
+		require.NoError(t, de.WriteToFile(path))
+		loaded, err := LoadFromFile(path)
+		require.NoError(t, err)
+		row, err = loaded.ReconstructRow(11)
+		require.NoError(t, err)
+		require.Equal(t, int64(31), row.Value)
+	})
+
+	t.Run("unsorted and repeated ids", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(3))
+		ids := []int{7, 2, 9, 2, 5}
+		for i, id := range ids {
+			de.AppendRow(Row{ID: id, Value: int64(i + 1), TS: int64(i)})
+		}
+
+		for id, want := range map[int]int64{7: 1, 2: 2, 9: 3, 5: 5} {
+			row, err := de.ReconstructRow(id)
+			require.NoError(t, err)
+			require.Equal(t, want, row.Value)
+		}
+		table, err := de.ReconstructTable()
+		require.NoError(t, err)
+		require.Equal(t, ids, rowIDs(table))
+
+		_, err = de.ReconstructRange(2, 9)
+		require.ErrorContains(t, err, "needs increasing row ids")
+
+		require.NoError(t, de.DeleteRow(9))
+		require.True(t, de.IsDeleted(9))
+		require.True(t, de.Seal().IsDeleted(9))
+		row, err := de.Seal().ReconstructRow(5)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), row.Value)
+	})
+}
+
+func rowIDs[T Numeric](rows []RowOf[T]) []int {
+	ids := []int{}
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	return ids
+}
+
+func TestNonFiniteValues(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	values := []float64{inf, 1.5, nan, 2.5, math.Inf(-1), inf, 3, nan, 4.25}
+	newDE := func(opts ...Option) *DeltaEncodingOf[float64] {
+		de := InitDEOf[float64](append([]Option{WithCheckpointInterval(3), WithVerification(true), WithValueSamples(1)}, opts...)...)
+		for i, v := range values {
+			de.AppendRow(RowOf[float64]{ID: i + 1, Value: v, TS: int64(i)})
+		}
+		return de
+	}
+	requireValues := func(t *testing.T, want []float64, rows []RowOf[float64]) {
+		t.Helper()
+		require.Len(t, rows, len(want))
+		for i, row := range rows {
+			if math.IsNaN(want[i]) {
+				require.True(t, math.IsNaN(row.Value), "row %d: got %v", row.ID, row.Value)
+			} else {
+				require.Equal(t, want[i], row.Value, "row %d", row.ID)
+			}
+		}
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		de := newDE()
+		require.Equal(t, 5, de.NonFinite())
+		requireCorrect(t, de)
+		rows, err := de.ReconstructTable()
+		require.NoError(t, err)
+		requireValues(t, values, rows)
+		requireValues(t, values, slices.Collect(de.All()))
+
+		// Finite values around them still decode exactly from any checkpoint.
+		row, err := de.ReconstructRow(9)
+		require.NoError(t, err)
+		require.Equal(t, 4.25, row.Value)
+		require.NoError(t, de.Recheckpoint(2))
+		rows, err = de.ReconstructTable()
+		require.NoError(t, err)
+		requireValues(t, values, rows)
+
+		median, err := de.ApproxMedian(1, 9)
+		require.NoError(t, err)
+		require.False(t, math.IsNaN(median))
+
+		var buf bytes.Buffer
+		_, err = de.WriteTo(&buf)
+		require.NoError(t, err)
+		loaded, err := decode[float64](buf.Bytes())
+		require.NoError(t, err)
+		rows, err = loaded.ReconstructTable()
+		require.NoError(t, err)
+		requireValues(t, values, rows)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		de := newDE()
+		require.NoError(t, de.UpdateRow(3, 2))
+		require.NoError(t, de.UpdateRow(4, nan))
+		require.NoError(t, de.UpdateRow(1, 1))
+		require.NoError(t, de.UpdateRow(9, inf))
+		want := slices.Clone(values)
+		want[2], want[3], want[0], want[8] = 2, nan, 1, inf
+		rows, err := de.ReconstructTable()
+		require.NoError(t, err)
+		requireValues(t, want, rows)
+		requireCorrect(t, de)
+		require.Equal(t, 5, de.NonFinite())
+
+		de.AppendRow(RowOf[float64]{ID: 10, Value: 5, TS: 10})
+		row, err := de.ReconstructRow(10)
+		require.NoError(t, err)
+		require.Equal(t, 5.0, row.Value)
+	})
+
+	t.Run("aggregation policy", func(t *testing.T) {
+		agg, err := newDE().Aggregate(1, 9)
+		require.NoError(t, err)
+		require.Equal(t, 9, agg.Count)
+		require.True(t, math.IsNaN(agg.Sum))
+		require.True(t, math.IsNaN(agg.Min))
+
+		agg, err = newDE().Aggregate(1, 2)
+		require.NoError(t, err)
+		require.True(t, math.IsInf(agg.Sum, 1))
+		require.Equal(t, 1.5, agg.Min)
+
+		agg, err = newDE(WithNonFiniteAggregation(SkipNonFinite)).Aggregate(1, 9)
+		require.NoError(t, err)
+		require.Equal(t, Aggregates[float64]{Count: 4, Sum: 11.25, Min: 1.5, Max: 4.25}, agg)
+	})
+}
+
+func TestNullValues(t *testing.T) {
+	newDE := func() *DeltaEncoding {
+		de := InitDE(WithCheckpointInterval(3), WithVerification(true), WithValueSamples(1))
+		de.AppendRow(Row{ID: 1, Value: 100, TS: 10})
+		de.AppendNull(2, 20)
+		de.AppendRow(Row{ID: 3, Value: 103, TS: 30})
+		de.AppendNull(4, 40)
+		de.AppendNull(5, 50)
+		de.AppendRow(Row{ID: 6, Value: 0, TS: 60})
+		de.AppendRow(Row{ID: 7, Value: 107, TS: 70})
+		return de
+	}
+	values := []int64{100, 0, 103, 0, 0, 0, 107}
+	nulls := []bool{false, true, false, true, true, false, false}
+	requireNulls := func(t *testing.T, de interface {
+		ValueOf(int) (int64, bool, error)
+	}, values []int64, nulls []bool) {
+		t.Helper()
+		for i := range values {
+			value, ok, err := de.ValueOf(i + 1)
+			require.NoError(t, err)
+			require.Equal(t, values[i], value, "row %d", i+1)
+			require.Equal(t, !nulls[i], ok, "row %d", i+1)
+		}
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		de := newDE()
+		require.Equal(t, 3, de.Nulls())
+		require.True(t, de.IsNull(4))
+		require.False(t, de.IsNull(6))
+		require.False(t, de.IsNull(99))
+		requireCorrect(t, de)
+		requireNulls(t, de, values, nulls)
+
+		// Nulls do not break the delta chain: the row after them is one delta
+		// away from the last non-null value.
+		require.Equal(t, []int64{0, 0, 3, 0, 0, -103, 107}, de.deltaValueList)
+		require.NoError(t, de.Recheckpoint(2))
+		requireNulls(t, de, values, nulls)
+
+		_, _, err := de.ValueOf(99)
+		require.Error(t, err)
+
+		var buf bytes.Buffer
+		_, err = de.WriteTo(&buf)
+		require.NoError(t, err)
+		loaded, err := decode[int64](buf.Bytes())
+		require.NoError(t, err)
+		requireNulls(t, loaded, values, nulls)
+		requireNulls(t, de.Seal(), values, nulls)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		de := newDE()
+		require.NoError(t, de.UpdateRow(4, 104))
+		want, wantNulls := slices.Clone(values), slices.Clone(nulls)
+		want[3], wantNulls[3] = 104, false
+		requireNulls(t, de, want, wantNulls)
+		requireCorrect(t, de)
+		require.Equal(t, 2, de.Nulls())
+	})
+
+	t.Run("aggregate skips nulls", func(t *testing.T) {
+		agg, err := newDE().Aggregate(1, 7)
+		require.NoError(t, err)
+		require.Equal(t, Aggregates[int64]{Count: 4, Sum: 310, Min: 0, Max: 107}, agg)
+	})
+}
+
+func TestMemoryUsage(t *testing.T) {
+	require.Zero(t, InitDE().MemoryUsage().Total())
+
+	de := InitDE(WithCheckpointInterval(4))
+	for i := range 100 {
+		de.AppendRow(Row{ID: i + 1, Value: int64(i), TS: int64(i)})
+	}
+	m := de.MemoryUsage()
+	require.GreaterOrEqual(t, m.IDs, 100*8)
+	require.GreaterOrEqual(t, m.ValueDeltas, 100*8)
+	require.GreaterOrEqual(t, m.TSDeltas, 100*8)
+	require.GreaterOrEqual(t, m.Checkpoints, 2*26*8)
+	require.Zero(t, m.Originals)
+	require.Zero(t, m.Indexes)
+	require.Zero(t, m.Bitmaps)
+	require.Zero(t, m.SideColumns)
+	require.Zero(t, m.Buffered)
+
+	// Verification retains the original rows, which shows up on its own line.
+	verified := InitDE(WithCheckpointInterval(4), WithVerification(true), WithTimeBucketIndex(10))
+	for i := range 100 {
+		verified.AppendRow(Row{ID: i + 1, Value: int64(i), TS: int64(i)})
+	}
+	require.NoError(t, verified.DeleteRow(3))
+	require.NoError(t, verified.AttachExemplar(5, Exemplar{TraceID: "abc", Labels: map[string]string{"pod": "a"}}))
+	vm := verified.MemoryUsage()
+	require.GreaterOrEqual(t, vm.Originals, 100*int(unsafe.Sizeof(Row{})))
+	require.Positive(t, vm.Indexes)
+	require.Positive(t, vm.Bitmaps)
+	require.Positive(t, vm.SideColumns)
+	require.Greater(t, vm.Total(), m.Total())
+
+	// Sealing trims the slices to their length.
+	sealed := de.Seal().MemoryUsage()
+	require.Equal(t, 100*8, sealed.IDs)
+	require.LessOrEqual(t, sealed.Total(), m.Total())
+}
+
+func TestPlainBlockFallback(t *testing.T) {
+	// Values alternating between large positive and negative numbers have
+	// deltas twice their size.
+	adversarial := func(i int) int64 {
+		if i%2 == 0 {
+			return 1 << 40
+		}
+		return -1 << 40
+	}
+	newDE := func(opts ...Option) *DeltaEncoding {
+		de := InitDE(append([]Option{WithCheckpointInterval(4), WithVerification(true)}, opts...)...)
+		for i := range 20 {
+			value := int64(i)
+			if i >= 8 {
+				value = adversarial(i)
+			}
+			if i == 13 {
+				de.AppendNull(i+1, int64(i))
+				continue
+			}
+			de.AppendRow(Row{ID: i + 1, Value: value, TS: int64(i)})
+		}
+		return de
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"delta ts", nil},
+		{"delta-of-delta ts", []Option{WithTSDeltaOfDelta()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			de := newDE(tc.opts...)
+			stats := de.Stats()
+			// Blocks 2 and 4 fall back; the null in block 3 carries the chain
+			// value over, which keeps its deltas small.
+			require.Equal(t, 6, stats.Blocks)
+			require.Equal(t, 2, stats.PlainBlocks)
+
+			var buf bytes.Buffer
+			_, err := de.WriteTo(&buf)
+			require.NoError(t, err)
+			require.Equal(t, stats.FileBytes, buf.Len())
+			loaded, err := decode[int64](buf.Bytes(), WithVerification(true))
+			require.NoError(t, err)
+			requireCorrect(t, loaded)
+			want, err := de.ReconstructTable()
+			require.NoError(t, err)
+			got, err := loaded.ReconstructTable()
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+			require.True(t, loaded.IsNull(14))
+		})
+	}
+
+	t.Run("float values", func(t *testing.T) {
+		// Floats take 8 bytes either way; an irregular ts column still falls back.
+		de := InitDEOf[float64](WithCheckpointInterval(4))
+		for i := range 12 {
+			ts := int64(i)
+			if i%2 == 1 {
+				ts = 1 << 40
+			}
+			de.AppendRow(RowOf[float64]{ID: i + 1, Value: 0.1 * float64(i), TS: ts})
+		}
+		de.AppendRow(RowOf[float64]{ID: 13, Value: math.NaN(), TS: 12})
+		require.Positive(t, de.Stats().PlainBlocks)
+
+		var buf bytes.Buffer
+		_, err := de.WriteTo(&buf)
+		require.NoError(t, err)
+		loaded, err := decode[float64](buf.Bytes())
+		require.NoError(t, err)
+		for i := range 12 {
+			want, err := de.ReconstructRow(i + 1)
+			require.NoError(t, err)
+			got, err := loaded.ReconstructRow(i + 1)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		}
+		row, err := loaded.ReconstructRow(13)
+		require.NoError(t, err)
+		require.True(t, math.IsNaN(row.Value))
+	})
+
+	t.Run("regular data stays delta encoded", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(4))
+		for i := range 20 {
+			de.AppendRow(Row{ID: i + 1, Value: int64(1000 + i), TS: int64(1_700_000_000 + i)})
+		}
+		require.Zero(t, de.Stats().PlainBlocks)
+	})
+}
+
+func TestReconstructColumn(t *testing.T) {
+	requireColumns := func(t *testing.T, de *DeltaEncodingOf[float64], fromID, toID int) {
+		t.Helper()
+		rows, err := de.ReconstructRange(fromID, toID)
+		require.NoError(t, err)
+		ids, err := de.ReconstructColumn(ColumnID, fromID, toID)
+		require.NoError(t, err)
+		values, err := de.ReconstructColumn(ColumnValue, fromID, toID)
+		require.NoError(t, err)
+		ts, err := de.ReconstructColumn(ColumnTS, fromID, toID)
+		require.NoError(t, err)
+		require.Nil(t, values.IDs)
+		require.Nil(t, values.TS)
+		require.Len(t, values.Values, len(rows))
+		for i, row := range rows {
+			require.Equal(t, row.ID, ids.IDs[i])
+			require.Equal(t, row.TS, ts.TS[i])
+			if math.IsNaN(row.Value) {
+				require.True(t, math.IsNaN(values.Values[i]))
+			} else {
+				require.Equal(t, row.Value, values.Values[i])
+			}
+		}
+	}
+
+	for _, tsDeltaOfDelta := range []bool{false, true} {
+		opts := []Option{WithCheckpointInterval(3)}
+		if tsDeltaOfDelta {
+			opts = append(opts, WithTSDeltaOfDelta())
+		}
+		de := InitDEOf[float64](opts...)
+		for i := range 20 {
+			switch i {
+			case 5:
+				de.AppendRow(RowOf[float64]{ID: i + 1, Value: math.NaN(), TS: int64(i * i)})
+			case 9:
+				de.AppendNull(i+1, int64(i*i))
+			default:
+				de.AppendRow(RowOf[float64]{ID: i + 1, Value: float64(i) * 1.5, TS: int64(i * i)})
+			}
+		}
+		require.NoError(t, de.DeleteRow(8))
+		requireColumns(t, de, 1, 20)
+		requireColumns(t, de, 5, 11)
+		requireColumns(t, de, 8, 8)
+		requireColumns(t, de, 20, 20)
+	}
+
+	de := InitDE()
+	de.AppendRow(Row{ID: 1, Value: 1, TS: 1})
+	_, err := de.ReconstructColumn("name", 1, 1)
+	require.ErrorContains(t, err, "unknown column")
+	_, err = de.ReconstructColumn(ColumnValue, 1, 2)
+	require.Error(t, err)
+	column, err := de.Seal().ReconstructColumn(ColumnTS, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, Column{TS: []int64{1}}, column)
+}
+
+func TestDecodeInto(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(8), WithTSDeltaOfDelta())
+	for i := range 1000 {
+		de.AppendRow(Row{ID: i + 1, Value: int64(i * i % 97), TS: int64(i * 15)})
+	}
+	require.NoError(t, de.DeleteRow(500))
+
+	values := make([]int64, 1000)
+	ts := make([]int64, 1000)
+	ids := make([]int, 1000)
+	for _, r := range [][2]int{{1, 1000}, {3, 3}, {490, 510}, {997, 1000}} {
+		rows, err := de.ReconstructRange(r[0], r[1])
+		require.NoError(t, err)
+		n, err := de.DecodeValuesInto(values, r[0], r[1])
+		require.NoError(t, err)
+		require.Equal(t, len(rows), n)
+		_, err = de.DecodeTSInto(ts, r[0], r[1])
+		require.NoError(t, err)
+		_, err = de.DecodeIDsInto(ids, r[0], r[1])
+		require.NoError(t, err)
+		for i, row := range rows {
+			require.Equal(t, row, Row{ID: ids[i], Value: values[i], TS: ts[i]})
+		}
+	}
+
+	_, err := de.DecodeValuesInto(values[:10], 1, 11)
+	require.ErrorContains(t, err, "buffer holds 10 rows")
+	_, err = de.DecodeTSInto(ts, 0, 5)
+	require.Error(t, err)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = de.DecodeValuesInto(values, 1, 1000)
+		_, _ = de.DecodeTSInto(ts, 1, 1000)
+	})
+	require.Zero(t, allocs)
+
+	n, err := de.Seal().DecodeValuesInto(values, 10, 12)
+	require.NoError(t, err)
+	require.Equal(t, []int64{81, 3, 24}, values[:n])
+}
+
+func TestSealChoosesEncodings(t *testing.T) {
+	roundTrip := func(t *testing.T, de *DeltaEncoding) int {
+		t.Helper()
+		sealed := de.Seal()
+		var buf bytes.Buffer
+		_, err := sealed.WriteTo(&buf)
+		require.NoError(t, err)
+		loaded, err := decode[int64](buf.Bytes())
+		require.NoError(t, err)
+		want, err := de.ReconstructTable()
+		require.NoError(t, err)
+		got, err := loaded.ReconstructTable()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Equal(t, de.Nulls(), loaded.Nulls())
+		return buf.Len()
+	}
+	unsealedSize := func(de *DeltaEncoding) int {
+		var buf bytes.Buffer
+		_, err := de.WriteTo(&buf)
+		require.NoError(t, err)
+		return buf.Len()
+	}
+
+	t.Run("counter", func(t *testing.T) {
+		// A constant rate makes every second difference 0.
+		de := InitDE(WithCheckpointInterval(16))
+		for i := range 1000 {
+			de.AppendRow(Row{ID: i + 1, Value: int64(i) * 1000, TS: int64(i) * 1000})
+		}
+		require.Equal(t, ColumnEncodings{Value: EncodingDeltaOfDelta, TS: EncodingDeltaOfDelta}, de.Seal().Encodings())
+		require.Less(t, roundTrip(t, de), unsealedSize(de))
+	})
+
+	t.Run("random values", func(t *testing.T) {
+		de := InitDE(WithCheckpointInterval(16))
+		r := rand.New(rand.NewSource(1))
+		for i := range 1000 {
+			if i == 500 {
+				de.AppendNull(i+1, int64(i))
+				continue
+			}
+			de.AppendRow(Row{ID: i + 1, Value: r.Int63n(1<<35) - 1<<34, TS: int64(i)})
+		}
+		require.NoError(t, de.DeleteRow(10))
+		// 35 random bits per value pack tighter than plain varints, and the
+		// ts offsets within a block of 16 take 4 bits.
+		require.Equal(t, ColumnEncodings{Value: EncodingFOR, TS: EncodingFOR}, de.Seal().Encodings())
+		roundTrip(t, de)
+		// Unsealed files fall back block by block, which ends up close.
+		require.Positive(t, de.Stats().PlainBlocks)
+	})
+
+	t.Run("gauge keeps deltas", func(t *testing.T) {
+		// A random walk with large steps: offsets from the block minimum grow
+		// with the walk, while each delta stays small.
+		de := InitDE(WithCheckpointInterval(16), WithTSDeltaOfDelta())
+		r := rand.New(rand.NewSource(1))
+		value := int64(1 << 30)
+		for i := range 1000 {
+			value += r.Int63n(101) - 50
+			de.AppendRow(Row{ID: i + 1, Value: value, TS: int64(i) * 10})
+		}
+		require.Equal(t, ColumnEncodings{Value: EncodingDelta, TS: EncodingDeltaOfDelta}, de.Seal().Encodings())
+		require.Equal(t, unsealedSize(de), roundTrip(t, de))
+	})
+
+	t.Run("narrow gauge", func(t *testing.T) {
+		// Values cycling through a small range cost 3 bits each as offsets.
+		de := InitDE(WithCheckpointInterval(16), WithTSDeltaOfDelta())
+		for i := range 1000 {
+			de.AppendRow(Row{ID: i + 1, Value: 1<<30 + int64(i%7), TS: int64(i) * 10})
+		}
+		require.Equal(t, ColumnEncodings{Value: EncodingFOR, TS: EncodingDeltaOfDelta}, de.Seal().Encodings())
+		require.Less(t, roundTrip(t, de), unsealedSize(de))
+	})
+
+	t.Run("float values", func(t *testing.T) {
+		de := InitDEOf[float64](WithCheckpointInterval(8))
+		for i := range 100 {
+			de.AppendRow(RowOf[float64]{ID: i + 1, Value: float64(i) * 0.1, TS: int64(i) * 1000})
+		}
+		de.AppendRow(RowOf[float64]{ID: 101, Value: math.Inf(1), TS: 100_000})
+		sealed := de.Seal()
+		require.Equal(t, ColumnEncodings{Value: EncodingDelta, TS: EncodingDeltaOfDelta}, sealed.Encodings())
+		var buf bytes.Buffer
+		_, err := sealed.WriteTo(&buf)
+		require.NoError(t, err)
+		loaded, err := decode[float64](buf.Bytes())
+		require.NoError(t, err)
+		want, err := de.ReconstructTable()
+		require.NoError(t, err)
+		got, err := loaded.ReconstructTable()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+
+	require.Equal(t, "delta-of-delta", EncodingDeltaOfDelta.String())
+	require.Equal(t, ColumnEncodings{}, InitDE().Seal().Encodings())
+}
+
+func TestFrameOfReference(t *testing.T) {
+	for _, values := range [][]int64{
+		{},
+		{7, 7, 7},
+		{5, 6},
+		{-3, 4, 0, 1, -3, 2, 2, 4, 0},
+		{1000, 1255, 1100},
+		{1000, 1256, 1100},
+		{math.MinInt64, math.MaxInt64, 0, -1},
+	} {
+		buf := appendFOR(nil, values)
+		r := &fileReader{buf: buf}
+		require.Equal(t, values, readFOR(r, len(values)))
+		require.NoError(t, r.done())
+		if len(values) > 0 {
+			// Any offset can be read without decoding the rows before it.
+			lo, n := binary.Varint(buf)
+			width, packed := int(buf[n]), buf[n+1:]
+			for i := len(values) - 1; i >= 0; i-- {
+				require.Equal(t, values[i], int64(uint64(lo)+unpackOffset(packed, width, i)))
+			}
+		}
+	}
+	r := &fileReader{buf: appendFOR(nil, []int64{1, 2, 3})[:2]}
+	readFOR(r, 3)
+	require.ErrorIs(t, r.done(), ErrCorruptBlock)
+
+	// A gauge within a narrow range: 3-bit offsets beat 1-byte deltas, while
+	// the constant-rate ts compresses best as delta-of-deltas.
+	de := InitDE(WithCheckpointInterval(32))
+	for i := range 320 {
+		de.AppendRow(Row{ID: i + 1, Value: 500 + int64(i*5%7), TS: int64(i) * 60})
+	}
+	stats := de.Stats()
+	require.Less(t, stats.ValueFORBytes, stats.ValueBytes)
+	require.Less(t, stats.TSDeltaOfDeltaBytes, stats.TSFORBytes)
+	require.Equal(t, "frame-of-reference", EncodingFOR.String())
+
+	sealed := de.Seal()
+	require.Equal(t, EncodingFOR, sealed.Encodings().Value)
+	var buf bytes.Buffer
+	_, err := sealed.WriteTo(&buf)
+	require.NoError(t, err)
+	loaded, err := decode[int64](buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, reconstructTable(t, de), reconstructTable(t, loaded))
+
+	// A constant column packs at width 0, so its rows take no value bytes.
+	for _, interval := range []int{4, 64, 1024} {
+		constant := InitDE(WithCheckpointInterval(interval))
+		for i := range 5000 {
+			constant.AppendRow(Row{ID: i + 1, Value: 7, TS: int64(i)})
+		}
+		path := filepath.Join(t.TempDir(), "constant.bin")
+		require.NoError(t, constant.Seal().WriteToFile(path))
+		loaded, err := LoadFromFile(path)
+		require.NoError(t, err)
+		require.Equal(t, reconstructTable(t, constant), reconstructTable(t, loaded))
+		f, err := OpenBlockFile(path)
+		require.NoError(t, err)
+		all, err := f.LoadBlocks(0, f.NumBlocks())
+		require.NoError(t, err)
+		require.Equal(t, reconstructTable(t, constant), reconstructTable(t, all))
+		require.NoError(t, f.Close())
+	}
+}
+
+func TestTransform(t *testing.T) {
+	build := func() *DeltaEncoding {
+		de := InitDE(WithCheckpointInterval(3), WithVerification(true), WithValueSamples(1))
+		for i := range 20 {
+			if i == 7 {
+				de.AppendNull(i+1, int64(i*10))
+				continue
+			}
+			de.AppendRow(Row{ID: i + 1, Value: int64(100 + i*i - 3*i), TS: int64(i * 10)})
+		}
+		require.NoError(t, de.DeleteRow(4))
+		return de
+	}
+	requireTransformed := func(t *testing.T, src, out *DeltaEncoding, f func(int64) int64) {
+		t.Helper()
+		want, err := src.ReconstructTable()
+		require.NoError(t, err)
+		got, err := out.ReconstructTable()
+		require.NoError(t, err)
+		require.Len(t, got, len(want))
+		for i, row := range want {
+			if !src.IsNull(row.ID) {
+				row.Value = f(row.Value)
+			}
+			require.Equal(t, row, got[i])
+		}
+		require.True(t, out.IsDeleted(4))
+		require.True(t, out.IsNull(8))
+		ok, err := out.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	t.Run("affine keeps deltas", func(t *testing.T) {
+		de := build()
+		out := de.Transform(Affine[int64](3, 5), Affine[int64](-2, 1))
+		requireTransformed(t, de, out, func(v int64) int64 { return (v*3+5)*-2 + 1 })
+		for i, d := range de.deltaValueList {
+			require.Equal(t, d*-6, out.deltaValueList[i])
+		}
+		require.Equal(t, de.deltaTsList, out.deltaTsList)
+
+		// The result still appends and answers sample queries.
+		out.AppendRow(Row{ID: 21, Value: 7, TS: 200})
+		row, err := out.ReconstructRow(21)
+		require.NoError(t, err)
+		require.Equal(t, int64(7), row.Value)
+		median, err := out.ApproxMedian(1, 3)
+		require.NoError(t, err)
+		require.Equal(t, (int64(98)*3+5)*-2+1, median)
+	})
+
+	t.Run("map re-encodes", func(t *testing.T) {
+		de := build()
+		out := de.Transform(Affine[int64](2, 0), Clamp[int64](150, 400))
+		requireTransformed(t, de, out, func(v int64) int64 { return min(max(v*2, 150), 400) })
+		last, err := out.ReconstructRow(20)
+		require.NoError(t, err)
+		require.Equal(t, int64(400), last.Value)
+	})
+
+	t.Run("float unit conversion", func(t *testing.T) {
+		de := InitDEOf[float64](WithCheckpointInterval(4))
+		for i := range 10 {
+			value := float64(i) * 2.5
+			if i == 3 {
+				value = math.Inf(1)
+			}
+			de.AppendRow(RowOf[float64]{ID: i + 1, Value: value, TS: int64(i)})
+		}
+		out := de.Transform(Affine(1.8, 32.0))
+		rows, err := out.ReconstructTable()
+		require.NoError(t, err)
+		for i, row := range rows {
+			if i == 3 {
+				require.True(t, math.IsInf(row.Value, 1))
+				continue
+			}
+			require.InDelta(t, float64(i)*2.5*1.8+32, row.Value, 1e-9)
+		}
+	})
+}
+
+func TestAffineColumnMath(t *testing.T) {
+	build := func() *DeltaEncoding {
+		de := InitDE(WithCheckpointInterval(4), WithVerification(true), WithValueSamples(1), WithTimeBucketIndex(100))
+		for i := range 30 {
+			if i == 11 {
+				de.AppendNull(i+1, int64(1000+i*15))
+				continue
+			}
+			de.AppendRow(Row{ID: i + 1, Value: int64(50 + (i*37)%23 - i), TS: int64(1000 + i*15)})
+		}
+		require.NoError(t, de.DeleteRow(6))
+		return de
+	}
+	requireMapped := func(t *testing.T, want []Row, de *DeltaEncoding, f func(Row) Row) {
+		t.Helper()
+		got, err := de.ReconstructTable()
+		require.NoError(t, err)
+		require.Len(t, got, len(want))
+		for i, row := range want {
+			if !de.IsNull(row.ID) {
+				row = f(row)
+			} else {
+				row.TS = f(row).TS
+			}
+			require.Equal(t, row, got[i])
+		}
+		ok, err := de.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	t.Run("AddConstant", func(t *testing.T) {
+		de := build()
+		want, err := de.ReconstructTable()
+		require.NoError(t, err)
+		deltas := slices.Clone(de.deltaValueList)
+		de.AddConstant(-1000)
+		require.Equal(t, deltas, de.deltaValueList)
+		requireMapped(t, want, de, func(r Row) Row { r.Value -= 1000; return r })
+
+		de.AppendRow(Row{ID: 31, Value: 5, TS: 2000})
+		row, err := de.ReconstructRow(31)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), row.Value)
+	})
+
+	t.Run("Scale", func(t *testing.T) {
+		de := build()
+		want, err := de.ReconstructTable()
+		require.NoError(t, err)
+		de.Scale(-3)
+		requireMapped(t, want, de, func(r Row) Row { r.Value *= -3; return r })
+		min, max, err := de.MinMax(1, 30)
+		require.NoError(t, err)
+		wantMin, wantMax, err := build().MinMax(1, 30)
+		require.NoError(t, err)
+		require.Equal(t, wantMax*-3, min)
+		require.Equal(t, wantMin*-3, max)
+		// Samples are scaled and re-sorted, so percentiles flip.
+		require.Equal(t, []int64{-198, -189, -159, -150}, de.blockSamples[0])
+		p100, err := de.ApproxPercentile(1, 30, 100)
+		require.NoError(t, err)
+		require.Equal(t, wantMin*-3, p100)
+	})
+
+	t.Run("ShiftTime", func(t *testing.T) {
+		for _, delta := range []int64{500, -37} {
+			de := build()
+			want, err := de.ReconstructTable()
+			require.NoError(t, err)
+			de.ShiftTime(delta)
+			requireMapped(t, want, de, func(r Row) Row { r.TS += delta; return r })
+
+			fresh := InitDE(WithCheckpointInterval(4), WithTimeBucketIndex(100))
+			for _, row := range want {
+				fresh.AppendRow(Row{ID: row.ID, Value: row.Value, TS: row.TS + delta})
+			}
+			for from := int64(900); from < 1600; from += 50 {
+				require.Equal(t, fresh.CountInTimeRange(from, from+199), de.CountInTimeRange(from, from+199), "delta %d from %d", delta, from)
+			}
+		}
+	})
+
+	t.Run("float", func(t *testing.T) {
+		de := InitDEOf[float64](WithCheckpointInterval(3))
+		for i := range 10 {
+			de.AppendRow(RowOf[float64]{ID: i + 1, Value: float64(i) * 0.5, TS: int64(i)})
+		}
+		de.AppendRow(RowOf[float64]{ID: 11, Value: math.Inf(-1), TS: 10})
+		de.Scale(4)
+		de.AddConstant(1.5)
+		rows, err := de.ReconstructTable()
+		require.NoError(t, err)
+		for i, row := range rows[:10] {
+			require.InDelta(t, float64(i)*2+1.5, row.Value, 1e-9)
+		}
+		require.True(t, math.IsInf(rows[10].Value, -1))
+	})
+}
+
+func TestSentinelErrors(t *testing.T) {
+	de := InitDE()
+	_, err := de.ReconstructRange(1, 5)
+	require.ErrorIs(t, err, ErrEmptyEncoding)
+	_, err = de.Block(0)
+	require.ErrorIs(t, err, ErrEmptyEncoding)
+
+	for i := range 10 {
+		de.AppendRow(Row{ID: i + 1, Value: int64(i), TS: int64(i)})
+	}
+	require.NoError(t, de.DeleteRow(3))
+	_, err = de.ReconstructRow(11)
+	require.ErrorIs(t, err, ErrRowNotFound)
+	require.ErrorContains(t, err, "id 11")
+	require.ErrorIs(t, de.UpdateRow(42, 1), ErrRowNotFound)
+	require.ErrorIs(t, de.AttachExemplar(0, Exemplar{TraceID: "t"}), ErrRowNotFound)
+	require.ErrorIs(t, de.DeleteRow(12), ErrRowNotFound)
+	_, err = de.ReconstructRow(3)
+	require.ErrorIs(t, err, ErrRowDeleted)
+	require.ErrorIs(t, de.UpdateRow(3, 1), ErrRowDeleted)
+	require.ErrorIs(t, de.DeleteRow(3), ErrRowDeleted)
+	require.NotErrorIs(t, err, ErrRowNotFound)
+
+	// Corruption errors are distinct from missing rows.
+	_, err = decode[int64]([]byte("DENC\x02\x03abc\x00\x00\x00\x00"))
+	require.ErrorIs(t, err, ErrCorruptBlock)
+	require.NotErrorIs(t, err, ErrRowNotFound)
+}
+
+func TestCheckInvariants(t *testing.T) {
+	segment := func(rows ...Row) *DeltaEncoding {
+		de := InitDE()
+		de.AppendRows(rows)
+		return de
+	}
+	hour1 := segment(Row{ID: 1, TS: 10}, Row{ID: 2, TS: 20}, Row{ID: 3, TS: 20})
+	hour2 := segment(Row{ID: 4, TS: 30}, Row{ID: 5, TS: 40})
+	require.Empty(t, CheckInvariants(hour1, hour2))
+	require.Empty(t, hour1.CheckInvariants())
+
+	// hour3 starts before hour2 ends and reuses id 2.
+	hour3 := segment(Row{ID: 6, TS: 35}, Row{ID: 2, TS: 50})
+	violations := CheckInvariants(hour1, hour2, hour3)
+	require.Equal(t, []Violation{
+		{Constraint: ConstraintMonotonicTS, Segment: 2, RowID: 6, TS: 35, Detail: "ts 35 is before ts 40 of row 5 in segment 1"},
+		{Constraint: ConstraintUniqueID, Segment: 2, RowID: 2, TS: 50, Detail: "id already used in segment 0"},
+	}, violations)
+
+	// Deleted rows do not count.
+	require.NoError(t, hour3.DeleteRow(6))
+	require.Len(t, CheckInvariants(hour1, hour2, hour3), 1)
+
+	de := segment(Row{ID: 1, TS: 10}, Row{ID: 2, TS: 5}, Row{ID: 3, TS: 20})
+	_, err := de.SealChecked()
+	var invariant *InvariantError
+	require.ErrorAs(t, err, &invariant)
+	require.Len(t, invariant.Violations, 1)
+	require.ErrorContains(t, err, "1 invariant violations; monotonic-ts: segment 0 row 2")
+	require.NoError(t, de.DeleteRow(2))
+	sealed, err := de.SealChecked()
+	require.NoError(t, err)
+	require.Empty(t, sealed.CheckInvariants())
+}
+
+func reconstructTable[T Numeric](t *testing.T, de *DeltaEncodingOf[T]) []RowOf[T] {
+	t.Helper()
+	rows, err := de.ReconstructTable()
+	require.NoError(t, err)
+	return rows
+}
+
+func TestMerge(t *testing.T) {
+	hour := func(start, rows int) *DeltaEncoding {
+		de := InitDE(WithCheckpointInterval(4), WithVerification(true), WithTimeBucketIndex(100))
+		for i := range rows {
+			id := start + i
+			de.AppendRow(Row{ID: id, Value: int64(1000 + id*7 - (id%5)*20), TS: int64(id * 10)})
+		}
+		return de
+	}
+
+	t.Run("concatenate", func(t *testing.T) {
+		first, second := hour(1, 10), hour(11, 7)
+		require.NoError(t, first.DeleteRow(3))
+		second.AppendNull(18, 180)
+		require.NoError(t, second.AttachExemplar(12, Exemplar{TraceID: "abc"}))
+
+		merged, err := first.Merge(second)
+		require.NoError(t, err)
+		require.Equal(t, 18, merged.Len())
+		require.Equal(t, 10, first.Len())
+		// The prefix is copied; the boundary delta is recomputed from row 10.
+		require.Equal(t, first.deltaValueList, merged.deltaValueList[:10])
+		require.Equal(t, int64(1000+11*7-20)-int64(1000+10*7), merged.deltaValueList[10])
+		require.Equal(t, merged.checkpointValues, func() []int64 {
+			want := hour(1, 17)
+			want.AppendNull(18, 180)
+			return want.checkpointValues
+		}())
+
+		want := append(reconstructTable(t, first), reconstructTable(t, second)...)
+		require.Equal(t, want, reconstructTable(t, merged))
+		require.True(t, merged.IsDeleted(3))
+		require.True(t, merged.IsNull(18))
+		ex, ok := merged.Exemplar(12)
+		require.True(t, ok)
+		require.Equal(t, "abc", ex.TraceID)
+		require.Equal(t, 3, merged.CountInTimeRange(100, 120))
+		ok, err = merged.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("interleave", func(t *testing.T) {
+		odd, even := InitDE(WithCheckpointInterval(3)), InitDE(WithCheckpointInterval(5))
+		for i := 1; i <= 20; i++ {
+			row := Row{ID: i, Value: int64(i * i), TS: int64(i * 10)}
+			if i%2 == 1 {
+				odd.AppendRow(row)
+			} else {
+				even.AppendRow(row)
+			}
+		}
+		require.NoError(t, even.DeleteRow(8))
+		merged, err := odd.Merge(even)
+		require.NoError(t, err)
+		require.Equal(t, 3, merged.CheckpointInterval())
+		rows := reconstructTable(t, merged)
+		require.Len(t, rows, 19)
+		for i, row := range rows {
+			id := i + 1
+			if id >= 8 {
+				id++
+			}
+			require.Equal(t, Row{ID: id, Value: int64(id * id), TS: int64(id * 10)}, row)
+		}
+	})
+
+	t.Run("precision", func(t *testing.T) {
+		a := InitDE(WithTimePrecision(Millisecond))
+		_, err := a.Merge(InitDE(WithTimePrecision(Second)))
+		require.ErrorContains(t, err, "cannot merge")
+	})
+}
+
+func TestSplitByWindow(t *testing.T) {
+	de := InitDE(WithCheckpointInterval(4), WithVerification(true))
+	for i := range 25 {
+		de.AppendRow(Row{ID: i + 1, Value: int64(100 + i*3), TS: int64(i * 10)})
+	}
+	require.NoError(t, de.DeleteRow(12))
+	require.NoError(t, de.AttachExemplar(15, Exemplar{TraceID: "x"}))
+
+	parts, err := de.SplitByWindow(60)
+	require.NoError(t, err)
+	require.Len(t, parts, 5)
+	var rows []Row
+	for i, part := range parts {
+		// Every part starts from its own checkpoint at its first row.
+		first, err := part.Block(0)
+		require.NoError(t, err)
+		require.Equal(t, int64(i*60), first.CheckpointTS)
+		require.Equal(t, int64(100+i*18), first.CheckpointValue)
+		ok, err := part.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.True(t, ok)
+		rows = append(rows, reconstructTable(t, part)...)
+	}
+	require.Equal(t, reconstructTable(t, de), rows)
+	require.Equal(t, 6, parts[1].Len())
+	require.True(t, parts[1].IsDeleted(12))
+	_, ok := parts[2].Exemplar(15)
+	require.True(t, ok)
+	require.Equal(t, 1, parts[4].Len())
+
+	// Negative ts fall in windows below zero, and out of order rows rejoin
+	// their window.
+	de = InitDE()
+	de.AppendRows([]Row{{ID: 1, TS: -5}, {ID: 2, TS: 3}, {ID: 3, TS: -1}, {ID: 4, TS: 12}})
+	parts, err = de.SplitByWindow(10)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	require.Equal(t, []int{1, 3}, rowIDs(reconstructTable(t, parts[0])))
+	require.Equal(t, []int{2}, rowIDs(reconstructTable(t, parts[1])))
+
+	_, err = de.SplitByWindow(0)
+	require.Error(t, err)
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		de := InitDEOf[float64](WithCheckpointInterval(3), WithTSDeltaOfDelta(), WithTimePrecision(Millisecond))
+		for i := range 11 {
+			switch i {
+			case 4:
+				de.AppendRow(RowOf[float64]{ID: i + 1, Value: math.Inf(-1), TS: int64(i * 1000)})
+			case 6:
+				de.AppendNull(i+1, int64(i*1000))
+			default:
+				de.AppendRow(RowOf[float64]{ID: i + 1, Value: float64(i) * 0.1, TS: int64(i*1000 + i%2)})
+			}
+		}
+		require.NoError(t, de.DeleteRow(2))
+		require.NoError(t, de.AttachExemplar(9, Exemplar{TraceID: "t9", Labels: map[string]string{"pod": "a"}}))
+
+		data, err := json.Marshal(de)
+		require.NoError(t, err)
+		loaded := InitDEOf[float64](WithVerification(true))
+		require.NoError(t, json.Unmarshal(data, loaded))
+		require.Equal(t, 3, loaded.CheckpointInterval())
+		require.Equal(t, Millisecond, loaded.TimePrecision())
+		require.Equal(t, de.deltaValueList, loaded.deltaValueList)
+		require.Equal(t, de.deltaTsList, loaded.deltaTsList)
+		require.Equal(t, de.checkpointValues, loaded.checkpointValues)
+		require.True(t, loaded.IsDeleted(2))
+		require.True(t, loaded.IsNull(7))
+		ex, ok := loaded.Exemplar(9)
+		require.True(t, ok)
+		require.Equal(t, "a", ex.Labels["pod"])
+		want, got := reconstructTable(t, de), reconstructTable(t, loaded)
+		require.Len(t, got, len(want))
+		for i := range want {
+			require.Equal(t, want[i].ID, got[i].ID)
+			require.Equal(t, want[i].TS, got[i].TS)
+			require.Equal(t, want[i].Value, got[i].Value)
+		}
+		ok, err = loaded.VerifyDeltaEncodingCorrectness()
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		sealed, err := json.Marshal(de.Seal())
+		require.NoError(t, err)
+		require.JSONEq(t, string(data), string(sealed))
+	})
+
+	t.Run("fixture", func(t *testing.T) {
+		fixture := `{
+			"valueType": "int64",
+			"checkpointInterval": 2,
+			"tsDeltaOfDelta": false,
+			"ids": [1, 2, 3],
+			"valueDeltas": [0, 5, -2],
+			"tsDeltas": [0, 10, 10],
+			"checkpoints": [{"value": 100, "ts": 1000, "tsDelta": 0}, {"value": 105, "ts": 1010, "tsDelta": 10}],
+			"deleted": [1],
+			"nulls": [],
+			"nonFinite": [],
+			"exemplars": []
+		}`
+		var de DeltaEncoding
+		require.NoError(t, json.Unmarshal([]byte(fixture), &de))
+		require.Equal(t, []Row{{ID: 1, Value: 100, TS: 1000}, {ID: 3, Value: 103, TS: 1020}}, reconstructTable(t, &de))
+		data, err := json.Marshal(&de)
+		require.NoError(t, err)
+		require.JSONEq(t, fixture, string(data))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var de DeltaEncoding
+		for _, tc := range []struct{ json, err string }{
+			{`{"valueType": "float64", "checkpointInterval": 2}`, "holds float64 values"},
+			{`{"valueType": "int64", "checkpointInterval": 0}`, "checkpoint interval 0"},
+			{`{"valueType": "int64", "checkpointInterval": 2, "ids": [1], "valueDeltas": [], "tsDeltas": [0]}`, "1 ids, 0 value deltas"},
+			{`{"valueType": "int64", "checkpointInterval": 2, "ids": [1], "valueDeltas": [0], "tsDeltas": [0], "checkpoints": []}`, "0 checkpoints for 1 rows, want 1"},
+			{`{"valueType": "int64", "checkpointInterval": 2, "ids": [1], "valueDeltas": [0], "tsDeltas": [0], "checkpoints": [{}], "deleted": [1]}`, "deleted row 1 of 1"},
+			{`{"valueType": "int64", "checkpointInterval": 2, "ids": [1], "valueDeltas": [0], "tsDeltas": [0], "checkpoints": [{}], "nonFinite": [{"row": 0, "value": "NaN"}]}`, "non-finite values in a int64 column"},
+		} {
+			require.ErrorContains(t, json.Unmarshal([]byte(tc.json), &de), tc.err)
+		}
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,40 @@
+package delta_encoding
+
+import "fmt"
+
+// DeleteRow marks a row as deleted in the tombstone bitmap. The row's deltas stay
+// in place, so every later row still decodes correctly; reads just skip it.
+// time complexity: O(1), O(log buckets) with a time bucket index
+func (de *DeltaEncodingOf[T]) DeleteRow(rowID int) error {
+	rowIndex, ok := de.indexOf(rowID)
+	if !ok {
+		return fmt.Errorf("%w: id %d", ErrRowNotFound, rowID)
+	}
+	if de.tombstones.get(rowIndex) {
+		return fmt.Errorf("%w: id %d was already deleted", ErrRowDeleted, rowID)
+	}
+	de.deleteAt(rowIndex)
+	return nil
+}
+
+// deleteAt tombstones the live row at rowIndex.
+func (de *DeltaEncodingOf[T]) deleteAt(rowIndex int) {
+	de.tombstones.set(rowIndex)
+	de.deletedCount++
+
+	if idx := de.timeIndex; idx != nil && !idx.broken {
+		idx.counts[idx.bucketAt(rowIndex)]--
+	}
+}
+
+// IsDeleted reports whether the row has been deleted.
+func (de *DeltaEncodingOf[T]) IsDeleted(rowID int) bool {
+	rowIndex, ok := de.indexOf(rowID)
+	return ok && de.tombstones.get(rowIndex)
+}
+
+// Deleted returns the number of deleted rows. Len still counts them, since
+// their deltas remain part of the encoding.
+func (de *DeltaEncodingOf[T]) Deleted() int {
+	return de.deletedCount
+}
@@ -0,0 +1,237 @@
+package delta_encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ColumnEncoding selects how a column of a checkpoint block is stored in a file.
+// In memory, values are always deltas and ts deltas or delta-of-deltas; a file
+// block may store either column differently when that is smaller.
+type ColumnEncoding byte
+
+const (
+	// EncodingDelta stores the difference from the previous row.
+	EncodingDelta ColumnEncoding = iota
+	// EncodingPlain stores absolute values, the best choice for random data.
+	EncodingPlain
+	// EncodingDeltaOfDelta stores the difference between consecutive deltas,
+	// which is 0 for a column growing at a constant rate.
+	EncodingDeltaOfDelta
+	// EncodingFOR (frame of reference) stores the minimum of the block and the
+	// offset of every row from it, bit-packed at the width of the largest one.
+	// Rows decode independently, without walking a chain of deltas.
+	EncodingFOR
+)
+
+func (e ColumnEncoding) String() string {
+	switch e {
+	case EncodingDelta:
+		return "delta"
+	case EncodingPlain:
+		return "plain"
+	case EncodingDeltaOfDelta:
+		return "delta-of-delta"
+	case EncodingFOR:
+		return "frame-of-reference"
+	default:
+		return fmt.Sprintf("ColumnEncoding(%d)", byte(e))
+	}
+}
+
+// ColumnEncodings holds the file encoding of each column.
+type ColumnEncodings struct {
+	Value ColumnEncoding
+	TS    ColumnEncoding
+}
+
+// modeByte packs the encodings into the mode byte of a block payload.
+func (e ColumnEncodings) modeByte() byte {
+	return byte(e.Value) | byte(e.TS)<<2
+}
+
+func encodingsFromMode(mode byte) (ColumnEncodings, error) {
+	e := ColumnEncodings{Value: ColumnEncoding(mode & 3), TS: ColumnEncoding(mode >> 2)}
+	if e.Value > EncodingFOR || e.TS > EncodingFOR {
+		return ColumnEncodings{}, fmt.Errorf("unknown block mode %d", mode)
+	}
+	return e, nil
+}
+
+// nativeEncodings returns the encodings of the in-memory streams.
+func (de *DeltaEncodingOf[T]) nativeEncodings() ColumnEncodings {
+	if de.tsDeltaOfDelta {
+		return ColumnEncodings{Value: EncodingDelta, TS: EncodingDeltaOfDelta}
+	}
+	return ColumnEncodings{Value: EncodingDelta, TS: EncodingDelta}
+}
+
+// blockColumns holds the columns of one checkpoint block with the state their
+// delta encodings start from.
+type blockColumns[T Numeric] struct {
+	checkpointValue   T
+	checkpointTs      int64
+	checkpointTsDelta int64
+	ids               []int
+	valueDeltas       []T // the in-memory deltas, exact for floats too
+	values            []T // chain values, so null and non-finite rows keep their place
+	ts                []int64
+}
+
+// blockColumns fills b with block k, decoding forward with c.
+func (de *DeltaEncodingOf[T]) blockColumns(b *blockColumns[T], c *cursor[T], k int) {
+	start := min(k*de.checkpointInterval, len(de.idList))
+	end := min(start+de.checkpointInterval, len(de.idList))
+	b.checkpointValue = de.checkpointValues[k]
+	b.checkpointTs = de.checkpointTs[k]
+	b.checkpointTsDelta = de.checkpointTsDeltas[k]
+	b.ids = de.idList[start:end]
+	b.valueDeltas = de.deltaValueList[start:end]
+	b.values, b.ts = b.values[:0], b.ts[:0]
+	for rowIndex := start; rowIndex < end; rowIndex++ {
+		b.ts = append(b.ts, c.seek(rowIndex).TS)
+		b.values = append(b.values, c.value)
+	}
+}
+
+// appendValueColumn appends the value column of b in encoding e. Float values
+// are only ever delta or plain: their second differences would not round-trip,
+// and EncodingFOR stores them plain.
+func appendValueColumn[T Numeric](buf []byte, b *blockColumns[T], e ColumnEncoding) []byte {
+	switch e {
+	case EncodingPlain:
+		return appendValues(buf, b.values)
+	case EncodingFOR:
+		if isFloat[T]() {
+			return appendValues(buf, b.values)
+		}
+		values := make([]int64, len(b.values))
+		for i, v := range b.values {
+			values[i] = int64(v)
+		}
+		return appendFOR(buf, values)
+	case EncodingDeltaOfDelta:
+		var prev T
+		for _, d := range b.valueDeltas {
+			buf = appendValue(buf, d-prev)
+			prev = d
+		}
+		return buf
+	default:
+		return appendValues(buf, b.valueDeltas)
+	}
+}
+
+// appendTSColumn appends the ts column of b in encoding e.
+func appendTSColumn[T Numeric](buf []byte, b *blockColumns[T], e ColumnEncoding) []byte {
+	switch e {
+	case EncodingPlain:
+		return appendInts(buf, b.ts)
+	case EncodingFOR:
+		return appendFOR(buf, b.ts)
+	}
+	prev, prevDelta := b.checkpointTs, b.checkpointTsDelta
+	for _, ts := range b.ts {
+		d := ts - prev
+		if e == EncodingDeltaOfDelta {
+			buf = binary.AppendVarint(buf, d-prevDelta)
+		} else {
+			buf = binary.AppendVarint(buf, d)
+		}
+		prev, prevDelta = ts, d
+	}
+	return buf
+}
+
+// readValueColumn reverses appendValueColumn for n rows.
+func readValueColumn[T Numeric](r *fileReader, e ColumnEncoding, n int, checkpoint T) []T {
+	if e == EncodingFOR && !isFloat[T]() {
+		values := make([]T, n)
+		for i, v := range readFOR(r, n) {
+			values[i] = T(v)
+		}
+		return values
+	}
+	values := readValues[T](r, n)
+	if e == EncodingPlain || e == EncodingFOR {
+		return values
+	}
+	// Summing in row order from the checkpoint gives exactly the cursor's
+	// chain values, floats included.
+	value, d := checkpoint, T(0)
+	for i, v := range values {
+		if e == EncodingDeltaOfDelta {
+			d += v
+		} else {
+			d = v
+		}
+		value += d
+		values[i] = value
+	}
+	return values
+}
+
+// readTSColumn reverses appendTSColumn for n rows.
+func readTSColumn(r *fileReader, e ColumnEncoding, n int, checkpointTs, checkpointTsDelta int64) []int64 {
+	if e == EncodingFOR {
+		return readFOR(r, n)
+	}
+	ts := readInts[int64](r, n)
+	if e == EncodingPlain {
+		return ts
+	}
+	prev, d := checkpointTs, checkpointTsDelta
+	for i, v := range ts {
+		if e == EncodingDeltaOfDelta {
+			d += v
+		} else {
+			d = v
+		}
+		prev += d
+		ts[i] = prev
+	}
+	return ts
+}
+
+// analyzeBlocks bounds how many blocks chooseEncodings sizes.
+const analyzeBlocks = 64
+
+// chooseEncodings sizes each column under every encoding over up to
+// analyzeBlocks evenly spaced blocks and returns the smallest per column,
+// preferring the native encoding on ties. Random or high-entropy columns, whose
+// deltas are larger than the values, get EncodingPlain.
+// time complexity: O(min(n, analyzeBlocks*checkpointInterval))
+func (de *DeltaEncodingOf[T]) chooseEncodings() ColumnEncodings {
+	native := de.nativeEncodings()
+	blocks := checkpointBlocks(len(de.idList), de.checkpointInterval)
+	if blocks == 0 {
+		return native
+	}
+	candidates := []ColumnEncoding{EncodingDelta, EncodingPlain, EncodingDeltaOfDelta, EncodingFOR}
+	var valueSizes, tsSizes [4]int
+	var b blockColumns[T]
+	var buf []byte
+	c := de.newCursor()
+	step := max(1, blocks/analyzeBlocks)
+	for k := 0; k < blocks; k += step {
+		de.blockColumns(&b, c, k)
+		for _, e := range candidates {
+			buf = appendValueColumn(buf[:0], &b, e)
+			valueSizes[e] += len(buf)
+			buf = appendTSColumn(buf[:0], &b, e)
+			tsSizes[e] += len(buf)
+		}
+	}
+
+	chosen := native
+	for _, e := range candidates {
+		// Floats take 8 bytes in every encoding, so their deltas stay.
+		if !isFloat[T]() && valueSizes[e] < valueSizes[chosen.Value] {
+			chosen.Value = e
+		}
+		if tsSizes[e] < tsSizes[chosen.TS] {
+			chosen.TS = e
+		}
+	}
+	return chosen
+}
@@ -0,0 +1,18 @@
+package delta_encoding
+
+import "errors"
+
+// Errors returned by the encoding, usually wrapped with the row or section they
+// concern. Callers test for them with errors.Is.
+var (
+	// ErrRowNotFound is returned when no row has the requested id.
+	ErrRowNotFound = errors.New("row does not exist")
+	// ErrRowDeleted is returned when the requested row has been deleted.
+	ErrRowDeleted = errors.New("row is deleted")
+	// ErrEmptyEncoding is returned by range and block queries on an encoding
+	// without rows.
+	ErrEmptyEncoding = errors.New("encoding has no rows")
+	// ErrCorruptBlock is returned when an encoding file fails a checksum or
+	// holds data that does not parse. *CorruptionError matches it too.
+	ErrCorruptBlock = errors.New("invalid encoding file")
+)
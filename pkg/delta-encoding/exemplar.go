@@ -0,0 +1,75 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"maps"
+)
+
+// Exemplar links a sample to the trace that produced it, e.g. one slow request
+// behind a latency spike.
+type Exemplar struct {
+	TraceID string
+	Labels  map[string]string
+}
+
+// RowExemplar is an exemplar together with the row it is attached to.
+type RowExemplar struct {
+	RowID int
+	Exemplar
+}
+
+// AttachExemplar attaches ex to a row, replacing any exemplar it already has.
+// time complexity: O(n/64 + e) for e exemplars
+func (de *DeltaEncodingOf[T]) AttachExemplar(rowID int, ex Exemplar) error {
+	rowIndex, ok := de.indexOf(rowID)
+	if !ok {
+		return fmt.Errorf("%w: id %d", ErrRowNotFound, rowID)
+	}
+	de.attachExemplarAt(rowIndex, ex)
+	return nil
+}
+
+func (de *DeltaEncodingOf[T]) attachExemplarAt(rowIndex int, ex Exemplar) {
+	ex.Labels = maps.Clone(ex.Labels)
+	de.exemplars.set(rowIndex, ex)
+}
+
+// Exemplar returns the exemplar attached to a row, if any.
+// time complexity: O(n/64)
+func (de *DeltaEncodingOf[T]) Exemplar(rowID int) (Exemplar, bool) {
+	rowIndex, ok := de.indexOf(rowID)
+	if !ok {
+		return Exemplar{}, false
+	}
+	return de.exemplars.get(rowIndex)
+}
+
+// ExemplarsInRange returns the exemplars of live rows with fromID <= ID <= toID.
+// time complexity: O(n/64 + k) for k rows
+func (de *DeltaEncodingOf[T]) ExemplarsInRange(fromID, toID int) ([]RowExemplar, error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+	out := []RowExemplar{}
+	for rowIndex, ex := range de.exemplars.rows(from, to) {
+		if !de.tombstones.get(rowIndex) {
+			out = append(out, RowExemplar{RowID: de.idList[rowIndex], Exemplar: ex})
+		}
+	}
+	return out, nil
+}
+
+// ReconstructRangeWithExemplars is ReconstructRange that also returns the
+// exemplars attached to the returned rows.
+func (de *DeltaEncodingOf[T]) ReconstructRangeWithExemplars(fromID, toID int) ([]RowOf[T], []RowExemplar, error) {
+	rows, err := de.ReconstructRange(fromID, toID)
+	if err != nil {
+		return nil, nil, err
+	}
+	exemplars, err := de.ExemplarsInRange(fromID, toID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, exemplars, nil
+}
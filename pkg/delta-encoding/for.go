@@ -0,0 +1,102 @@
+package delta_encoding
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"slices"
+)
+
+// appendFOR appends values in frame-of-reference form: their minimum as a
+// varint, the bit width of the largest offset from it, and every offset packed
+// into width bits, least significant bit first. Nothing is written for an
+// empty block.
+// time complexity: O(n)
+func appendFOR(buf []byte, values []int64) []byte {
+	if len(values) == 0 {
+		return buf
+	}
+	lo := slices.Min(values)
+	// Offsets are taken as uint64 so a range wider than int64 still fits.
+	width := bits.Len64(uint64(slices.Max(values)) - uint64(lo))
+	buf = binary.AppendVarint(buf, lo)
+	buf = append(buf, byte(width))
+	var cur byte
+	used := 0 // bits of cur already holding offset bits
+	for _, v := range values {
+		offset := uint64(v) - uint64(lo)
+		for left := width; left > 0; {
+			take := min(left, 8-used)
+			cur |= byte(offset&(1<<take-1)) << used
+			offset >>= take
+			left -= take
+			if used += take; used == 8 {
+				buf = append(buf, cur)
+				cur, used = 0, 0
+			}
+		}
+	}
+	if used > 0 {
+		buf = append(buf, cur)
+	}
+	return buf
+}
+
+// readFOR reverses appendFOR for n values. Each value only depends on the
+// minimum and its own offset, which unpackOffset reads at a fixed bit position,
+// so unlike deltas no earlier row has to be decoded first.
+// time complexity: O(n)
+func readFOR(r *fileReader, n int) []int64 {
+	values := make([]int64, n)
+	if n == 0 {
+		return values
+	}
+	lo, width := r.varint(), int(r.byte())
+	if r.err != nil {
+		return values
+	}
+	if width > 64 {
+		r.fail("frame-of-reference width %d", width)
+		return values
+	}
+	size := (n*width + 7) / 8
+	if len(r.buf) < size {
+		r.fail("truncated stream")
+		return values
+	}
+	packed := r.buf[:size]
+	r.buf = r.buf[size:]
+	for i := range values {
+		values[i] = int64(uint64(lo) + unpackOffset(packed, width, i))
+	}
+	return values
+}
+
+// unpackOffset returns offset i of width bits from packed.
+func unpackOffset(packed []byte, width, i int) uint64 {
+	var offset uint64
+	pos := i * width
+	for got := 0; got < width; {
+		take := min(width-got, 8-pos%8)
+		offset |= uint64(packed[pos/8]>>(pos%8)&(1<<take-1)) << got
+		got += take
+		pos += take
+	}
+	return offset
+}
+
+// forEncodingSizes returns the size of the value and ts columns with every
+// block stored in EncodingFOR.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) forEncodingSizes() (values, ts int) {
+	var b blockColumns[T]
+	var buf []byte
+	c := de.newCursor()
+	for k := range checkpointBlocks(len(de.idList), de.checkpointInterval) {
+		de.blockColumns(&b, c, k)
+		buf = appendValueColumn(buf[:0], &b, EncodingFOR)
+		values += len(buf)
+		buf = appendTSColumn(buf[:0], &b, EncodingFOR)
+		ts += len(buf)
+	}
+	return values, ts
+}
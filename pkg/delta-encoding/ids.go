@@ -0,0 +1,67 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Row ids are whatever the caller appended. They are usually 1..N, which
+// indexOf checks in O(1); increasing ids with gaps (rows deleted upstream) are
+// found by binary search over idList, and only ids that ever go backwards pay
+// for the idIndex map.
+
+// trackID keeps idIndex in sync for the id about to be appended at the end.
+func (de *DeltaEncodingOf[T]) trackID(id int) {
+	n := len(de.idList)
+	if de.idIndex == nil {
+		if n == 0 || id > de.idList[n-1] {
+			return
+		}
+		de.idIndex = make(map[int]int, n+1)
+		for rowIndex := n - 1; rowIndex >= 0; rowIndex-- {
+			de.idIndex[de.idList[rowIndex]] = rowIndex
+		}
+	}
+	// A repeated id keeps resolving to its first row.
+	if _, ok := de.idIndex[id]; !ok {
+		de.idIndex[id] = n
+	}
+}
+
+// indexOf returns the position of the row with the given id. For increasing ids
+// that are not present it returns the position the id would be inserted at.
+// time complexity: O(1) for ids 1..N or unsorted ids, O(log n) otherwise
+func (de *DeltaEncodingOf[T]) indexOf(rowID int) (int, bool) {
+	if de.idIndex != nil {
+		rowIndex, ok := de.idIndex[rowID]
+		return rowIndex, ok
+	}
+	if rowID >= 1 && rowID <= len(de.idList) && de.idList[rowID-1] == rowID {
+		return rowID - 1, true
+	}
+	rowIndex := sort.SearchInts(de.idList, rowID)
+	return rowIndex, rowIndex < len(de.idList) && de.idList[rowIndex] == rowID
+}
+
+// indexRange returns the positions [from, to) of the rows with
+// fromID <= id <= toID. The range must lie within the first and last id, and
+// the ids must be increasing.
+// time complexity: O(log n)
+func (de *DeltaEncodingOf[T]) indexRange(fromID, toID int) (int, int, error) {
+	if de.idIndex != nil {
+		return 0, 0, fmt.Errorf("row range [%d, %d] needs increasing row ids", fromID, toID)
+	}
+	n := len(de.idList)
+	if n == 0 {
+		return 0, 0, ErrEmptyEncoding
+	}
+	if fromID > toID || fromID < de.idList[0] || toID > de.idList[n-1] {
+		return 0, 0, fmt.Errorf("invalid row range [%d, %d]", fromID, toID)
+	}
+	from, _ := de.indexOf(fromID)
+	to, ok := de.indexOf(toID)
+	if ok {
+		to++
+	}
+	return from, to, nil
+}
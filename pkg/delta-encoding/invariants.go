@@ -0,0 +1,105 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraints checked by CheckInvariants.
+const (
+	ConstraintMonotonicTS = "monotonic-ts"
+	ConstraintUniqueID    = "unique-id"
+)
+
+// Violation is one broken constraint found by CheckInvariants.
+type Violation struct {
+	Constraint string
+	Segment    int // index of the encoding in the checked sequence
+	RowID      int
+	TS         int64
+	Detail     string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: segment %d row %d: %s", v.Constraint, v.Segment, v.RowID, v.Detail)
+}
+
+// InvariantError is returned by SealChecked for an encoding that breaks a
+// constraint.
+type InvariantError struct {
+	Violations []Violation
+}
+
+func (e *InvariantError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invariant violations", len(e.Violations))
+	for i, v := range e.Violations {
+		if i == 3 {
+			fmt.Fprintf(&b, "; ...")
+			break
+		}
+		fmt.Fprintf(&b, "; %s", v)
+	}
+	return b.String()
+}
+
+// CheckInvariants validates constraints across a sequence of encodings holding
+// consecutive segments of one series: ts never decreases, including across
+// segment boundaries, and no row id appears twice in any segment. Deleted rows
+// are ignored. Appending accepts rows that break either constraint, so this is
+// how they are caught before a segment is sealed or compacted.
+// time complexity: O(n) for n rows in total
+func CheckInvariants[T Numeric](segments ...*DeltaEncodingOf[T]) []Violation {
+	var violations []Violation
+	type location struct{ segment, rowID int }
+	seen := map[int]location{}
+	var lastTS int64
+	var last location
+	started := false
+	for segment, de := range segments {
+		for row := range de.All() {
+			if started && row.TS < lastTS {
+				violations = append(violations, Violation{
+					Constraint: ConstraintMonotonicTS,
+					Segment:    segment,
+					RowID:      row.ID,
+					TS:         row.TS,
+					Detail:     fmt.Sprintf("ts %d is before ts %d of row %d in segment %d", row.TS, lastTS, last.rowID, last.segment),
+				})
+			}
+			if prev, ok := seen[row.ID]; ok {
+				violations = append(violations, Violation{
+					Constraint: ConstraintUniqueID,
+					Segment:    segment,
+					RowID:      row.ID,
+					TS:         row.TS,
+					Detail:     fmt.Sprintf("id already used in segment %d", prev.segment),
+				})
+			} else {
+				seen[row.ID] = location{segment, row.ID}
+			}
+			lastTS, last, started = row.TS, location{segment, row.ID}, true
+		}
+	}
+	return violations
+}
+
+// CheckInvariants checks the constraints of CheckInvariants within de.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) CheckInvariants() []Violation {
+	return CheckInvariants(de)
+}
+
+// SealChecked is Seal for encodings that must satisfy CheckInvariants. It
+// returns an *InvariantError listing every violation instead of sealing an
+// encoding that breaks a constraint.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) SealChecked() (*SealedOf[T], error) {
+	if de.reorder != nil {
+		de.Flush()
+	}
+	if violations := de.CheckInvariants(); len(violations) > 0 {
+		return nil, &InvariantError{Violations: violations}
+	}
+	return de.Seal(), nil
+}
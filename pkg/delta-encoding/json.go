@@ -0,0 +1,223 @@
+package delta_encoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// encodingJSON is the JSON form of an encoding, meant for inspecting blocks and
+// for test fixtures shared with other languages:
+//
+//	{
+//	  "valueType": "float64",
+//	  "checkpointInterval": 2,
+//	  "tsDeltaOfDelta": false,
+//	  "timePrecision": 1000000,
+//	  "ids": [1, 2, 3],
+//	  "valueDeltas": [0, 5, 0],
+//	  "tsDeltas": [0, 10, 10],
+//	  "checkpoints": [{"value": 100, "ts": 1000, "tsDelta": 0}, {"value": 105, "ts": 1010, "tsDelta": 10}],
+//	  "deleted": [1],
+//	  "nulls": [],
+//	  "nonFinite": [{"row": 2, "value": "NaN"}],
+//	  "exemplars": [{"row": 0, "traceId": "4bf92f", "labels": {"pod": "a"}}]
+//	}
+//
+// valueType is the Go kind of the values and timePrecision the nanoseconds per
+// ts unit, omitted when ts is unitless. Every row has an id, a value delta and
+// a ts delta; the first row's deltas are 0. Checkpoint 0 holds the first row,
+// and checkpoint k > 0 the value, ts and first-order ts delta of row
+// k*checkpointInterval-1. deleted, nulls, nonFinite and exemplars refer to rows
+// by index, not id; non-finite values are "NaN", "+Inf" or "-Inf".
+type encodingJSON[T Numeric] struct {
+	ValueType          string              `json:"valueType"`
+	CheckpointInterval int                 `json:"checkpointInterval"`
+	TSDeltaOfDelta     bool                `json:"tsDeltaOfDelta"`
+	TimePrecision      Precision           `json:"timePrecision,omitempty"`
+	IDs                []int               `json:"ids"`
+	ValueDeltas        []T                 `json:"valueDeltas"`
+	TSDeltas           []int64             `json:"tsDeltas"`
+	Checkpoints        []checkpointJSON[T] `json:"checkpoints"`
+	Deleted            []int               `json:"deleted"`
+	Nulls              []int               `json:"nulls"`
+	NonFinite          []nonFiniteJSON     `json:"nonFinite"`
+	Exemplars          []exemplarJSON      `json:"exemplars"`
+}
+
+type checkpointJSON[T Numeric] struct {
+	Value   T     `json:"value"`
+	TS      int64 `json:"ts"`
+	TSDelta int64 `json:"tsDelta"`
+}
+
+type nonFiniteJSON struct {
+	Row   int    `json:"row"`
+	Value string `json:"value"`
+}
+
+type exemplarJSON struct {
+	Row     int               `json:"row"`
+	TraceID string            `json:"traceId"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// MarshalJSON encodes the checkpoints and delta streams in the structure
+// documented on encodingJSON. Rows still in the reorder buffer are not
+// included; call Flush first to include them.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) MarshalJSON() ([]byte, error) {
+	n := len(de.idList)
+	out := encodingJSON[T]{
+		ValueType:          valueKind[T]().String(),
+		CheckpointInterval: de.checkpointInterval,
+		TSDeltaOfDelta:     de.tsDeltaOfDelta,
+		TimePrecision:      de.timePrecision,
+		IDs:                de.idList,
+		ValueDeltas:        de.deltaValueList,
+		TSDeltas:           de.deltaTsList,
+		Checkpoints:        make([]checkpointJSON[T], len(de.checkpointValues)),
+		Deleted:            []int{},
+		Nulls:              []int{},
+		NonFinite:          []nonFiniteJSON{},
+		Exemplars:          []exemplarJSON{},
+	}
+	for k, v := range de.checkpointValues {
+		out.Checkpoints[k] = checkpointJSON[T]{Value: v, TS: de.checkpointTs[k], TSDelta: de.checkpointTsDeltas[k]}
+	}
+	for rowIndex := range n {
+		if de.tombstones.get(rowIndex) {
+			out.Deleted = append(out.Deleted, rowIndex)
+		}
+		if de.nulls.get(rowIndex) {
+			out.Nulls = append(out.Nulls, rowIndex)
+		}
+	}
+	for rowIndex, v := range de.nonFinite.rows(0, n) {
+		out.NonFinite = append(out.NonFinite, nonFiniteJSON{Row: rowIndex, Value: strconv.FormatFloat(float64(v), 'g', -1, 64)})
+	}
+	for rowIndex, ex := range de.exemplars.rows(0, n) {
+		out.Exemplars = append(out.Exemplars, exemplarJSON{Row: rowIndex, TraceID: ex.TraceID, Labels: ex.Labels})
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON replaces the rows of de with those in data, which must have the
+// structure MarshalJSON produces. The checkpoint interval, ts mode and time
+// precision are taken from data; other options of de, such as verification or
+// value samples, are kept and their state rebuilt as if the rows were appended.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) UnmarshalJSON(data []byte) error {
+	var in encodingJSON[T]
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	stored, err := in.encoding()
+	if err != nil {
+		return fmt.Errorf("invalid encoding JSON: %w", err)
+	}
+	de.reset(stored)
+	for rowIndex, row := range stored.allRows() {
+		de.appendFrom(stored, rowIndex, row)
+	}
+	return nil
+}
+
+// encoding validates in and returns the encoding it describes, without any
+// derived state.
+func (in *encodingJSON[T]) encoding() (*DeltaEncodingOf[T], error) {
+	n := len(in.IDs)
+	switch {
+	case in.ValueType != valueKind[T]().String():
+		return nil, fmt.Errorf("holds %s values, want %s", in.ValueType, valueKind[T]())
+	case in.CheckpointInterval < 1:
+		return nil, fmt.Errorf("checkpoint interval %d", in.CheckpointInterval)
+	case in.TimePrecision != 0 && !in.TimePrecision.valid():
+		return nil, fmt.Errorf("time precision %d", in.TimePrecision)
+	case len(in.ValueDeltas) != n || len(in.TSDeltas) != n:
+		return nil, fmt.Errorf("%d ids, %d value deltas and %d ts deltas", n, len(in.ValueDeltas), len(in.TSDeltas))
+	case len(in.Checkpoints) != checkpointBlocks(n, in.CheckpointInterval):
+		return nil, fmt.Errorf("%d checkpoints for %d rows, want %d", len(in.Checkpoints), n, checkpointBlocks(n, in.CheckpointInterval))
+	case len(in.NonFinite) > 0 && !isFloat[T]():
+		return nil, fmt.Errorf("non-finite values in a %s column", in.ValueType)
+	}
+	stored := &DeltaEncodingOf[T]{
+		idList:             in.IDs,
+		deltaValueList:     in.ValueDeltas,
+		deltaTsList:        in.TSDeltas,
+		checkpointInterval: in.CheckpointInterval,
+		tsDeltaOfDelta:     in.TSDeltaOfDelta,
+		timePrecision:      in.TimePrecision,
+	}
+	for _, c := range in.Checkpoints {
+		stored.checkpointValues = append(stored.checkpointValues, c.Value)
+		stored.checkpointTs = append(stored.checkpointTs, c.TS)
+		stored.checkpointTsDeltas = append(stored.checkpointTsDeltas, c.TSDelta)
+	}
+	inRange := func(field string, rowIndex int) error {
+		if rowIndex < 0 || rowIndex >= n {
+			return fmt.Errorf("%s row %d of %d", field, rowIndex, n)
+		}
+		return nil
+	}
+	for _, rowIndex := range in.Deleted {
+		if err := inRange("deleted", rowIndex); err != nil {
+			return nil, err
+		}
+		stored.tombstones.set(rowIndex)
+	}
+	for _, rowIndex := range in.Nulls {
+		if err := inRange("null", rowIndex); err != nil {
+			return nil, err
+		}
+		stored.nulls.set(rowIndex)
+	}
+	for _, nf := range in.NonFinite {
+		if err := inRange("non-finite", nf.Row); err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(nf.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("non-finite row %d: %w", nf.Row, err)
+		}
+		stored.nonFinite.set(nf.Row, T(v))
+	}
+	for _, ex := range in.Exemplars {
+		if err := inRange("exemplar", ex.Row); err != nil {
+			return nil, err
+		}
+		stored.exemplars.set(ex.Row, Exemplar{TraceID: ex.TraceID, Labels: ex.Labels})
+	}
+	return stored, nil
+}
+
+// reset drops every row of de and takes the layout options of stored, keeping
+// the rest of de's options.
+func (de *DeltaEncodingOf[T]) reset(stored *DeltaEncodingOf[T]) {
+	de.idList = []int{}
+	de.deltaValueList = []T{}
+	de.deltaTsList = []int64{}
+	de.originalRows = nil
+	de.tombstones, de.deletedCount = nil, 0
+	de.idIndex = nil
+	de.exemplars = sparse[Exemplar]{}
+	de.nonFinite = sparse[T]{}
+	de.nulls = nil
+	de.lastValue, de.lastTs, de.lastTsDelta = 0, 0, 0
+	de.tsUnordered = false
+	de.checkpointInterval = stored.checkpointInterval
+	de.checkpointValues = []T{}
+	de.checkpointTs = []int64{}
+	de.checkpointTsDeltas = []int64{}
+	de.tsDeltaOfDelta = stored.tsDeltaOfDelta
+	de.timePrecision = stored.timePrecision
+	de.blockSamples = nil
+	de.encodings = nil
+	if de.reorder != nil {
+		de.reorder = &reorderBuffer[T]{window: de.reorder.window}
+	}
+	if de.timeIndex != nil {
+		de.timeIndex = &timeBucketIndex{size: de.timeIndex.size}
+	}
+	de.ResetDecodeStats()
+}
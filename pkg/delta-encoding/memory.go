@@ -0,0 +1,65 @@
+package delta_encoding
+
+import "unsafe"
+
+// MemoryBreakdown reports the heap bytes held by an encoding, per internal
+// structure. Slices are sized by capacity, since that is what is allocated;
+// maps and strings are estimated from their contents without allocator or
+// bucket overhead, so Total is a lower bound suited to budgets and alerts.
+type MemoryBreakdown struct {
+	IDs         int // id column
+	ValueDeltas int // value delta column
+	TSDeltas    int // ts delta column
+	Checkpoints int // absolute values, ts and ts deltas at checkpoints
+	Originals   int // rows retained for verification
+	Indexes     int // id-to-index map and time bucket index
+	Bitmaps     int // tombstones and nulls
+	SideColumns int // exemplars and non-finite values
+	Samples     int // per-block value samples
+	Buffered    int // rows waiting in the reorder buffer
+}
+
+// Total returns the sum of all parts.
+func (m MemoryBreakdown) Total() int {
+	return m.IDs + m.ValueDeltas + m.TSDeltas + m.Checkpoints + m.Originals +
+		m.Indexes + m.Bitmaps + m.SideColumns + m.Samples + m.Buffered
+}
+
+// sliceBytes returns the bytes allocated for the backing array of s.
+func sliceBytes[E any](s []E) int {
+	var e E
+	return cap(s) * int(unsafe.Sizeof(e))
+}
+
+// MemoryUsage reports the memory held by the encoding.
+// time complexity: O(blocks + exemplars)
+func (de *DeltaEncodingOf[T]) MemoryUsage() MemoryBreakdown {
+	m := MemoryBreakdown{
+		IDs:         sliceBytes(de.idList),
+		ValueDeltas: sliceBytes(de.deltaValueList),
+		TSDeltas:    sliceBytes(de.deltaTsList),
+		Checkpoints: sliceBytes(de.checkpointValues) + sliceBytes(de.checkpointTs) + sliceBytes(de.checkpointTsDeltas),
+		Originals:   sliceBytes(de.originalRows),
+		Indexes:     len(de.idIndex) * 2 * int(unsafe.Sizeof(0)),
+		Bitmaps:     sliceBytes(de.tombstones) + sliceBytes(de.nulls),
+		SideColumns: sliceBytes(de.exemplars.present) + sliceBytes(de.exemplars.values) +
+			sliceBytes(de.nonFinite.present) + sliceBytes(de.nonFinite.values),
+		Samples: sliceBytes(de.blockSamples),
+	}
+	if idx := de.timeIndex; idx != nil {
+		m.Indexes += sliceBytes(idx.buckets) + sliceBytes(idx.counts) + sliceBytes(idx.rows) + sliceBytes(idx.firstRow)
+	}
+	for _, e := range de.exemplars.values {
+		m.SideColumns += len(e.TraceID)
+		for name, value := range e.Labels {
+			m.SideColumns += len(name) + len(value) + 2*int(unsafe.Sizeof(""))
+		}
+	}
+	for _, samples := range de.blockSamples {
+		m.Samples += sliceBytes(samples)
+	}
+	if de.reorder != nil {
+		m.Buffered = sliceBytes(de.reorder.rows)
+	}
+	return m
+}
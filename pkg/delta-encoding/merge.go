@@ -0,0 +1,66 @@
+package delta_encoding
+
+import "fmt"
+
+// Merge returns a new encoding holding the rows of de and other, with the
+// options of de, so that e.g. hourly encodings can be combined into a daily
+// one. Neither input is modified, apart from flushing their reorder buffers.
+//
+// When other starts at or after the last ts of de, its rows are appended after
+// de's: de's columns are copied as they are and only the delta at the boundary
+// and the checkpoints from there on are recomputed. Otherwise the rows of both
+// are interleaved by ts, rows with equal ts keeping de's first, and the result
+// is re-encoded. Row ids are kept as they are, deleted rows stay deleted, and
+// nulls and exemplars carry over.
+// time complexity: O(n + m)
+func (de *DeltaEncodingOf[T]) Merge(other *DeltaEncodingOf[T]) (*DeltaEncodingOf[T], error) {
+	if de.timePrecision != other.timePrecision {
+		return nil, fmt.Errorf("cannot merge ts in %s with ts in %s", other.timePrecision, de.timePrecision)
+	}
+	for _, e := range []*DeltaEncodingOf[T]{de, other} {
+		if e.reorder != nil {
+			e.Flush()
+		}
+	}
+
+	if len(de.idList) == 0 || len(other.idList) == 0 || other.checkpointTs[0] >= de.lastTs {
+		out := de.clone()
+		for rowIndex, row := range other.allRows() {
+			out.appendFrom(other, rowIndex, row)
+		}
+		if de.logger != nil {
+			de.logger.Debug("merged by concatenation", "rows", len(de.idList), "appended", len(other.idList))
+		}
+		return out, nil
+	}
+
+	out := de.emptyLike()
+	out.grow(len(de.idList) + len(other.idList))
+	a, b := de.newCursor(), other.newCursor()
+	i, j := 0, 0
+	for i < len(de.idList) || j < len(other.idList) {
+		if j == len(other.idList) || i < len(de.idList) && a.seek(i).TS <= b.seek(j).TS {
+			out.appendFrom(de, i, a.seek(i))
+			i++
+		} else {
+			out.appendFrom(other, j, b.seek(j))
+			j++
+		}
+	}
+	if de.logger != nil {
+		de.logger.Debug("merged by interleaving", "rows", len(de.idList), "interleaved", len(other.idList))
+	}
+	return out, nil
+}
+
+// appendFrom appends row, decoded from rowIndex of src, together with its
+// null, tombstone and exemplar.
+func (de *DeltaEncodingOf[T]) appendFrom(src *DeltaEncodingOf[T], rowIndex int, row RowOf[T]) {
+	de.appendRow(row, src.nulls.get(rowIndex))
+	if src.tombstones.get(rowIndex) {
+		de.deleteAt(len(de.idList) - 1)
+	}
+	if ex, ok := src.exemplars.get(rowIndex); ok {
+		de.attachExemplarAt(len(de.idList)-1, ex)
+	}
+}
@@ -0,0 +1,55 @@
+package delta_encoding
+
+import "math"
+
+// NaN and ±Inf cannot take part in a delta chain: NaN - x is NaN, and
+// Inf - Inf is NaN, so a single non-finite sample would corrupt every value
+// decoded after it in its block. Float columns therefore keep non-finite values
+// in a sparse side column and store a zero delta for the row, as if it repeated
+// the previous finite value. The cursor substitutes the real value on decode.
+
+// NonFinitePolicy selects how Aggregate treats NaN and ±Inf values.
+type NonFinitePolicy int
+
+const (
+	// PropagateNonFinite follows IEEE-754: a NaN makes Sum, Min and Max NaN,
+	// and an Inf makes Sum infinite.
+	PropagateNonFinite NonFinitePolicy = iota
+	// SkipNonFinite leaves NaN and ±Inf rows out of Count, Sum, Min and Max.
+	SkipNonFinite
+)
+
+// WithNonFiniteAggregation sets how Aggregate, SumValues and MinMax treat NaN
+// and ±Inf values. The default is PropagateNonFinite.
+func WithNonFiniteAggregation(policy NonFinitePolicy) Option {
+	return func(cfg *config) error {
+		cfg.nonFinitePolicy = policy
+		return nil
+	}
+}
+
+// isFinite reports whether v is neither NaN nor ±Inf. Integers always are.
+func isFinite[T Numeric](v T) bool {
+	if !isFloat[T]() {
+		return true
+	}
+	f := float64(v)
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// chainValue returns the value row rowIndex contributes to the delta chain,
+// recording it in the side column if it is not finite. prev is the chain value
+// of the row before (0 for the first row).
+func (de *DeltaEncodingOf[T]) chainValue(rowIndex int, value, prev T) T {
+	if isFinite(value) {
+		de.nonFinite.clear(rowIndex)
+		return value
+	}
+	de.nonFinite.set(rowIndex, value)
+	return prev
+}
+
+// NonFinite returns the number of NaN and ±Inf values stored.
+func (de *DeltaEncodingOf[T]) NonFinite() int {
+	return len(de.nonFinite.values)
+}
@@ -0,0 +1,37 @@
+package delta_encoding
+
+// AppendNull appends a row whose value is missing, e.g. a scrape that failed.
+// The row stores a zero delta, so the delta chain carries the previous value
+// across it and the null never distorts the deltas around it.
+// time complexity: O(1)
+func (de *DeltaEncodingOf[T]) AppendNull(id int, ts int64) {
+	de.appendRow(RowOf[T]{ID: id, TS: ts}, true)
+}
+
+// IsNull reports whether the row's value is null.
+func (de *DeltaEncodingOf[T]) IsNull(rowID int) bool {
+	rowIndex, ok := de.indexOf(rowID)
+	return ok && de.nulls.get(rowIndex)
+}
+
+// ValueOf returns the row's value and whether it is non-null, like sql.Null.
+// Rows returned by ReconstructRow and the scans carry a zero Value for nulls;
+// use ValueOf or IsNull to tell them apart from a real zero.
+func (de *DeltaEncodingOf[T]) ValueOf(rowID int) (T, bool, error) {
+	row, err := de.ReconstructRow(rowID)
+	if err != nil {
+		return 0, false, err
+	}
+	return row.Value, !de.IsNull(rowID), nil
+}
+
+// Nulls returns the number of rows with a null value.
+func (de *DeltaEncodingOf[T]) Nulls() int {
+	count := 0
+	for rowIndex := range de.idList {
+		if de.nulls.get(rowIndex) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,92 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// config collects the settings applied by options. It is kept separate from
+// DeltaEncodingOf so the same options work for every value type.
+type config struct {
+	checkpointInterval int
+	valueSampleRate    int
+	logger             *slog.Logger
+	verify             bool
+	timeBucketSize     int64
+	tsDeltaOfDelta     bool
+	timePrecision      Precision
+	reorderWindow      *int64
+	adaptiveTarget     float64
+	compressor         Compressor
+	checksum           Checksum
+	nonFinitePolicy    NonFinitePolicy
+	pageCache          int // decoded pages a BlockFile keeps
+}
+
+func defaultConfig() config {
+	return config{checkpointInterval: 4}
+}
+
+// Option configures a DeltaEncoding at construction time.
+type Option func(cfg *config) error
+
+// applyOptions returns the config opts select.
+func applyOptions(opts []Option) (config, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// WithCheckpointInterval stores an absolute checkpoint every n rows. Smaller
+// intervals make point queries cheaper at the cost of more stored checkpoints.
+func WithCheckpointInterval(n int) Option {
+	return func(cfg *config) error {
+		if n < 1 {
+			return fmt.Errorf("checkpoint interval must be >= 1, got %d", n)
+		}
+		cfg.checkpointInterval = n
+		return nil
+	}
+}
+
+// WithValueSamples is the construction-time form of EnableValueSamples.
+func WithValueSamples(rate int) Option {
+	return func(cfg *config) error {
+		if rate < 1 {
+			return fmt.Errorf("sample rate must be >= 1, got %d", rate)
+		}
+		cfg.valueSampleRate = rate
+		return nil
+	}
+}
+
+// WithLogger is the construction-time form of SetLogger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *config) error {
+		cfg.logger = logger
+		return nil
+	}
+}
+
+// WithVerification retains a copy of every appended row so that
+// VerifyDeltaEncodingCorrectness can compare it with the decoded data. It is
+// off by default because the copy costs more memory than the encoding saves.
+func WithVerification(enabled bool) Option {
+	return func(cfg *config) error {
+		cfg.verify = enabled
+		return nil
+	}
+}
+
+// WithTSDeltaOfDelta stores the ts column as second-order deltas. Regular-interval
+// series have constant ts deltas, so nearly every stored value becomes zero.
+func WithTSDeltaOfDelta() Option {
+	return func(cfg *config) error {
+		cfg.tsDeltaOfDelta = true
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// EnableValueSamples keeps a sorted sample of every rate-th value per checkpoint
+// block, so percentile queries can be answered from the block summaries without
+// decoding the deltas. It must be called before the first AppendRow.
+func (de *DeltaEncodingOf[T]) EnableValueSamples(rate int) error {
+	if rate < 1 {
+		return fmt.Errorf("sample rate must be >= 1, got %d", rate)
+	}
+	if len(de.idList) > 0 {
+		return fmt.Errorf("value samples must be enabled before appending rows")
+	}
+	de.valueSampleRate = rate
+	return nil
+}
+
+// sampleValue inserts the value at rowIndex into its block's sorted sample.
+// time complexity: O(s) where s is the sample size of the block
+func (de *DeltaEncodingOf[T]) sampleValue(rowIndex int, value T) {
+	if de.valueSampleRate == 0 || rowIndex%de.valueSampleRate != 0 {
+		return
+	}
+	block := rowIndex / de.checkpointInterval
+	for len(de.blockSamples) <= block {
+		de.blockSamples = append(de.blockSamples, []T{})
+	}
+	samples := de.blockSamples[block]
+	pos := sort.Search(len(samples), func(i int) bool { return samples[i] >= value })
+	samples = append(samples, 0)
+	copy(samples[pos+1:], samples[pos:])
+	samples[pos] = value
+	de.blockSamples[block] = samples
+}
+
+// ApproxPercentile estimates the p-th percentile (0 <= p <= 100) of the values
+// in rows [fromID, toID] using nearest-rank over the block samples.
+//
+// Note: The range is widened to whole checkpoint blocks, so values just outside
+// the requested range may contribute to the estimate.
+func (de *DeltaEncodingOf[T]) ApproxPercentile(fromID, toID int, p float64) (T, error) {
+	if de.valueSampleRate == 0 {
+		return 0, fmt.Errorf("value samples are not enabled")
+	}
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 100 {
+		return 0, fmt.Errorf("percentile must be within [0, 100], got %g", p)
+	}
+
+	merged := []T{}
+	for block := from / de.checkpointInterval; from < to && block <= (to-1)/de.checkpointInterval && block < len(de.blockSamples); block++ {
+		merged = append(merged, de.blockSamples[block]...)
+	}
+	if len(merged) == 0 {
+		return 0, fmt.Errorf("no samples cover row range [%d, %d]", fromID, toID)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+
+	rank := int(math.Ceil(p/100*float64(len(merged)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return merged[rank], nil
+}
+
+// ApproxMedian is ApproxPercentile at p = 50.
+func (de *DeltaEncodingOf[T]) ApproxMedian(fromID, toID int) (T, error) {
+	return de.ApproxPercentile(fromID, toID, 50)
+}
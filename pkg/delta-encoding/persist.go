@@ -0,0 +1,741 @@
+package delta_encoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+)
+
+// File layout, all integers as varints unless noted:
+//
+//	magic "DENC" | version byte
+//	section: flags byte | value kind byte | row count | checkpoint interval | time precision
+//	one section per checkpoint block k:
+//	    checkpoint value | checkpoint ts | checkpoint ts delta
+//	    ids | values | ts of rows [k*interval, (k+1)*interval)
+//	section: tombstone words | exemplars
+//
+// When a float column holds NaN or ±Inf values, the trailer ends with their
+// count and (row index, float64 bits) pairs, since the delta streams only hold
+// the chain around them. When rows have null values, the null bitmap words
+// follow last.
+//
+// Blocks store each column in a ColumnEncoding. By default these are the
+// in-memory streams: value deltas, and ts deltas or delta-of-deltas; a Sealed
+// encoding may pick others per column. Deltas of adversarial data, such as
+// values alternating between large positive and negative numbers, take more
+// bytes than the values themselves, so a column of a block is stored plain
+// whenever that is smaller, which bounds every block by its plain size. Unless
+// every block uses the in-memory encodings, every block payload starts with a
+// mode byte: the value encoding in bits 0-1 and the ts encoding in bits 2-3.
+//
+// With WithCompression the header also holds the compressor name, and every
+// block and trailer payload is a marker byte followed by the compressed or, when
+// compression does not help, the raw payload.
+//
+// Every section is its payload length, the payload and a checksum of the
+// payload, so bit rot or a truncated write is caught per block before any of it
+// is decoded. The checksum is a little-endian CRC32C unless the file was written
+// WithChecksum, in which case the header holds the checksum name, after the
+// compressor name if any, and blocks and trailer end with that checksum. The
+// header itself is always checked with CRC32C, since the reader only learns the
+// checksum from it. There are 1+rows/interval blocks; the last one holds only a
+// checkpoint when rows is a multiple of the interval.
+//
+// Float values are stored as 8 little-endian bytes of their float64 bits. The
+// append state (last value, ts and ts delta) is not stored; it is the last row,
+// which decodes from the streams.
+const (
+	fileMagic   = "DENC"
+	fileVersion = 2
+
+	flagTSDeltaOfDelta = 1 << 0
+	flagCompressed     = 1 << 1
+	flagNonFinite      = 1 << 2
+	flagNulls          = 1 << 3
+	flagBlockModes     = 1 << 4
+	flagChecksum       = 1 << 5
+)
+
+// CorruptionError reports a section of an encoding file whose checksum does not
+// match its contents, or that ends before its stored length.
+type CorruptionError struct {
+	Section   string // "header", "block" or "trailer"
+	Block     int    // block index when Section is "block"
+	Stored    []byte
+	Computed  []byte
+	Truncated bool
+}
+
+func (e *CorruptionError) Error() string {
+	where := e.Section
+	if e.Section == "block" {
+		where = fmt.Sprintf("block %d", e.Block)
+	}
+	if e.Truncated {
+		return fmt.Sprintf("corrupt encoding file: %s is truncated", where)
+	}
+	return fmt.Sprintf("corrupt encoding file: %s checksum mismatch (stored %#x, computed %#x)", where, e.Stored, e.Computed)
+}
+
+// Is reports whether target is ErrCorruptBlock.
+func (e *CorruptionError) Is(target error) bool {
+	return target == ErrCorruptBlock
+}
+
+// appendSection appends payload with its length prefix and checksum.
+func appendSection(buf, payload []byte, c Checksum) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+	return c.Append(buf, payload)
+}
+
+// valueKind identifies T in the file header so a file is only loaded back into
+// the value type it was written from.
+func valueKind[T Numeric]() reflect.Kind {
+	return reflect.TypeFor[T]().Kind()
+}
+
+func appendValue[T Numeric](buf []byte, v T) []byte {
+	if isFloat[T]() {
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(float64(v)))
+	}
+	return binary.AppendVarint(buf, int64(v))
+}
+
+func appendValues[T Numeric](buf []byte, values []T) []byte {
+	for _, v := range values {
+		buf = appendValue(buf, v)
+	}
+	return buf
+}
+
+func appendInts[I ~int | ~int64](buf []byte, values []I) []byte {
+	for _, v := range values {
+		buf = binary.AppendVarint(buf, int64(v))
+	}
+	return buf
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// checkpointBlocks returns the number of checkpoints stored for rows rows.
+func checkpointBlocks(rows, interval int) int {
+	if rows == 0 {
+		return 0
+	}
+	return 1 + rows/interval
+}
+
+// encodeBlocks serializes every checkpoint block with the column encodings in
+// enc, falling back to EncodingPlain for a column of a block where that is
+// smaller. Unless every block uses the native encodings, each payload starts
+// with its mode byte. It also returns how many blocks fell back.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) encodeBlocks(enc ColumnEncodings) (blocks [][]byte, modes bool, plainBlocks int) {
+	native := de.nativeEncodings()
+	var b blockColumns[T]
+	var plain []byte
+	c := de.newCursor()
+	for k := range checkpointBlocks(len(de.idList), de.checkpointInterval) {
+		de.blockColumns(&b, c, k)
+		block := []byte{0}
+		block = appendValue(block, b.checkpointValue)
+		block = appendInts(block, []int64{b.checkpointTs, b.checkpointTsDelta})
+		block = appendInts(block, b.ids)
+
+		used := enc
+		column := len(block)
+		block = appendValueColumn(block, &b, enc.Value)
+		if plain = appendValueColumn(plain[:0], &b, EncodingPlain); len(plain) < len(block)-column {
+			block, used.Value = append(block[:column], plain...), EncodingPlain
+		}
+		column = len(block)
+		block = appendTSColumn(block, &b, enc.TS)
+		if plain = appendTSColumn(plain[:0], &b, EncodingPlain); len(plain) < len(block)-column {
+			block, used.TS = append(block[:column], plain...), EncodingPlain
+		}
+
+		if used != enc {
+			plainBlocks++
+		}
+		modes = modes || used != native
+		block[0] = used.modeByte()
+		blocks = append(blocks, block)
+	}
+	if !modes {
+		for k := range blocks {
+			blocks[k] = blocks[k][1:]
+		}
+	}
+	return blocks, modes, plainBlocks
+}
+
+// fileEncodings returns the column encodings files are written with.
+func (de *DeltaEncodingOf[T]) fileEncodings() ColumnEncodings {
+	if de.encodings != nil {
+		return *de.encodings
+	}
+	return de.nativeEncodings()
+}
+
+// encode serializes the encoding in the file layout, compressing blocks with c
+// unless it is nil.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) encode(c Compressor) ([]byte, error) {
+	var flags byte
+	if de.tsDeltaOfDelta {
+		flags |= flagTSDeltaOfDelta
+	}
+	if c != nil {
+		flags |= flagCompressed
+	}
+	if len(de.nonFinite.values) > 0 {
+		flags |= flagNonFinite
+	}
+	if de.Nulls() > 0 {
+		flags |= flagNulls
+	}
+	checksum := de.fileChecksum()
+	if checksum.Name() != CRC32C.Name() {
+		flags |= flagChecksum
+	}
+	blocks, modes, _ := de.encodeBlocks(de.fileEncodings())
+	if modes {
+		flags |= flagBlockModes
+	}
+	buf := append([]byte(fileMagic), fileVersion)
+
+	header := []byte{flags, byte(valueKind[T]())}
+	header = binary.AppendUvarint(header, uint64(len(de.idList)))
+	header = binary.AppendUvarint(header, uint64(de.checkpointInterval))
+	header = binary.AppendUvarint(header, uint64(de.timePrecision))
+	if c != nil {
+		header = appendString(header, c.Name())
+	}
+	if flags&flagChecksum != 0 {
+		header = appendString(header, checksum.Name())
+	}
+	buf = appendSection(buf, header, CRC32C)
+	appendBlock := func(payload []byte) error {
+		if c != nil {
+			var err error
+			if payload, err = compressSection(c, payload); err != nil {
+				return err
+			}
+		}
+		buf = appendSection(buf, payload, checksum)
+		return nil
+	}
+
+	for _, block := range blocks {
+		if err := appendBlock(block); err != nil {
+			return nil, err
+		}
+	}
+
+	trailer := binary.AppendUvarint(nil, uint64(len(de.tombstones)))
+	for _, word := range de.tombstones {
+		trailer = binary.LittleEndian.AppendUint64(trailer, word)
+	}
+	trailer = binary.AppendUvarint(trailer, uint64(len(de.exemplars.values)))
+	for rowIndex, e := range de.exemplars.rows(0, len(de.idList)) {
+		trailer = binary.AppendUvarint(trailer, uint64(rowIndex))
+		trailer = appendString(trailer, e.TraceID)
+		trailer = binary.AppendUvarint(trailer, uint64(len(e.Labels)))
+		// Sorted so the same encoding always produces the same file.
+		for _, name := range slices.Sorted(maps.Keys(e.Labels)) {
+			trailer = appendString(trailer, name)
+			trailer = appendString(trailer, e.Labels[name])
+		}
+	}
+	if flags&flagNonFinite != 0 {
+		trailer = binary.AppendUvarint(trailer, uint64(len(de.nonFinite.values)))
+		for rowIndex, v := range de.nonFinite.rows(0, len(de.idList)) {
+			trailer = binary.AppendUvarint(trailer, uint64(rowIndex))
+			trailer = binary.LittleEndian.AppendUint64(trailer, math.Float64bits(float64(v)))
+		}
+	}
+	if flags&flagNulls != 0 {
+		trailer = binary.AppendUvarint(trailer, uint64(len(de.nulls)))
+		for _, word := range de.nulls {
+			trailer = binary.LittleEndian.AppendUint64(trailer, word)
+		}
+	}
+	if err := appendBlock(trailer); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// fileChecksum returns the checksum files are written with.
+func (de *DeltaEncodingOf[T]) fileChecksum() Checksum {
+	if de.checksum != nil {
+		return de.checksum
+	}
+	return CRC32C
+}
+
+// WriteTo writes the encoding in the file layout to w. Rows still held by a
+// reorder buffer are not written; call Flush first to include them.
+func (de *DeltaEncodingOf[T]) WriteTo(w io.Writer) (int64, error) {
+	data, err := de.encode(de.compressor)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteToFile writes the encoding to path, replacing any existing file, so it
+// survives process restarts. The file is written and synced under a temporary
+// name in the same directory and then renamed over path, so a failed or
+// interrupted write leaves any previous file intact.
+func (de *DeltaEncodingOf[T]) WriteToFile(path string) error {
+	mode := os.FileMode(0o644) // a new file; a replaced one keeps its mode
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // a no-op once renamed
+	w := bufio.NewWriter(file)
+	_, err = de.WriteTo(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Chmod(mode)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// fileReader reads the file layout, remembering the first error so the caller
+// can check it once after reading a section.
+type fileReader struct {
+	buf []byte
+	err error
+}
+
+func (r *fileReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s", ErrCorruptBlock, fmt.Sprintf(format, args...))
+	}
+}
+
+// section reads one section checksummed with c and returns a reader over its
+// payload.
+func (r *fileReader) section(name string, block int, c Checksum) (*fileReader, error) {
+	n, size := binary.Uvarint(r.buf)
+	if size <= 0 || n > uint64(len(r.buf)-size) || len(r.buf)-size-int(n) < c.Size() {
+		return nil, &CorruptionError{Section: name, Block: block, Truncated: true}
+	}
+	payload := r.buf[size : size+int(n)]
+	stored := r.buf[size+int(n) : size+int(n)+c.Size()]
+	if computed := c.Append(nil, payload); !bytes.Equal(stored, computed) {
+		return nil, &CorruptionError{Section: name, Block: block, Stored: slices.Clone(stored), Computed: computed}
+	}
+	r.buf = r.buf[size+int(n)+c.Size():]
+	return &fileReader{buf: payload}, nil
+}
+
+// done records an error if a section has bytes left after parsing.
+func (r *fileReader) done() error {
+	if r.err == nil && len(r.buf) != 0 {
+		r.fail("%d trailing bytes", len(r.buf))
+	}
+	return r.err
+}
+
+func (r *fileReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail("truncated or malformed varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *fileReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail("truncated or malformed varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *fileReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 1 {
+		r.fail("truncated stream")
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *fileReader) uint64() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 8 {
+		r.fail("truncated stream")
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+func (r *fileReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.buf)) {
+		r.fail("truncated string")
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+// count reads a length prefix, rejecting lengths the remaining bytes cannot
+// hold so a corrupt header cannot trigger a huge allocation.
+func (r *fileReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail("length %d exceeds file size", n)
+		return 0
+	}
+	return int(n)
+}
+
+func readValues[T Numeric](r *fileReader, n int) []T {
+	values := make([]T, n)
+	for i := range values {
+		if isFloat[T]() {
+			values[i] = T(math.Float64frombits(r.uint64()))
+		} else {
+			values[i] = T(r.varint())
+		}
+	}
+	return values
+}
+
+func readInts[I ~int | ~int64](r *fileReader, n int) []I {
+	values := make([]I, n)
+	for i := range values {
+		values[i] = I(r.varint())
+	}
+	return values
+}
+
+// LoadFromFile reads an int64 encoding written by WriteToFile.
+func LoadFromFile(path string, opts ...Option) (*DeltaEncoding, error) {
+	return LoadFromFileOf[int64](path, opts...)
+}
+
+// LoadFromFileOf reads an encoding of T written by WriteToFile. The checkpoint
+// interval, ts mode and time precision come from the file, and so does the
+// checksum unless opts hold WithChecksum; opts configure the rest (logger,
+// verification, samples, time index, ...).
+func LoadFromFileOf[T Numeric](path string, opts ...Option) (*DeltaEncodingOf[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decode[T](data, opts...)
+}
+
+// decode parses the file layout and replays its rows into a new encoding built
+// with opts, which rebuilds every piece of derived state (append state, samples,
+// time index, verification rows) exactly as appending them did.
+// time complexity: O(n)
+func decode[T Numeric](data []byte, opts ...Option) (*DeltaEncodingOf[T], error) {
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	file := &fileReader{buf: data}
+	h, err := readHeader[T](file, len(data), &cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Every row stores its id as a varint of at least one byte, which bounds a
+	// corrupt count before it is used to size the streams; values and ts can
+	// take no bytes at all in frame-of-reference blocks. Compressed blocks can
+	// be smaller, so there the streams only grow as blocks are read.
+	capacity := h.rows
+	if h.compressor != nil {
+		capacity = min(h.rows, len(file.buf))
+	}
+
+	decoded := make([]RowOf[T], 0, capacity)
+	for k := range checkpointBlocks(h.rows, h.interval) {
+		r, err := h.section(file, "block", k)
+		if err != nil {
+			return nil, err
+		}
+		if decoded, err = readBlock(r, h, k, decoded); err != nil {
+			return nil, err
+		}
+	}
+	r, err := h.section(file, "trailer", 0)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := readTrailer[T](r, h)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.buf) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCorruptBlock, len(file.buf))
+	}
+
+	de, err := newFileEncoding[T](h, opts)
+	if err != nil {
+		return nil, err
+	}
+	for rowIndex, row := range decoded {
+		de.appendFrom(stored, rowIndex, stored.withSideValues(rowIndex, row))
+	}
+	return de, nil
+}
+
+// fileHeader is the parsed header of an encoding file, with the compressor and
+// checksum its other sections are read with.
+type fileHeader struct {
+	flags      byte
+	rows       int
+	interval   int
+	precision  Precision
+	compressor Compressor // nil for uncompressed files
+	checksum   Checksum
+}
+
+// readHeader parses the magic bytes, version and header section at the start
+// of file, leaving file at the first block. size is the length of the whole
+// file, which bounds the row count. The compressor and checksum named in the
+// header are resolved with cfg.
+func readHeader[T Numeric](file *fileReader, size int, cfg *config) (*fileHeader, error) {
+	data := file.buf
+	if len(data) < len(fileMagic)+1 || string(data[:len(fileMagic)]) != fileMagic {
+		return nil, errors.New("not an encoding file: bad magic bytes")
+	}
+	if version := data[len(fileMagic)]; version != fileVersion {
+		return nil, fmt.Errorf("unsupported encoding file version %d, want %d", version, fileVersion)
+	}
+	file.buf = data[len(fileMagic)+1:]
+
+	r, err := file.section("header", 0, CRC32C)
+	if err != nil {
+		return nil, err
+	}
+	h := &fileHeader{flags: r.byte()}
+	kind := reflect.Kind(r.byte())
+	h.rows = int(r.uvarint())
+	h.interval = int(r.uvarint())
+	h.precision = Precision(r.uvarint())
+	compressor := ""
+	if h.flags&flagCompressed != 0 {
+		compressor = r.string()
+	}
+	checksumName := CRC32C.Name()
+	if h.flags&flagChecksum != 0 {
+		checksumName = r.string()
+	}
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	if kind != valueKind[T]() {
+		return nil, fmt.Errorf("encoding file holds %s values, want %s", kind, valueKind[T]())
+	}
+	if h.interval < 1 {
+		return nil, fmt.Errorf("%w: checkpoint interval %d", ErrCorruptBlock, h.interval)
+	}
+	if h.precision != 0 && !h.precision.valid() {
+		return nil, fmt.Errorf("%w: time precision %d", ErrCorruptBlock, h.precision)
+	}
+	rest := size - (len(data) - len(file.buf))
+	if h.rows < 0 || compressor == "" && h.rows > rest {
+		return nil, fmt.Errorf("%w: row count %d exceeds file size", ErrCorruptBlock, h.rows)
+	}
+	if h.compressor, h.checksum, err = cfg.codecs("encoding file", compressor, checksumName); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// codecs resolves the compressor and checksum named in the header of what: the
+// compressor, if any, must be set in cfg, and the checksum must be built in or
+// set in cfg.
+func (cfg *config) codecs(what, compressor, checksumName string) (Compressor, Checksum, error) {
+	if compressor != "" && (cfg.compressor == nil || cfg.compressor.Name() != compressor) {
+		return nil, nil, fmt.Errorf("%s is compressed with %q; load it with WithCompression", what, compressor)
+	}
+	c, ok := checksumByName(checksumName)
+	if cfg.checksum != nil && cfg.checksum.Name() == checksumName {
+		c, ok = cfg.checksum, true
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is checksummed with %q; load it with WithChecksum", what, checksumName)
+	}
+	if compressor == "" {
+		return nil, c, nil
+	}
+	return cfg.compressor, c, nil
+}
+
+// section reads the next block or trailer section from file, verifies it and
+// undoes its compression.
+func (h *fileHeader) section(file *fileReader, name string, index int) (*fileReader, error) {
+	r, err := file.section(name, index, h.checksum)
+	if err != nil || h.compressor == nil {
+		return r, err
+	}
+	payload, err := decompressSection(h.compressor, r.buf)
+	if err != nil {
+		return nil, err
+	}
+	return &fileReader{buf: payload}, nil
+}
+
+// blockRows returns the row index range [start, end) of block k.
+func (h *fileHeader) blockRows(k int) (start, end int) {
+	start = min(k*h.interval, h.rows)
+	return start, min(start+h.interval, h.rows)
+}
+
+// readBlock parses the payload of block k and appends its rows, with chain
+// values, to dst.
+func readBlock[T Numeric](r *fileReader, h *fileHeader, k int, dst []RowOf[T]) ([]RowOf[T], error) {
+	start, end := h.blockRows(k)
+	n := end - start
+	enc := ColumnEncodings{Value: EncodingDelta, TS: EncodingDelta}
+	if h.flags&flagTSDeltaOfDelta != 0 {
+		enc.TS = EncodingDeltaOfDelta
+	}
+	if h.flags&flagBlockModes != 0 {
+		var err error
+		if enc, err = encodingsFromMode(r.byte()); err != nil {
+			r.fail("%v", err)
+		}
+	}
+	checkpointValue := readValues[T](r, 1)[0]
+	checkpoint := readInts[int64](r, 2)
+	ids := readInts[int](r, n)
+	values := readValueColumn(r, enc.Value, n, checkpointValue)
+	ts := readTSColumn(r, enc.TS, n, checkpoint[0], checkpoint[1])
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	for i := range n {
+		dst = append(dst, RowOf[T]{ID: ids[i], Value: values[i], TS: ts[i]})
+	}
+	return dst, nil
+}
+
+// readTrailer parses the trailer into an encoding without rows that holds the
+// side columns: tombstones, exemplars, non-finite values and nulls, by row
+// index of the whole file.
+func readTrailer[T Numeric](r *fileReader, h *fileHeader) (*DeltaEncodingOf[T], error) {
+	stored := &DeltaEncodingOf[T]{}
+	stored.tombstones = make(bitmap, r.count())
+	for i := range stored.tombstones {
+		stored.tombstones[i] = r.uint64()
+	}
+	for range r.count() {
+		rowIndex := int(r.uvarint())
+		ex := Exemplar{TraceID: r.string()}
+		if labels := r.count(); labels > 0 {
+			ex.Labels = make(map[string]string, labels)
+			for range labels {
+				name := r.string()
+				ex.Labels[name] = r.string()
+			}
+		}
+		if r.err != nil {
+			break
+		}
+		if rowIndex < 0 || rowIndex >= h.rows {
+			return nil, fmt.Errorf("%w: exemplar for row %d of %d", ErrCorruptBlock, rowIndex, h.rows)
+		}
+		stored.exemplars.set(rowIndex, ex)
+	}
+	if h.flags&flagNonFinite != 0 {
+		for range r.count() {
+			rowIndex := int(r.uvarint())
+			value := T(math.Float64frombits(r.uint64()))
+			if r.err != nil {
+				break
+			}
+			if rowIndex < 0 || rowIndex >= h.rows || isFinite(value) {
+				r.fail("non-finite value for row %d", rowIndex)
+				break
+			}
+			stored.nonFinite.set(rowIndex, value)
+		}
+	}
+	if h.flags&flagNulls != 0 {
+		stored.nulls = make(bitmap, r.count())
+		for i := range stored.nulls {
+			stored.nulls[i] = r.uint64()
+		}
+	}
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// newFileEncoding returns an empty encoding built with opts and the layout of
+// the file described by h. It keeps the file's checksum unless opts choose one.
+func newFileEncoding[T Numeric](h *fileHeader, opts []Option) (*DeltaEncodingOf[T], error) {
+	opts = append(slices.Clip(opts), func(cfg *config) error {
+		cfg.checkpointInterval = h.interval
+		cfg.tsDeltaOfDelta = h.flags&flagTSDeltaOfDelta != 0
+		cfg.timePrecision = h.precision
+		if cfg.checksum == nil {
+			cfg.checksum = h.checksum
+		}
+		return nil
+	})
+	return NewDEOf[T](opts...)
+}
@@ -0,0 +1,145 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"iter"
+	"math"
+)
+
+// Precision is the unit of a ts value, expressed as nanoseconds per unit.
+type Precision int64
+
+const (
+	Nanosecond  Precision = 1
+	Microsecond Precision = 1000
+	Millisecond Precision = 1000 * Microsecond
+	Second      Precision = 1000 * Millisecond
+)
+
+func (p Precision) String() string {
+	switch p {
+	case Nanosecond:
+		return "ns"
+	case Microsecond:
+		return "µs"
+	case Millisecond:
+		return "ms"
+	case Second:
+		return "s"
+	}
+	return fmt.Sprintf("Precision(%d)", int64(p))
+}
+
+func (p Precision) valid() bool {
+	return p == Nanosecond || p == Microsecond || p == Millisecond || p == Second
+}
+
+// Convert changes ts from precision p to precision to. Converting to a coarser
+// unit truncates towards negative infinity; converting to a finer unit fails
+// if the result overflows int64.
+func (p Precision) Convert(ts int64, to Precision) (int64, error) {
+	if !p.valid() || !to.valid() {
+		return 0, fmt.Errorf("unknown precision conversion %s -> %s", p, to)
+	}
+	if p >= to {
+		factor := int64(p / to)
+		if ts > math.MaxInt64/factor || ts < math.MinInt64/factor {
+			return 0, fmt.Errorf("ts %d%s overflows when converted to %s", ts, p, to)
+		}
+		return ts * factor, nil
+	}
+	factor := int64(to / p)
+	converted := ts / factor
+	if ts%factor != 0 && ts < 0 {
+		converted--
+	}
+	return converted, nil
+}
+
+// WithTimePrecision fixes the unit ts values are stored in. Rows appended with
+// AppendRowIn are normalized to it.
+func WithTimePrecision(p Precision) Option {
+	return func(cfg *config) error {
+		if !p.valid() {
+			return fmt.Errorf("unknown time precision %d", int64(p))
+		}
+		cfg.timePrecision = p
+		return nil
+	}
+}
+
+// TimePrecision returns the configured ts unit, or 0 if none was set.
+func (de *DeltaEncodingOf[T]) TimePrecision() Precision {
+	return de.timePrecision
+}
+
+// AppendRowIn appends a row whose ts is expressed in precision p, converting it
+// to the encoding's configured precision first.
+func (de *DeltaEncodingOf[T]) AppendRowIn(row RowOf[T], p Precision) error {
+	if de.timePrecision == 0 {
+		return fmt.Errorf("no time precision configured, create the encoding WithTimePrecision")
+	}
+	ts, err := p.Convert(row.TS, de.timePrecision)
+	if err != nil {
+		return err
+	}
+	row.TS = ts
+	de.AppendRow(row)
+	return nil
+}
+
+// CountInTimeRangeIn is CountInTimeRange with bounds expressed in precision p.
+// A bound finer than the stored precision only matches stored ts values whose
+// whole unit lies inside the range.
+func (de *DeltaEncodingOf[T]) CountInTimeRangeIn(fromTS, toTS int64, p Precision) (int, error) {
+	from, to, err := de.storedTimeRange(fromTS, toTS, p)
+	if err != nil {
+		return 0, err
+	}
+	return de.CountInTimeRange(from, to), nil
+}
+
+// RowsInTimeRangeIn is RowsInTimeRange with bounds expressed in precision p,
+// matching the rows CountInTimeRangeIn counts.
+func (de *DeltaEncodingOf[T]) RowsInTimeRangeIn(fromTS, toTS int64, p Precision) ([]RowOf[T], error) {
+	from, to, err := de.storedTimeRange(fromTS, toTS, p)
+	if err != nil {
+		return nil, err
+	}
+	return de.RowsInTimeRange(from, to), nil
+}
+
+// RowsInTimeRangeSeqIn is RowsInTimeRangeSeq with bounds expressed in
+// precision p.
+func (de *DeltaEncodingOf[T]) RowsInTimeRangeSeqIn(fromTS, toTS int64, p Precision) (iter.Seq[RowOf[T]], error) {
+	from, to, err := de.storedTimeRange(fromTS, toTS, p)
+	if err != nil {
+		return nil, err
+	}
+	return de.RowsInTimeRangeSeq(from, to), nil
+}
+
+// storedTimeRange converts the query bounds fromTS and toTS from precision p to
+// the stored precision, keeping only stored units that lie wholly inside the
+// range.
+func (de *DeltaEncodingOf[T]) storedTimeRange(fromTS, toTS int64, p Precision) (from, to int64, err error) {
+	if de.timePrecision == 0 {
+		return 0, 0, fmt.Errorf("no time precision configured, create the encoding WithTimePrecision")
+	}
+	if from, err = p.Convert(fromTS, de.timePrecision); err != nil {
+		return 0, 0, err
+	}
+	if to, err = p.Convert(toTS, de.timePrecision); err != nil {
+		return 0, 0, err
+	}
+	// Convert floors, so round the lower bound up when it was truncated, and
+	// end a finer upper bound at the last stored unit it fully covers.
+	if back, _ := de.timePrecision.Convert(from, p); back < fromTS {
+		from++
+	}
+	if p < de.timePrecision && toTS < math.MaxInt64 {
+		next, _ := p.Convert(toTS+1, de.timePrecision)
+		to = next - 1
+	}
+	return from, to, nil
+}
@@ -0,0 +1,52 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// QueryStats describes the work a range query does, so a client can tell a
+// slow query from a large one.
+type QueryStats struct {
+	RowsScanned   int // rows decoded, from the checkpoint the range starts in
+	RowsReturned  int // live rows in the range
+	BlocksScanned int
+	BlocksPruned  int // blocks outside the range, never read
+	BytesDecoded  int // bytes of the in-memory columns read
+}
+
+// QueryStats returns the work ReconstructColumn(col, fromID, toID) does, or
+// ReconstructRange(fromID, toID) when col is empty, without decoding anything.
+// Values and ts are walked from the checkpoint before fromID; ids are read in
+// place.
+// time complexity: O(log n + n/64)
+func (de *DeltaEncodingOf[T]) QueryStats(col string, fromID, toID int) (QueryStats, error) {
+	from, to, err := de.indexRange(fromID, toID)
+	if err != nil {
+		return QueryStats{}, err
+	}
+	var rowBytes int
+	start := from - from%de.checkpointInterval
+	switch col {
+	case "":
+		rowBytes = int(unsafe.Sizeof(0)) + int(unsafe.Sizeof(T(0))) + 8
+	case ColumnID:
+		rowBytes, start = int(unsafe.Sizeof(0)), from
+	case ColumnValue:
+		rowBytes = int(unsafe.Sizeof(T(0)))
+	case ColumnTS:
+		rowBytes = 8
+	default:
+		return QueryStats{}, fmt.Errorf("unknown column %q, want %q, %q or %q", col, ColumnID, ColumnValue, ColumnTS)
+	}
+	s := QueryStats{
+		RowsScanned:  max(0, to-start),
+		RowsReturned: to - from - (de.tombstones.rank(to) - de.tombstones.rank(from)),
+	}
+	if to > from {
+		s.BlocksScanned = (to-1)/de.checkpointInterval - from/de.checkpointInterval + 1
+	}
+	s.BlocksPruned = de.NumBlocks() - s.BlocksScanned
+	s.BytesDecoded = s.RowsScanned * rowBytes
+	return s, nil
+}
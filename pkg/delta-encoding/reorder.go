@@ -0,0 +1,80 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"sort"
+)
+
+// reorderBuffer holds recently ingested rows sorted by ts until they are older
+// than the window relative to the newest ts seen.
+type reorderBuffer[T Numeric] struct {
+	window    int64
+	rows      []RowOf[T]
+	maxTs     int64
+	committed bool // at least one row has been committed
+}
+
+// WithReorderWindow buffers rows passed to Ingest and commits them in ts order
+// once they are more than window ts units older than the newest row seen, so
+// slightly late data still lands in sorted position.
+func WithReorderWindow(window int64) Option {
+	return func(cfg *config) error {
+		if window < 0 {
+			return fmt.Errorf("reorder window must be >= 0, got %d", window)
+		}
+		cfg.reorderWindow = &window
+		return nil
+	}
+}
+
+// Ingest adds a row that may arrive out of ts order. Without a reorder window it
+// is the same as AppendRow. With one, the row is buffered and rows that fell out
+// of the window are committed. A row older than the last committed ts cannot be
+// placed any more and is rejected.
+func (de *DeltaEncodingOf[T]) Ingest(row RowOf[T]) error {
+	buf := de.reorder
+	if buf == nil {
+		de.AppendRow(row)
+		return nil
+	}
+	if buf.committed && row.TS < de.lastTs {
+		return fmt.Errorf("row %d with ts %d is older than the committed ts %d", row.ID, row.TS, de.lastTs)
+	}
+
+	// Insert after rows with an equal ts to keep arrival order among ties.
+	pos := sort.Search(len(buf.rows), func(i int) bool { return buf.rows[i].TS > row.TS })
+	buf.rows = append(buf.rows, RowOf[T]{})
+	copy(buf.rows[pos+1:], buf.rows[pos:])
+	buf.rows[pos] = row
+	if len(buf.rows) == 1 || row.TS > buf.maxTs {
+		buf.maxTs = row.TS
+	}
+
+	ready := sort.Search(len(buf.rows), func(i int) bool { return buf.rows[i].TS > buf.maxTs-buf.window })
+	de.commitBuffered(ready)
+	return nil
+}
+
+// Flush commits every buffered row regardless of the window.
+func (de *DeltaEncodingOf[T]) Flush() {
+	if de.reorder != nil {
+		de.commitBuffered(len(de.reorder.rows))
+	}
+}
+
+// Buffered returns the number of rows waiting in the reorder buffer.
+func (de *DeltaEncodingOf[T]) Buffered() int {
+	if de.reorder == nil {
+		return 0
+	}
+	return len(de.reorder.rows)
+}
+
+func (de *DeltaEncodingOf[T]) commitBuffered(n int) {
+	buf := de.reorder
+	for _, row := range buf.rows[:n] {
+		de.AppendRow(row)
+		buf.committed = true
+	}
+	buf.rows = append(buf.rows[:0], buf.rows[n:]...)
+}
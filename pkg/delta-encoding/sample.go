@@ -0,0 +1,65 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/rahil/database-internals/internal/sample"
+)
+
+// SampleScan returns every n-th row starting from the first one. Rows between
+// samples that span whole checkpoint blocks are skipped without decoding.
+func (de *DeltaEncodingOf[T]) SampleScan(every int) ([]RowOf[T], error) {
+	seq, err := de.SampleScanSeq(every)
+	if err != nil {
+		return nil, err
+	}
+	return slices.AppendSeq([]RowOf[T]{}, seq), nil
+}
+
+// SampleScanSeq is SampleScan as an iterator, decoding each row as it is
+// consumed.
+func (de *DeltaEncodingOf[T]) SampleScanSeq(every int) (iter.Seq[RowOf[T]], error) {
+	if every < 1 {
+		return nil, fmt.Errorf("sample step must be >= 1, got %d", every)
+	}
+	return func(yield func(RowOf[T]) bool) {
+		c := de.newCursor()
+		for rowIndex := 0; rowIndex < len(de.idList); rowIndex += every {
+			if !de.tombstones.get(rowIndex) && !yield(c.seek(rowIndex)) {
+				return
+			}
+		}
+	}, nil
+}
+
+// RandomSample returns n distinct live rows chosen uniformly at random, in row
+// order, or every live row when there are fewer than n. The same seed always
+// yields the same sample.
+func (de *DeltaEncodingOf[T]) RandomSample(n int, seed int64) ([]RowOf[T], error) {
+	seq, err := de.RandomSampleSeq(n, seed)
+	if err != nil {
+		return nil, err
+	}
+	return slices.AppendSeq([]RowOf[T]{}, seq), nil
+}
+
+// RandomSampleSeq is RandomSample as an iterator. The sample is drawn up
+// front; rows are decoded as they are consumed.
+func (de *DeltaEncodingOf[T]) RandomSampleSeq(n int, seed int64) (iter.Seq[RowOf[T]], error) {
+	if n < 0 {
+		return nil, fmt.Errorf("sample size must be >= 0, got %d", n)
+	}
+	// Draw ranks among the live rows, so deleted rows never shrink the sample.
+	ranks := sample.Indexes(len(de.idList)-de.deletedCount, n, seed)
+	indexes := de.tombstones.selectUnset(ranks)
+	return func(yield func(RowOf[T]) bool) {
+		c := de.newCursor()
+		for _, rowIndex := range indexes {
+			if !yield(c.seek(rowIndex)) {
+				return
+			}
+		}
+	}, nil
+}
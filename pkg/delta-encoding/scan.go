@@ -0,0 +1,101 @@
+package delta_encoding
+
+import (
+	"encoding/binary"
+	"iter"
+)
+
+// ScanOptions bounds the work done by Scan. Zero values mean unlimited.
+type ScanOptions struct {
+	Limit           int // stop once this many rows have been returned
+	MaxDecodedBytes int // stop once this many encoded bytes have been decoded
+}
+
+// ScanResultOf holds the rows returned by Scan.
+//
+// Partial is set when the decode budget ran out before the scan finished, so
+// Rows is a prefix of the full result rather than the whole answer.
+type ScanResultOf[T Numeric] struct {
+	Rows         []RowOf[T]
+	DecodedBytes int
+	Partial      bool
+}
+
+type ScanResult = ScanResultOf[int64]
+
+// Scan decodes rows in order, stopping as soon as the limit is satisfied or the
+// decode budget is exceeded. Decoded bytes are counted as the varint size of the
+// id and delta streams consumed for each row.
+// time complexity: O(min(n, limit))
+func (de *DeltaEncodingOf[T]) Scan(opts ScanOptions) ScanResultOf[T] {
+	result := ScanResultOf[T]{Rows: []RowOf[T]{}}
+	result.DecodedBytes, result.Partial = de.scan(opts, func(row RowOf[T]) bool {
+		result.Rows = append(result.Rows, row)
+		return true
+	})
+	return result
+}
+
+// ScanSeq is Scan as an iterator, decoding each row as it is consumed. It does
+// not report whether the decode budget cut the scan short; use Scan for that.
+func (de *DeltaEncodingOf[T]) ScanSeq(opts ScanOptions) iter.Seq[RowOf[T]] {
+	return func(yield func(RowOf[T]) bool) {
+		de.scan(opts, yield)
+	}
+}
+
+// scan passes the rows of Scan to yield until it returns false, and returns
+// the bytes decoded and whether the budget ran out.
+func (de *DeltaEncodingOf[T]) scan(opts ScanOptions, yield func(RowOf[T]) bool) (decoded int, partial bool) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	c := de.newCursor()
+	returned := 0
+	for rowIndex := range len(de.idList) {
+		if opts.Limit > 0 && returned >= opts.Limit {
+			break
+		}
+		rowBytes := binary.PutVarint(buf, int64(de.idList[rowIndex])) +
+			valueEncodedSize(de.deltaValueList[rowIndex], buf) +
+			binary.PutVarint(buf, de.deltaTsList[rowIndex])
+		if opts.MaxDecodedBytes > 0 && decoded+rowBytes > opts.MaxDecodedBytes {
+			if de.logger != nil {
+				de.logger.Debug("scan stopped by decode budget", "rows", returned, "decodedBytes", decoded)
+			}
+			return decoded, true
+		}
+		decoded += rowBytes
+		if !de.tombstones.get(rowIndex) {
+			returned++
+			if !yield(c.seek(rowIndex)) {
+				break
+			}
+		}
+	}
+	return decoded, false
+}
+
+// All returns an iterator over every live row in order, decoding lazily so
+// callers can stop early without reconstructing the whole table:
+//
+//	for row := range de.All() { ... }
+func (de *DeltaEncodingOf[T]) All() iter.Seq[RowOf[T]] {
+	return func(yield func(RowOf[T]) bool) {
+		for rowIndex, row := range de.allRows() {
+			if !de.tombstones.get(rowIndex) && !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// allRows iterates every encoded row by index, including deleted ones.
+func (de *DeltaEncodingOf[T]) allRows() iter.Seq2[int, RowOf[T]] {
+	return func(yield func(int, RowOf[T]) bool) {
+		c := de.newCursor()
+		for rowIndex := range len(de.idList) {
+			if !yield(rowIndex, c.seek(rowIndex)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package delta_encoding
+
+import (
+	"io"
+	"iter"
+	"maps"
+)
+
+// SealedOf is an immutable, compacted encoding produced by Seal. It keeps only
+// the encoded columns and exposes decode and query methods, so it is suited to
+// long-lived in-memory storage of a finished block.
+type SealedOf[T Numeric] struct {
+	de *DeltaEncodingOf[T]
+}
+
+type Sealed = SealedOf[int64]
+
+// Seal flushes any reorder buffer and returns the encoded rows as a read-only
+// block. Every column is copied into a slice of exact capacity, and state that
+// only the append path needs (last value and ts, verification rows, the reorder
+// buffer) is dropped. Seal also picks the smallest file encoding of each column
+// from a sample of blocks, which WriteTo uses. The receiver's reorder buffer is
+// flushed into its rows; nothing else about it changes, and it may be discarded
+// or reused.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) Seal() *SealedOf[T] {
+	if de.reorder != nil {
+		de.Flush()
+	}
+	sealed := &DeltaEncodingOf[T]{
+		idList:             exact(de.idList),
+		deltaValueList:     exact(de.deltaValueList),
+		deltaTsList:        exact(de.deltaTsList),
+		timePrecision:      de.timePrecision,
+		tsUnordered:        de.tsUnordered,
+		tombstones:         exact(de.tombstones),
+		deletedCount:       de.deletedCount,
+		idIndex:            maps.Clone(de.idIndex),
+		exemplars:          de.exemplars.clone(),
+		nonFinite:          de.nonFinite.clone(),
+		nonFinitePolicy:    de.nonFinitePolicy,
+		nulls:              exact(de.nulls),
+		checkpointInterval: de.checkpointInterval,
+		checkpointValues:   exact(de.checkpointValues),
+		checkpointTs:       exact(de.checkpointTs),
+		checkpointTsDeltas: exact(de.checkpointTsDeltas),
+		tsDeltaOfDelta:     de.tsDeltaOfDelta,
+		valueSampleRate:    de.valueSampleRate,
+		logger:             de.logger,
+		compressor:         de.compressor,
+		checksum:           de.checksum,
+	}
+	encodings := sealed.chooseEncodings()
+	sealed.encodings = &encodings
+	if de.timeIndex != nil {
+		sealed.timeIndex = de.timeIndex.clone()
+	}
+	if de.blockSamples != nil {
+		sealed.blockSamples = make([][]T, len(de.blockSamples))
+		for i, samples := range de.blockSamples {
+			sealed.blockSamples[i] = exact(samples)
+		}
+	}
+	return &SealedOf[T]{de: sealed}
+}
+
+// exact returns a copy of s whose capacity equals its length.
+func exact[S ~[]E, E any](s S) S {
+	out := make(S, len(s))
+	copy(out, s)
+	return out
+}
+
+// Encodings returns the file encoding Seal chose for each column.
+func (s *SealedOf[T]) Encodings() ColumnEncodings {
+	return *s.de.encodings
+}
+
+// WriteTo writes the sealed encoding in the file layout to w, with each column
+// in the encoding Seal chose for it.
+func (s *SealedOf[T]) WriteTo(w io.Writer) (int64, error) {
+	return s.de.WriteTo(w)
+}
+
+// WriteToFile writes the sealed encoding to path, replacing any existing file.
+func (s *SealedOf[T]) WriteToFile(path string) error {
+	return s.de.WriteToFile(path)
+}
+
+// Len returns the number of encoded rows.
+func (s *SealedOf[T]) Len() int {
+	return s.de.Len()
+}
+
+// MemoryUsage reports the memory held by the sealed encoding.
+func (s *SealedOf[T]) MemoryUsage() MemoryBreakdown {
+	return s.de.MemoryUsage()
+}
+
+// CheckpointInterval returns the number of rows per checkpoint block.
+func (s *SealedOf[T]) CheckpointInterval() int {
+	return s.de.CheckpointInterval()
+}
+
+// NumBlocks returns the number of checkpoint blocks holding at least one row.
+func (s *SealedOf[T]) NumBlocks() int {
+	return s.de.NumBlocks()
+}
+
+// Block returns the summary of the block at the given index.
+func (s *SealedOf[T]) Block(index int) (BlockSummaryOf[T], error) {
+	return s.de.Block(index)
+}
+
+// TimePrecision returns the unit of the stored ts values.
+func (s *SealedOf[T]) TimePrecision() Precision {
+	return s.de.TimePrecision()
+}
+
+// IsDeleted reports whether the row was deleted before sealing.
+func (s *SealedOf[T]) IsDeleted(rowID int) bool {
+	return s.de.IsDeleted(rowID)
+}
+
+// ReconstructRow decodes a single row.
+func (s *SealedOf[T]) ReconstructRow(rowID int) (RowOf[T], error) {
+	return s.de.ReconstructRow(rowID)
+}
+
+// ReconstructRange decodes the live rows with fromID <= ID <= toID.
+func (s *SealedOf[T]) ReconstructRange(fromID, toID int) ([]RowOf[T], error) {
+	return s.de.ReconstructRange(fromID, toID)
+}
+
+// RangeSeq iterates the live rows with fromID <= ID <= toID.
+func (s *SealedOf[T]) RangeSeq(fromID, toID int) (iter.Seq[RowOf[T]], error) {
+	return s.de.RangeSeq(fromID, toID)
+}
+
+// Exemplar returns the exemplar attached to a row, if any.
+func (s *SealedOf[T]) Exemplar(rowID int) (Exemplar, bool) {
+	return s.de.Exemplar(rowID)
+}
+
+// ExemplarsInRange returns the exemplars of live rows with fromID <= ID <= toID.
+func (s *SealedOf[T]) ExemplarsInRange(fromID, toID int) ([]RowExemplar, error) {
+	return s.de.ExemplarsInRange(fromID, toID)
+}
+
+// ReconstructColumn decodes a single column of rows fromID..toID.
+func (s *SealedOf[T]) ReconstructColumn(col string, fromID, toID int) (ColumnOf[T], error) {
+	return s.de.ReconstructColumn(col, fromID, toID)
+}
+
+// QueryStats returns the work of a column or row range query.
+func (s *SealedOf[T]) QueryStats(col string, fromID, toID int) (QueryStats, error) {
+	return s.de.QueryStats(col, fromID, toID)
+}
+
+// DecodeValuesInto decodes the values of rows fromID..toID into dst.
+func (s *SealedOf[T]) DecodeValuesInto(dst []T, fromID, toID int) (int, error) {
+	return s.de.DecodeValuesInto(dst, fromID, toID)
+}
+
+// DecodeTSInto decodes the ts of rows fromID..toID into dst.
+func (s *SealedOf[T]) DecodeTSInto(dst []int64, fromID, toID int) (int, error) {
+	return s.de.DecodeTSInto(dst, fromID, toID)
+}
+
+// IsNull reports whether the row's value is null.
+func (s *SealedOf[T]) IsNull(rowID int) bool {
+	return s.de.IsNull(rowID)
+}
+
+// ValueOf returns the row's value and whether it is non-null.
+func (s *SealedOf[T]) ValueOf(rowID int) (T, bool, error) {
+	return s.de.ValueOf(rowID)
+}
+
+// ReconstructTable decodes every live row.
+func (s *SealedOf[T]) ReconstructTable() ([]RowOf[T], error) {
+	return s.de.ReconstructTable()
+}
+
+// All returns an iterator over the live rows in order.
+func (s *SealedOf[T]) All() iter.Seq[RowOf[T]] {
+	return s.de.All()
+}
+
+// Rows returns a cursor-style iterator over the live rows.
+func (s *SealedOf[T]) Rows() *RowIterator[T] {
+	return s.de.Rows()
+}
+
+// Scan decodes rows in order within the limits of opts.
+func (s *SealedOf[T]) Scan(opts ScanOptions) ScanResultOf[T] {
+	return s.de.Scan(opts)
+}
+
+// ScanSeq iterates the rows Scan returns.
+func (s *SealedOf[T]) ScanSeq(opts ScanOptions) iter.Seq[RowOf[T]] {
+	return s.de.ScanSeq(opts)
+}
+
+// CountInTimeRange returns the number of live rows with fromTS <= ts <= toTS.
+func (s *SealedOf[T]) CountInTimeRange(fromTS, toTS int64) int {
+	return s.de.CountInTimeRange(fromTS, toTS)
+}
+
+// RowsInTimeRange returns the live rows with fromTS <= ts <= toTS.
+func (s *SealedOf[T]) RowsInTimeRange(fromTS, toTS int64) []RowOf[T] {
+	return s.de.RowsInTimeRange(fromTS, toTS)
+}
+
+// RowsInTimeRangeSeq iterates the live rows with fromTS <= ts <= toTS.
+func (s *SealedOf[T]) RowsInTimeRangeSeq(fromTS, toTS int64) iter.Seq[RowOf[T]] {
+	return s.de.RowsInTimeRangeSeq(fromTS, toTS)
+}
+
+// RowsInTimeRangeIn is RowsInTimeRange with bounds expressed in precision p.
+func (s *SealedOf[T]) RowsInTimeRangeIn(fromTS, toTS int64, p Precision) ([]RowOf[T], error) {
+	return s.de.RowsInTimeRangeIn(fromTS, toTS, p)
+}
+
+// RowsInTimeRangeSeqIn is RowsInTimeRangeSeq with bounds expressed in precision p.
+func (s *SealedOf[T]) RowsInTimeRangeSeqIn(fromTS, toTS int64, p Precision) (iter.Seq[RowOf[T]], error) {
+	return s.de.RowsInTimeRangeSeqIn(fromTS, toTS, p)
+}
+
+// CountInTimeRangeIn is CountInTimeRange with bounds expressed in precision p.
+func (s *SealedOf[T]) CountInTimeRangeIn(fromTS, toTS int64, p Precision) (int, error) {
+	return s.de.CountInTimeRangeIn(fromTS, toTS, p)
+}
+
+// Aggregate computes count, sum, min and max over the live rows in the range.
+func (s *SealedOf[T]) Aggregate(fromID, toID int) (Aggregates[T], error) {
+	return s.de.Aggregate(fromID, toID)
+}
+
+// Increase returns the counter increase over the range, accounting for resets.
+func (s *SealedOf[T]) Increase(fromID, toID int) (CounterIncrease[T], error) {
+	return s.de.Increase(fromID, toID)
+}
+
+// ApproxPercentile estimates the p-th percentile from the block samples.
+func (s *SealedOf[T]) ApproxPercentile(fromID, toID int, p float64) (T, error) {
+	return s.de.ApproxPercentile(fromID, toID, p)
+}
+
+// CheckInvariants checks that ts never decreases and row ids are unique.
+func (s *SealedOf[T]) CheckInvariants() []Violation {
+	return s.de.CheckInvariants()
+}
+
+// MarshalJSON encodes the sealed encoding like DeltaEncodingOf.MarshalJSON.
+func (s *SealedOf[T]) MarshalJSON() ([]byte, error) {
+	return s.de.MarshalJSON()
+}
@@ -0,0 +1,68 @@
+package delta_encoding
+
+import (
+	"iter"
+	"slices"
+)
+
+// sparse stores values for the few rows that have one. The presence bitmap
+// marks those rows, and the values are kept in row order, so the value of a row
+// is at the rank of its bit.
+type sparse[V any] struct {
+	present bitmap
+	values  []V
+}
+
+// get returns the value stored for rowIndex.
+// time complexity: O(n/64)
+func (s *sparse[V]) get(rowIndex int) (V, bool) {
+	if !s.present.get(rowIndex) {
+		var zero V
+		return zero, false
+	}
+	return s.values[s.present.rank(rowIndex)], true
+}
+
+// set stores v for rowIndex, replacing any value it already has.
+// time complexity: O(n/64 + k) for k stored values
+func (s *sparse[V]) set(rowIndex int, v V) {
+	rank := s.present.rank(rowIndex)
+	if s.present.get(rowIndex) {
+		s.values[rank] = v
+		return
+	}
+	s.present.set(rowIndex)
+	s.values = slices.Insert(s.values, rank, v)
+}
+
+// clear removes the value stored for rowIndex, if any.
+func (s *sparse[V]) clear(rowIndex int) {
+	if !s.present.get(rowIndex) {
+		return
+	}
+	rank := s.present.rank(rowIndex)
+	s.present.clear(rowIndex)
+	s.values = slices.Delete(s.values, rank, rank+1)
+}
+
+// rows yields the row indexes in [from, to) that hold a value, with the value.
+// time complexity: O(n/64 + (to-from))
+func (s *sparse[V]) rows(from, to int) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		next := s.present.rank(from)
+		for rowIndex := from; rowIndex < to && next < len(s.values); rowIndex++ {
+			if !s.present.get(rowIndex) {
+				continue
+			}
+			if !yield(rowIndex, s.values[next]) {
+				return
+			}
+			next++
+		}
+	}
+}
+
+// clone returns a copy with exact-capacity slices. Values are copied shallowly.
+func (s *sparse[V]) clone() sparse[V] {
+	return sparse[V]{present: exact(s.present), values: exact(s.values)}
+}
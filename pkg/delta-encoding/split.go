@@ -0,0 +1,50 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SplitByWindow cuts de into independent encodings, one per ts window of
+// windowSize units that holds rows, ordered by window. Windows are aligned to
+// multiples of windowSize, as in WithTimeBucketIndex, and every part starts
+// from its own base checkpoint, so parts can be retained, dropped or queried
+// in parallel on their own. Each part has the options of de; rows keep their
+// ids, and deleted rows, nulls and exemplars carry over. Rows whose ts went
+// backwards land in the part of their window, after that part's earlier rows.
+// de itself is unchanged, apart from flushing its reorder buffer.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) SplitByWindow(windowSize int64) ([]*DeltaEncodingOf[T], error) {
+	if windowSize < 1 {
+		return nil, fmt.Errorf("window size must be >= 1, got %d", windowSize)
+	}
+	if de.reorder != nil {
+		de.Flush()
+	}
+	parts := map[int64]*DeltaEncodingOf[T]{}
+	windows := []int64{}
+	var part *DeltaEncodingOf[T]
+	current := int64(0)
+	for rowIndex, row := range de.allRows() {
+		window := bucketOf(row.TS, windowSize)
+		if part == nil || window != current {
+			var ok bool
+			if part, ok = parts[window]; !ok {
+				part = de.emptyLike()
+				parts[window] = part
+				windows = append(windows, window)
+			}
+			current = window
+		}
+		part.appendFrom(de, rowIndex, row)
+	}
+	slices.Sort(windows)
+	out := make([]*DeltaEncodingOf[T], len(windows))
+	for i, window := range windows {
+		out[i] = parts[window]
+	}
+	if de.logger != nil {
+		de.logger.Debug("split by window", "rows", len(de.idList), "windowSize", windowSize, "parts", len(out))
+	}
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package delta_encoding
+
+// EncodingStats is a size breakdown of an encoding, with every column sized as
+// varints. Services can export it as metrics; PrintStats formats it.
+type EncodingStats struct {
+	Rows    int
+	Deleted int
+
+	IDBytes    int
+	ValueBytes int // value deltas
+	TSBytes    int // ts deltas, or delta-of-deltas in that mode
+
+	// CompressedBytes is IDBytes + ValueBytes + TSBytes.
+	CompressedBytes int
+	// CheckpointBytes is the overhead of the absolute values stored every
+	// checkpoint interval.
+	CheckpointBytes int
+	// OriginalBytes is the size of the live rows with absolute values.
+	OriginalBytes int
+
+	// TSDeltaBytes and TSDeltaOfDeltaBytes size the ts column in both modes,
+	// whichever one the encoding uses.
+	TSDeltaBytes        int
+	TSDeltaOfDeltaBytes int
+
+	// ValueFORBytes and TSFORBytes size both columns with every block in
+	// frame-of-reference form (EncodingFOR), to compare with ValueBytes and
+	// the ts sizes above. Float values are sized plain.
+	ValueFORBytes int
+	TSFORBytes    int
+
+	// FileBytes is the size WriteToFile produces without compression, and
+	// CompressedFileBytes the size with the configured Compressor (equal to
+	// FileBytes when there is none).
+	FileBytes           int
+	CompressedFileBytes int
+
+	// Blocks is the number of checkpoint blocks in the file, and PlainBlocks
+	// how many of them store a column plain because its deltas were larger.
+	Blocks      int
+	PlainBlocks int
+}
+
+// TotalBytes returns the compressed columns plus checkpoint overhead.
+func (s EncodingStats) TotalBytes() int {
+	return s.CompressedBytes + s.CheckpointBytes
+}
+
+// Ratio returns OriginalBytes / TotalBytes, or 0 for an empty encoding.
+func (s EncodingStats) Ratio() float64 {
+	if s.TotalBytes() == 0 {
+		return 0
+	}
+	return float64(s.OriginalBytes) / float64(s.TotalBytes())
+}
+
+// Stats computes the size breakdown of the encoding.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) Stats() EncodingStats {
+	stats := EncodingStats{
+		Rows:       len(de.idList),
+		Deleted:    de.deletedCount,
+		IDBytes:    varintEncodedSizeGeneric(de.idList),
+		ValueBytes: varintEncodedSizeGeneric(de.deltaValueList),
+		TSBytes:    varintEncodedSizeGeneric(de.deltaTsList),
+		CheckpointBytes: varintEncodedSizeGeneric(de.checkpointValues) +
+			varintEncodedSizeGeneric(de.checkpointTs),
+	}
+	if de.tsDeltaOfDelta {
+		stats.CheckpointBytes += varintEncodedSizeGeneric(de.checkpointTsDeltas)
+	}
+	stats.CompressedBytes = stats.IDBytes + stats.ValueBytes + stats.TSBytes
+
+	// Reconstructed rows equal the originals, so they are sized instead of
+	// requiring the originals to be retained.
+	rows, _ := de.ReconstructTable()
+	stats.OriginalBytes = binaryEncodedSize(rows)
+	stats.TSDeltaBytes, stats.TSDeltaOfDeltaBytes = de.TSEncodingSizes()
+	stats.ValueFORBytes, stats.TSFORBytes = de.forEncodingSizes()
+
+	stats.Blocks = checkpointBlocks(len(de.idList), de.checkpointInterval)
+	_, _, stats.PlainBlocks = de.encodeBlocks(de.fileEncodings())
+	file, _ := de.encode(nil)
+	stats.FileBytes = len(file)
+	stats.CompressedFileBytes = stats.FileBytes
+	if de.compressor != nil {
+		if compressed, err := de.encode(de.compressor); err == nil {
+			stats.CompressedFileBytes = len(compressed)
+		}
+	}
+	return stats
+}
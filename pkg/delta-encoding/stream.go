@@ -0,0 +1,385 @@
+package delta_encoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Stream layout, integers as varints unless noted:
+//
+//	magic "DSTR" | version byte
+//	section: flags byte | value kind byte | checkpoint interval | time precision
+//	one section per block: row count | mode byte | first value | first ts
+//	    ids | values | ts of the block's rows
+//
+// Unlike the file layout, nothing depends on the total row count, so blocks
+// are written as rows arrive. A block holds checkpoint interval rows, or fewer
+// when Flush cuts it short, and starts from its own first row; the value and ts
+// columns are encoded as in file blocks, with the mode byte always present. The
+// header names the compressor and checksum under the same flags as files, and
+// sections are checksummed and compressed the same way. The stream ends after
+// any complete section, which is where StreamDecoderOf reports io.EOF.
+const (
+	streamMagic   = "DSTR"
+	streamVersion = 1
+)
+
+// StreamEncoderOf delta-encodes rows straight to an io.Writer, holding at most
+// one block of rows in memory, for writing files or sockets too large to
+// encode in memory first. Only the checkpoint interval, ts mode, time
+// precision, compression and checksum options apply; rows carry no nulls,
+// deletes or exemplars.
+type StreamEncoderOf[T Numeric] struct {
+	w        io.Writer
+	cfg      config
+	checksum Checksum
+	block    []RowOf[T]
+	columns  blockColumns[T]
+	started  bool  // the header was written
+	err      error // the first write error, returned by every later call
+}
+
+type StreamEncoder = StreamEncoderOf[int64]
+
+// NewStreamEncoder returns an encoder of int64 rows writing to w.
+func NewStreamEncoder(w io.Writer, opts ...Option) (*StreamEncoder, error) {
+	return NewStreamEncoderOf[int64](w, opts...)
+}
+
+// NewStreamEncoderOf returns an encoder of rows of T writing to w. Nothing is
+// written until the first block is complete or Flush is called.
+func NewStreamEncoderOf[T Numeric](w io.Writer, opts ...Option) (*StreamEncoderOf[T], error) {
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	e := &StreamEncoderOf[T]{w: w, cfg: cfg, checksum: cfg.checksum}
+	if e.checksum == nil {
+		e.checksum = CRC32C
+	}
+	e.block = make([]RowOf[T], 0, cfg.checkpointInterval)
+	return e, nil
+}
+
+// Write adds row to the current block and writes the block once it holds
+// checkpoint interval rows.
+// time complexity: O(1) amortized
+func (e *StreamEncoderOf[T]) Write(row RowOf[T]) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.block = append(e.block, row)
+	if len(e.block) == e.cfg.checkpointInterval {
+		return e.writeBlock()
+	}
+	return nil
+}
+
+// Flush writes the rows of the current block, which ends it early, and flushes
+// w if it has a Flush method, such as a bufio.Writer. A stream is complete
+// after Flush; writing more rows continues it with a new block.
+func (e *StreamEncoderOf[T]) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	if !e.started || len(e.block) > 0 {
+		if err := e.writeBlock(); err != nil {
+			return err
+		}
+	}
+	if f, ok := e.w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			e.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBlock writes the header if it is due and the current block, unless it
+// is empty.
+func (e *StreamEncoderOf[T]) writeBlock() error {
+	var buf []byte
+	if !e.started {
+		buf = e.appendHeader(buf)
+	}
+	if len(e.block) > 0 {
+		payload := e.appendBlock(nil)
+		if e.cfg.compressor != nil {
+			var err error
+			if payload, err = compressSection(e.cfg.compressor, payload); err != nil {
+				e.err = err
+				return err
+			}
+		}
+		buf = appendSection(buf, payload, e.checksum)
+	}
+	if _, err := e.w.Write(buf); err != nil {
+		e.err = err
+		return err
+	}
+	e.started = true
+	e.block = e.block[:0]
+	return nil
+}
+
+// appendHeader appends the magic bytes, version and header section.
+func (e *StreamEncoderOf[T]) appendHeader(buf []byte) []byte {
+	var flags byte
+	if e.cfg.tsDeltaOfDelta {
+		flags |= flagTSDeltaOfDelta
+	}
+	if e.cfg.compressor != nil {
+		flags |= flagCompressed
+	}
+	if e.checksum.Name() != CRC32C.Name() {
+		flags |= flagChecksum
+	}
+	buf = append(append(buf, streamMagic...), streamVersion)
+	header := []byte{flags, byte(valueKind[T]())}
+	header = binary.AppendUvarint(header, uint64(e.cfg.checkpointInterval))
+	header = binary.AppendUvarint(header, uint64(e.cfg.timePrecision))
+	if e.cfg.compressor != nil {
+		header = appendString(header, e.cfg.compressor.Name())
+	}
+	if flags&flagChecksum != 0 {
+		header = appendString(header, e.checksum.Name())
+	}
+	return appendSection(buf, header, CRC32C)
+}
+
+// appendBlock appends the payload of the current block. Each column is stored
+// in the native encoding, or plain when that is smaller; float values holding
+// NaN or ±Inf are always stored plain, since a delta chain cannot pass them.
+// time complexity: O(checkpointInterval)
+func (e *StreamEncoderOf[T]) appendBlock(buf []byte) []byte {
+	b := &e.columns
+	first := e.block[0]
+	b.checkpointValue, b.checkpointTs, b.checkpointTsDelta = first.Value, first.TS, 0
+	b.ids, b.values, b.ts, b.valueDeltas = b.ids[:0], b.values[:0], b.ts[:0], b.valueDeltas[:0]
+	finite := true
+	prev := first.Value
+	for _, row := range e.block {
+		b.ids = append(b.ids, row.ID)
+		b.values = append(b.values, row.Value)
+		b.ts = append(b.ts, row.TS)
+		b.valueDeltas = append(b.valueDeltas, row.Value-prev)
+		prev = row.Value
+		finite = finite && isFinite(row.Value)
+	}
+
+	enc := ColumnEncodings{Value: EncodingDelta, TS: EncodingDelta}
+	if e.cfg.tsDeltaOfDelta {
+		enc.TS = EncodingDeltaOfDelta
+	}
+	values := appendValueColumn(nil, b, enc.Value)
+	if plain := appendValueColumn(nil, b, EncodingPlain); !finite || len(plain) < len(values) {
+		values, enc.Value = plain, EncodingPlain
+	}
+	ts := appendTSColumn(nil, b, enc.TS)
+	if plain := appendTSColumn(nil, b, EncodingPlain); len(plain) < len(ts) {
+		ts, enc.TS = plain, EncodingPlain
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(e.block)))
+	buf = append(buf, enc.modeByte())
+	buf = appendValue(buf, first.Value)
+	buf = binary.AppendVarint(buf, first.TS)
+	buf = appendInts(buf, b.ids)
+	buf = append(buf, values...)
+	return append(buf, ts...)
+}
+
+// StreamDecoderOf reads rows back from a stream written by StreamEncoderOf,
+// one block at a time, so a stream larger than memory can be processed as it
+// arrives. Each block is verified before any of its rows are returned.
+type StreamDecoderOf[T Numeric] struct {
+	r      byteReader
+	cfg    config
+	header *fileHeader // nil until the header is read
+	block  int         // index of the next block, for errors
+	rows   []RowOf[T]
+	next   int   // index in rows of the row Next returns
+	err    error // io.EOF at the end of the stream, or the first error
+}
+
+type StreamDecoder = StreamDecoderOf[int64]
+
+// byteReader reads length prefixes a byte at a time without reading past them.
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// NewStreamDecoder returns a decoder of int64 rows reading from r.
+func NewStreamDecoder(r io.Reader, opts ...Option) (*StreamDecoder, error) {
+	return NewStreamDecoderOf[int64](r, opts...)
+}
+
+// NewStreamDecoderOf returns a decoder of rows of T reading from r. As with
+// files, only the compression and checksum options apply, to name codecs the
+// stream header refers to; everything else comes from the stream. Nothing is
+// read until the first call to Next. r is buffered unless it is an
+// io.ByteReader, so it may be read past the end of the stream.
+func NewStreamDecoderOf[T Numeric](r io.Reader, opts ...Option) (*StreamDecoderOf[T], error) {
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &StreamDecoderOf[T]{r: br, cfg: cfg}, nil
+}
+
+// Next returns the next row of the stream. It returns io.EOF once the stream
+// ends after a complete section, and a *CorruptionError if it ends inside one
+// or a section fails its checksum. Errors are returned by every later call.
+// time complexity: O(1) amortized
+func (d *StreamDecoderOf[T]) Next() (RowOf[T], error) {
+	if d.next == len(d.rows) {
+		if d.err == nil {
+			d.err = d.readBlock()
+		}
+		if d.err != nil {
+			return RowOf[T]{}, d.err
+		}
+	}
+	d.next++
+	return d.rows[d.next-1], nil
+}
+
+// readBlock reads the header if it is due and the next block into rows.
+// time complexity: O(checkpointInterval)
+func (d *StreamDecoderOf[T]) readBlock() error {
+	if d.header == nil {
+		if err := d.readHeader(); err != nil {
+			return err
+		}
+	}
+	r, err := d.section("block", d.block, d.header.checksum)
+	if err != nil {
+		return err
+	}
+	if d.header.compressor != nil {
+		payload, err := decompressSection(d.header.compressor, r.buf)
+		if err != nil {
+			return err
+		}
+		r = &fileReader{buf: payload}
+	}
+	n := r.count()
+	if r.err == nil && (n < 1 || n > d.header.interval) {
+		r.fail("block of %d rows, want 1 to %d", n, d.header.interval)
+	}
+	enc, err := encodingsFromMode(r.byte())
+	if err != nil {
+		r.fail("%v", err)
+	}
+	first := readValues[T](r, 1)[0]
+	firstTs := r.varint()
+	ids := readInts[int](r, n)
+	values := readValueColumn(r, enc.Value, n, first)
+	ts := readTSColumn(r, enc.TS, n, firstTs, 0)
+	if err := r.done(); err != nil {
+		return err
+	}
+	d.rows, d.next = d.rows[:0], 0
+	for i := range n {
+		d.rows = append(d.rows, RowOf[T]{ID: ids[i], Value: values[i], TS: ts[i]})
+	}
+	d.block++
+	return nil
+}
+
+// readHeader reads the magic bytes, version and header section.
+func (d *StreamDecoderOf[T]) readHeader() error {
+	magic := make([]byte, len(streamMagic)+1)
+	if _, err := io.ReadFull(d.r, magic); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &CorruptionError{Section: "header", Truncated: true}
+	} else if err != nil {
+		return err
+	}
+	if string(magic[:len(streamMagic)]) != streamMagic {
+		return errors.New("not a delta stream: bad magic bytes")
+	}
+	if version := magic[len(streamMagic)]; version != streamVersion {
+		return fmt.Errorf("unsupported delta stream version %d, want %d", version, streamVersion)
+	}
+
+	r, err := d.section("header", 0, CRC32C)
+	if err == io.EOF {
+		return &CorruptionError{Section: "header", Truncated: true}
+	} else if err != nil {
+		return err
+	}
+	h := &fileHeader{flags: r.byte()}
+	kind := reflect.Kind(r.byte())
+	h.interval = int(r.uvarint())
+	h.precision = Precision(r.uvarint())
+	compressor := ""
+	if h.flags&flagCompressed != 0 {
+		compressor = r.string()
+	}
+	checksumName := CRC32C.Name()
+	if h.flags&flagChecksum != 0 {
+		checksumName = r.string()
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	if kind != valueKind[T]() {
+		return fmt.Errorf("delta stream holds %s values, want %s", kind, valueKind[T]())
+	}
+	if h.interval < 1 {
+		return fmt.Errorf("%w: checkpoint interval %d", ErrCorruptBlock, h.interval)
+	}
+	if h.compressor, h.checksum, err = d.cfg.codecs("delta stream", compressor, checksumName); err != nil {
+		return err
+	}
+	d.header = h
+	return nil
+}
+
+// section reads the next section, checksummed with c, and returns a reader over
+// its payload. It returns io.EOF if the stream ends before the section starts.
+// The payload is read as it arrives, so a corrupt length cannot allocate more
+// than the stream holds.
+func (d *StreamDecoderOf[T]) section(name string, index int, c Checksum) (*fileReader, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := d.r.ReadByte()
+		if err == io.EOF && buf.Len() == 0 {
+			return nil, io.EOF
+		} else if err == io.EOF {
+			return nil, &CorruptionError{Section: name, Block: index, Truncated: true}
+		} else if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+		if b < 0x80 {
+			break
+		}
+		if buf.Len() == binary.MaxVarintLen64 {
+			return nil, fmt.Errorf("%w: malformed %s length", ErrCorruptBlock, name)
+		}
+	}
+	if n, width := binary.Uvarint(buf.Bytes()); width <= 0 || n > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: %s of %d bytes", ErrCorruptBlock, name, n)
+	}
+	n, _ := binary.Uvarint(buf.Bytes())
+	if _, err := io.CopyN(&buf, d.r, int64(n)+int64(c.Size())); err == io.EOF {
+		return nil, &CorruptionError{Section: name, Block: index, Truncated: true}
+	} else if err != nil {
+		return nil, err
+	}
+	file := &fileReader{buf: buf.Bytes()}
+	return file.section(name, index, c)
+}
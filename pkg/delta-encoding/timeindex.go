@@ -0,0 +1,164 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"sort"
+)
+
+// timeBucketIndex counts rows per fixed-size ts bucket. Because ts is sorted,
+// every bucket covers a contiguous range of rows starting at firstRow.
+type timeBucketIndex struct {
+	size     int64
+	buckets  []int64 // bucket number, ts / size rounded down
+	counts   []int   // live rows per bucket
+	rows     []int   // encoded rows per bucket, including deleted ones
+	firstRow []int   // index of the first row in each bucket
+	broken   bool    // set when ts went backwards; queries then fall back to a scan
+}
+
+func bucketOf(ts, size int64) int64 {
+	bucket := ts / size
+	if ts%size != 0 && ts < 0 {
+		bucket--
+	}
+	return bucket
+}
+
+// add records the row at rowIndex with the given ts.
+// time complexity: O(1)
+func (idx *timeBucketIndex) add(rowIndex int, ts int64) {
+	if idx.broken {
+		return
+	}
+	bucket := bucketOf(ts, idx.size)
+	last := len(idx.buckets) - 1
+	switch {
+	case last >= 0 && idx.buckets[last] == bucket:
+		idx.counts[last]++
+		idx.rows[last]++
+	case last < 0 || idx.buckets[last] < bucket:
+		idx.buckets = append(idx.buckets, bucket)
+		idx.counts = append(idx.counts, 1)
+		idx.rows = append(idx.rows, 1)
+		idx.firstRow = append(idx.firstRow, rowIndex)
+	default:
+		idx.broken = true
+	}
+}
+
+// bucketAt returns the position in buckets of the bucket holding the row at
+// rowIndex.
+// time complexity: O(log buckets)
+func (idx *timeBucketIndex) bucketAt(rowIndex int) int {
+	return sort.SearchInts(idx.firstRow, rowIndex+1) - 1
+}
+
+// clone returns a copy of the index with exact-capacity slices.
+func (idx *timeBucketIndex) clone() *timeBucketIndex {
+	return &timeBucketIndex{
+		size:     idx.size,
+		buckets:  exact(idx.buckets),
+		counts:   exact(idx.counts),
+		rows:     exact(idx.rows),
+		firstRow: exact(idx.firstRow),
+		broken:   idx.broken,
+	}
+}
+
+// WithTimeBucketIndex maintains a count of rows per bucket of bucketSize ts units,
+// updated on append, so CountInTimeRange can answer aligned ranges without
+// decoding any rows.
+func WithTimeBucketIndex(bucketSize int64) Option {
+	return func(cfg *config) error {
+		if bucketSize < 1 {
+			return fmt.Errorf("time bucket size must be >= 1, got %d", bucketSize)
+		}
+		cfg.timeBucketSize = bucketSize
+		return nil
+	}
+}
+
+// CountInTimeRange returns the number of rows with fromTS <= ts <= toTS.
+//
+// With a time bucket index, buckets fully inside the range are counted from the
+// index and only the rows of the (at most two) partially covered buckets are
+// decoded. Without one, or if ts was appended out of order, every row is scanned.
+func (de *DeltaEncodingOf[T]) CountInTimeRange(fromTS, toTS int64) int {
+	if fromTS > toTS {
+		return 0
+	}
+	idx := de.timeIndex
+	if idx == nil || idx.broken {
+		count := 0
+		for row := range de.All() {
+			if row.TS >= fromTS && row.TS <= toTS {
+				count++
+			}
+		}
+		return count
+	}
+
+	fromBucket, toBucket := bucketOf(fromTS, idx.size), bucketOf(toTS, idx.size)
+	count := 0
+	c := de.newCursor()
+	for i := sort.Search(len(idx.buckets), func(i int) bool { return idx.buckets[i] >= fromBucket }); i < len(idx.buckets) && idx.buckets[i] <= toBucket; i++ {
+		bucketStart := idx.buckets[i] * idx.size
+		bucketEnd := bucketStart + idx.size - 1
+		if bucketStart >= fromTS && bucketEnd <= toTS {
+			count += idx.counts[i]
+			continue
+		}
+		for rowIndex := idx.firstRow[i]; rowIndex < idx.firstRow[i]+idx.rows[i]; rowIndex++ {
+			if de.tombstones.get(rowIndex) {
+				continue
+			}
+			if ts := c.seek(rowIndex).TS; ts >= fromTS && ts <= toTS {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// RowsInTimeRange returns the live rows with fromTS <= ts <= toTS. It binary
+// searches the checkpoint timestamps for the block where the range starts, then
+// decodes forward until ts passes toTS. If ts was appended out of order, every
+// row is scanned instead.
+// time complexity: O(log b + checkpointInterval + k) for b blocks and k rows
+func (de *DeltaEncodingOf[T]) RowsInTimeRange(fromTS, toTS int64) []RowOf[T] {
+	return slices.AppendSeq([]RowOf[T]{}, de.RowsInTimeRangeSeq(fromTS, toTS))
+}
+
+// RowsInTimeRangeSeq is RowsInTimeRange as an iterator, decoding each row as it
+// is consumed.
+func (de *DeltaEncodingOf[T]) RowsInTimeRangeSeq(fromTS, toTS int64) iter.Seq[RowOf[T]] {
+	return func(yield func(RowOf[T]) bool) {
+		if fromTS > toTS || len(de.idList) == 0 {
+			return
+		}
+		if de.tsUnordered {
+			for row := range de.All() {
+				if row.TS >= fromTS && row.TS <= toTS && !yield(row) {
+					return
+				}
+			}
+			return
+		}
+		// Checkpoint k holds the ts of the row just before block k, so every
+		// row up to it is older than fromTS when that ts is.
+		block := max(sort.Search(len(de.checkpointTs), func(i int) bool { return de.checkpointTs[i] >= fromTS })-1, 0)
+
+		c := de.newCursor()
+		for rowIndex := block * de.checkpointInterval; rowIndex < len(de.idList); rowIndex++ {
+			row := c.seek(rowIndex)
+			if row.TS > toTS {
+				return
+			}
+			if row.TS >= fromTS && !de.tombstones.get(rowIndex) && !yield(row) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package delta_encoding
+
+import (
+	"maps"
+	"slices"
+)
+
+// TransformOf is one stage of a Transform pipeline. Affine stages such as
+// unit conversions are applied to the checkpoints and deltas directly; any
+// other function is applied to decoded values.
+type TransformOf[T Numeric] struct {
+	fn     func(T) T
+	affine bool
+	scale  T
+	offset T
+}
+
+type Transform = TransformOf[int64]
+
+// Affine returns the stage v*scale + offset, e.g. Affine(1.8, 32.0) converts
+// Celsius to Fahrenheit.
+func Affine[T Numeric](scale, offset T) TransformOf[T] {
+	return TransformOf[T]{
+		fn:     func(v T) T { return v*scale + offset },
+		affine: true,
+		scale:  scale,
+		offset: offset,
+	}
+}
+
+// MapValues returns a stage applying fn to every value.
+func MapValues[T Numeric](fn func(T) T) TransformOf[T] {
+	return TransformOf[T]{fn: fn}
+}
+
+// Clamp returns a stage limiting values to [lo, hi].
+func Clamp[T Numeric](lo, hi T) TransformOf[T] {
+	return MapValues(func(v T) T { return min(max(v, lo), hi) })
+}
+
+// Transform returns a new encoding whose values are those of de passed through
+// each stage in order. Ids, ts, tombstones, nulls and exemplars carry over, and
+// null values are never passed to a stage.
+//
+// When every stage is affine the stages are folded into one, and the new
+// encoding is built from de's checkpoints and deltas without decoding: a
+// checkpoint c becomes c*scale + offset and a delta d becomes d*scale. For
+// floats the results may then differ from transforming decoded values by
+// rounding. Otherwise rows are decoded and re-appended block by block.
+//
+// Any reorder buffer is flushed first, as in Seal.
+// time complexity: O(n)
+func (de *DeltaEncodingOf[T]) Transform(stages ...TransformOf[T]) *DeltaEncodingOf[T] {
+	if de.reorder != nil {
+		de.Flush()
+	}
+	folded := Affine[T](1, 0)
+	for _, stage := range stages {
+		if !stage.affine {
+			return de.mapValues(stages)
+		}
+		folded = Affine(folded.scale*stage.scale, folded.offset*stage.scale+stage.offset)
+	}
+	return de.affineValues(folded)
+}
+
+// mapValues re-appends every row of de with its value passed through stages.
+func (de *DeltaEncodingOf[T]) mapValues(stages []TransformOf[T]) *DeltaEncodingOf[T] {
+	out := de.emptyLike()
+	out.grow(len(de.idList))
+	c := de.newCursor()
+	for rowIndex := range de.idList {
+		row := c.seek(rowIndex)
+		null := de.nulls.get(rowIndex)
+		if !null {
+			for _, stage := range stages {
+				row.Value = stage.fn(row.Value)
+			}
+		}
+		out.appendRow(row, null)
+		if de.tombstones.get(rowIndex) {
+			out.deleteAt(rowIndex)
+		}
+	}
+	out.exemplars = de.exemplars.clone()
+	if de.logger != nil {
+		de.logger.Debug("transformed values", "rows", len(de.idList), "stages", len(stages))
+	}
+	return out
+}
+
+// affineValues copies de and maps the copy under the affine stage t.
+func (de *DeltaEncodingOf[T]) affineValues(t TransformOf[T]) *DeltaEncodingOf[T] {
+	out := de.clone()
+	out.affine(t.scale, t.offset)
+	return out
+}
+
+// clone returns a deep copy of de that can be appended to independently.
+func (de *DeltaEncodingOf[T]) clone() *DeltaEncodingOf[T] {
+	out := de.emptyLike()
+	out.idList = slices.Clone(de.idList)
+	out.deltaValueList = slices.Clone(de.deltaValueList)
+	out.deltaTsList = slices.Clone(de.deltaTsList)
+	out.checkpointValues = slices.Clone(de.checkpointValues)
+	out.checkpointTs = slices.Clone(de.checkpointTs)
+	out.checkpointTsDeltas = slices.Clone(de.checkpointTsDeltas)
+	out.lastValue, out.lastTs, out.lastTsDelta = de.lastValue, de.lastTs, de.lastTsDelta
+	out.tsUnordered = de.tsUnordered
+	out.tombstones = slices.Clone(de.tombstones)
+	out.deletedCount = de.deletedCount
+	out.idIndex = maps.Clone(de.idIndex)
+	out.exemplars = de.exemplars.clone()
+	out.nulls = slices.Clone(de.nulls)
+	out.nonFinite = de.nonFinite.clone()
+	out.originalRows = slices.Clone(de.originalRows)
+	if de.timeIndex != nil {
+		out.timeIndex = de.timeIndex.clone()
+	}
+	if de.blockSamples != nil {
+		out.blockSamples = make([][]T, len(de.blockSamples))
+		for i, samples := range de.blockSamples {
+			out.blockSamples[i] = slices.Clone(samples)
+		}
+	}
+	return out
+}
+
+// emptyLike returns an empty encoding with the options of de.
+func (de *DeltaEncodingOf[T]) emptyLike() *DeltaEncodingOf[T] {
+	out := &DeltaEncodingOf[T]{
+		idList:             []int{},
+		deltaValueList:     []T{},
+		deltaTsList:        []int64{},
+		checkpointInterval: de.checkpointInterval,
+		checkpointValues:   []T{},
+		checkpointTs:       []int64{},
+		checkpointTsDeltas: []int64{},
+		tsDeltaOfDelta:     de.tsDeltaOfDelta,
+		timePrecision:      de.timePrecision,
+		adaptiveTarget:     de.adaptiveTarget,
+		valueSampleRate:    de.valueSampleRate,
+		logger:             de.logger,
+		verify:             de.verify,
+		compressor:         de.compressor,
+		checksum:           de.checksum,
+		nonFinitePolicy:    de.nonFinitePolicy,
+	}
+	if de.reorder != nil {
+		out.reorder = &reorderBuffer[T]{window: de.reorder.window}
+	}
+	if de.timeIndex != nil {
+		out.timeIndex = &timeBucketIndex{size: de.timeIndex.size}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package delta_encoding
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// UpdateRow replaces the value of one row in place, which also makes a null
+// value non-null. Only the row's own delta and
+// the next row's delta change (by +diff and -diff), plus the checkpoint that
+// stores the row's absolute value if there is one, so a late correction never
+// requires re-encoding the rest of the column.
+// time complexity: O(checkpointInterval)
+func (de *DeltaEncodingOf[T]) UpdateRow(rowID int, newValue T) error {
+	rowIndex, ok := de.indexOf(rowID)
+	if !ok {
+		return fmt.Errorf("%w: id %d", ErrRowNotFound, rowID)
+	}
+	if de.tombstones.get(rowIndex) {
+		return fmt.Errorf("%w: id %d", ErrRowDeleted, rowID)
+	}
+	c := de.newCursor()
+	oldValue := c.seek(rowIndex).Value
+	oldChain := c.value
+	prevChain := oldChain - de.deltaValueList[rowIndex]
+	if rowIndex == 0 {
+		prevChain = 0
+	}
+	newChain := de.chainValue(rowIndex, newValue, prevChain)
+	wasNull := de.nulls.get(rowIndex)
+	de.nulls.clear(rowIndex)
+	diff := newChain - oldChain
+
+	if rowIndex == 0 {
+		// The first row has no delta; its value lives in the base checkpoint.
+		de.checkpointValues[0] = newChain
+	} else {
+		de.deltaValueList[rowIndex] += diff
+	}
+	if rowIndex+1 < len(de.idList) {
+		de.deltaValueList[rowIndex+1] -= diff
+	}
+	if (rowIndex+1)%de.checkpointInterval == 0 {
+		de.checkpointValues[(rowIndex+1)/de.checkpointInterval] = newChain
+	}
+	if rowIndex == len(de.idList)-1 {
+		de.lastValue = newChain
+	}
+	if de.verify {
+		de.originalRows[rowIndex].Value = newValue
+	}
+	de.resample(rowIndex, oldValue, wasNull, newValue)
+	return nil
+}
+
+// resample swaps a row's value in its block sample, if the row was sampled.
+// A null row has no sample to remove, since nulls are never sampled.
+func (de *DeltaEncodingOf[T]) resample(rowIndex int, oldValue T, wasNull bool, newValue T) {
+	if de.valueSampleRate == 0 || rowIndex%de.valueSampleRate != 0 {
+		return
+	}
+	block := rowIndex / de.checkpointInterval
+	samples := de.blockSamples[block]
+	if pos, found := slices.BinarySearch(samples, oldValue); found && !wasNull {
+		samples = slices.Delete(samples, pos, pos+1)
+	}
+	if !isFinite(newValue) {
+		de.blockSamples[block] = samples
+		return
+	}
+	pos := sort.Search(len(samples), func(i int) bool { return samples[i] >= newValue })
+	de.blockSamples[block] = slices.Insert(samples, pos, newValue)
+}
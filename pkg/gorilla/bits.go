@@ -0,0 +1,77 @@
+package gorilla
+
+import "errors"
+
+var errShortStream = errors.New("gorilla: unexpected end of bit stream")
+
+// bitWriter appends bits most significant first into a byte slice.
+type bitWriter struct {
+	buf  []byte
+	free uint8 // unused low bits in the last byte
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.free == 0 {
+		w.buf = append(w.buf, 0)
+		w.free = 8
+	}
+	w.free--
+	if bit {
+		w.buf[len(w.buf)-1] |= 1 << w.free
+	}
+}
+
+// writeBits writes the low n bits of v, filling the last byte before
+// appending new ones.
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		take := min(int(w.free), n)
+		n -= take
+		chunk := byte(v>>n) & (1<<take - 1)
+		w.free -= uint8(take)
+		w.buf[len(w.buf)-1] |= chunk << w.free
+	}
+}
+
+// bits returns the number of bits written.
+func (w *bitWriter) bits() int {
+	return len(w.buf)*8 - int(w.free)
+}
+
+// bitReader reads bits back in the order bitWriter wrote them.
+type bitReader struct {
+	buf []byte
+	pos int // next bit to read
+	end int // total bits available
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= r.end {
+		return false, errShortStream
+	}
+	bit := r.buf[r.pos/8]>>(7-r.pos%8)&1 == 1
+	r.pos++
+	return bit, nil
+}
+
+// readBits reads n bits as the low bits of the result, a byte at a time where
+// possible.
+func (r *bitReader) readBits(n int) (uint64, error) {
+	if r.pos+n > r.end {
+		return 0, errShortStream
+	}
+	var v uint64
+	for n > 0 {
+		avail := 8 - r.pos%8
+		take := min(avail, n)
+		chunk := r.buf[r.pos/8] >> (avail - take) & (1<<take - 1)
+		v = v<<take | uint64(chunk)
+		r.pos += take
+		n -= take
+	}
+	return v, nil
+}
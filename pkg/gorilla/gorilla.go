@@ -0,0 +1,216 @@
+package gorilla
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"math/bits"
+)
+
+// Column stores float64 values with Gorilla XOR compression: each value is
+// XORed with the previous one's IEEE-754 bit pattern, and only the meaningful
+// bits between the leading and trailing zeros of the result are written.
+// Slowly changing gauges share most of their sign, exponent and mantissa bits,
+// so the XOR is mostly zeros and a value often costs a single bit.
+type Column struct {
+	w        bitWriter
+	n        int
+	prev     uint64
+	leading  int // leading zeros of the current window, -1 before the first one
+	trailing int
+	stats    Stats
+}
+
+// Stats describes how the values in a column were encoded.
+type Stats struct {
+	Values       int
+	EncodedBytes int
+	RawBytes     int // 8 bytes per value
+	Repeats      int // values equal to the previous one, stored as one bit
+	WindowReuses int // values that fit the previous leading/trailing zero window
+	NewWindows   int // values that wrote a new window header
+	NonFinite    int // NaN and ±Inf values
+}
+
+// Ratio returns RawBytes / EncodedBytes, or 0 for an empty column.
+func (s Stats) Ratio() float64 {
+	if s.EncodedBytes == 0 {
+		return 0
+	}
+	return float64(s.RawBytes) / float64(s.EncodedBytes)
+}
+
+func InitColumn() *Column {
+	return &Column{leading: -1}
+}
+
+// Append encodes v after the last value. NaN and ±Inf need no special case: the
+// XOR works on bit patterns, so they round-trip exactly, NaN payload included.
+// time complexity: O(1)
+func (c *Column) Append(v float64) {
+	value := math.Float64bits(v)
+	c.n++
+	c.stats.Values++
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		c.stats.NonFinite++
+	}
+	if c.n == 1 {
+		c.w.writeBits(value, 64)
+		c.prev = value
+		return
+	}
+
+	xor := value ^ c.prev
+	c.prev = value
+	if xor == 0 {
+		c.w.writeBit(false)
+		c.stats.Repeats++
+		return
+	}
+	c.w.writeBit(true)
+
+	// The leading zero count is stored in 5 bits, so it is capped at 31.
+	leading := min(bits.LeadingZeros64(xor), 31)
+	trailing := bits.TrailingZeros64(xor)
+	if c.leading >= 0 && leading >= c.leading && trailing >= c.trailing {
+		c.w.writeBit(false)
+		c.w.writeBits(xor>>c.trailing, 64-c.leading-c.trailing)
+		c.stats.WindowReuses++
+		return
+	}
+
+	// A new window: 5 bits of leading zeros, 6 bits of meaningful length
+	// (64 wraps to 0), then the meaningful bits.
+	meaningful := 64 - leading - trailing
+	c.w.writeBit(true)
+	c.w.writeBits(uint64(leading), 5)
+	c.w.writeBits(uint64(meaningful), 6)
+	c.w.writeBits(xor>>trailing, meaningful)
+	c.leading, c.trailing = leading, trailing
+	c.stats.NewWindows++
+}
+
+// Len returns the number of values in the column.
+func (c *Column) Len() int {
+	return c.n
+}
+
+// EncodedBits returns the size of the encoded stream in bits.
+func (c *Column) EncodedBits() int {
+	return c.w.bits()
+}
+
+// Stats returns the encoding statistics of the column.
+func (c *Column) Stats() Stats {
+	stats := c.stats
+	stats.EncodedBytes = len(c.w.buf)
+	stats.RawBytes = 8 * c.n
+	return stats
+}
+
+// decoder walks the bit stream of a column from the first value.
+type decoder struct {
+	r        bitReader
+	i        int
+	n        int
+	value    uint64
+	leading  int
+	trailing int
+}
+
+func (c *Column) newDecoder() *decoder {
+	return &decoder{r: bitReader{buf: c.w.buf, end: c.w.bits()}, n: c.n}
+}
+
+// next decodes the next value.
+func (d *decoder) next() (float64, error) {
+	if d.i >= d.n {
+		return 0, fmt.Errorf("gorilla: read past value %d", d.n)
+	}
+	d.i++
+	if d.i == 1 {
+		value, err := d.r.readBits(64)
+		if err != nil {
+			return 0, err
+		}
+		d.value = value
+		return math.Float64frombits(value), nil
+	}
+
+	changed, err := d.r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if !changed {
+		return math.Float64frombits(d.value), nil
+	}
+	newWindow, err := d.r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if newWindow {
+		leading, err := d.r.readBits(5)
+		if err != nil {
+			return 0, err
+		}
+		meaningful, err := d.r.readBits(6)
+		if err != nil {
+			return 0, err
+		}
+		if meaningful == 0 {
+			meaningful = 64
+		}
+		d.leading = int(leading)
+		d.trailing = 64 - d.leading - int(meaningful)
+	}
+	xor, err := d.r.readBits(64 - d.leading - d.trailing)
+	if err != nil {
+		return 0, err
+	}
+	d.value ^= xor << d.trailing
+	return math.Float64frombits(d.value), nil
+}
+
+// Values decodes every value in the column.
+// time complexity: O(n)
+func (c *Column) Values() ([]float64, error) {
+	values := make([]float64, 0, c.n)
+	d := c.newDecoder()
+	for range c.n {
+		v, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Get decodes the value at index. The stream has no checkpoints, so every
+// lookup decodes from the first value.
+// time complexity: O(index)
+func (c *Column) Get(index int) (float64, error) {
+	if index < 0 || index >= c.n {
+		return 0, fmt.Errorf("value at index %d does not exist", index)
+	}
+	d := c.newDecoder()
+	for {
+		v, err := d.next()
+		if err != nil || d.i == index+1 {
+			return v, err
+		}
+	}
+}
+
+// All returns an iterator over the values in order.
+func (c *Column) All() iter.Seq[float64] {
+	return func(yield func(float64) bool) {
+		d := c.newDecoder()
+		for range c.n {
+			v, err := d.next()
+			if err != nil || !yield(v) {
+				return
+			}
+		}
+	}
+}
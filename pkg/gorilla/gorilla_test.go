@@ -0,0 +1,92 @@
+package gorilla
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumn(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		values := []float64{12, 12, 12.5, 13, 13, 24, -0.001, 1e300, 0, math.SmallestNonzeroFloat64, math.MaxFloat64, 12}
+		col := InitColumn()
+		for _, v := range values {
+			col.Append(v)
+		}
+		require.Equal(t, len(values), col.Len())
+
+		got, err := col.Values()
+		require.NoError(t, err)
+		require.Equal(t, values, got)
+		require.Equal(t, values, slices.Collect(col.All()))
+
+		for i, v := range values {
+			got, err := col.Get(i)
+			require.NoError(t, err)
+			require.Equal(t, v, got)
+		}
+		_, err = col.Get(len(values))
+		require.Error(t, err)
+		_, err = col.Get(-1)
+		require.Error(t, err)
+	})
+
+	t.Run("random walk", func(t *testing.T) {
+		r := rand.New(rand.NewSource(7))
+		values := []float64{}
+		value := 50.0
+		col := InitColumn()
+		for range 5000 {
+			value += math.Round((r.Float64()-0.5)*100) / 100
+			values = append(values, value)
+			col.Append(value)
+		}
+		got, err := col.Values()
+		require.NoError(t, err)
+		require.Equal(t, values, got)
+	})
+
+	t.Run("non-finite values", func(t *testing.T) {
+		payloadNaN := math.Float64frombits(0x7ff8_0000_0000_beef)
+		values := []float64{math.NaN(), 1, math.Inf(1), math.Inf(-1), payloadNaN, payloadNaN, 2, math.Inf(1)}
+		col := InitColumn()
+		for _, v := range values {
+			col.Append(v)
+		}
+		got, err := col.Values()
+		require.NoError(t, err)
+		require.Len(t, got, len(values))
+		for i := range values {
+			require.Equal(t, math.Float64bits(values[i]), math.Float64bits(got[i]), "value %d", i)
+		}
+		require.Equal(t, 6, col.Stats().NonFinite)
+		require.Equal(t, 1, col.Stats().Repeats)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		col := InitColumn()
+		require.Equal(t, Stats{}, col.Stats())
+		require.Zero(t, col.Stats().Ratio())
+
+		for range 100 {
+			col.Append(0.75)
+		}
+		stats := col.Stats()
+		require.Equal(t, 100, stats.Values)
+		require.Equal(t, 99, stats.Repeats)
+		require.Equal(t, 800, stats.RawBytes)
+		// 64 bits for the first value and one bit per repeat.
+		require.Equal(t, 64+99, col.EncodedBits())
+		require.Equal(t, 21, stats.EncodedBytes)
+		require.Greater(t, stats.Ratio(), 30.0)
+
+		col.Append(0.5)
+		col.Append(0.75)
+		stats = col.Stats()
+		require.Equal(t, 1, stats.NewWindows)
+		require.Equal(t, 1, stats.WindowReuses)
+	})
+}
@@ -0,0 +1,42 @@
+package rle
+
+// TSAggregate holds the aggregates of one GROUP BY ts bucket.
+type TSAggregate struct {
+	TS    string
+	Count int
+	Sum   int
+	Min   int
+	Max   int
+}
+
+// AggregateByTS computes GROUP BY ts aggregates one run at a time. COUNT comes
+// straight from the run length and the value column is sliced by run extent, so
+// ts is never expanded back into rows.
+// time complexity: O(n)
+func (rle *RLE) AggregateByTS() []TSAggregate {
+	aggregates := make([]TSAggregate, 0, len(rle.TSRuns))
+	start := 0
+	for i, run := range rle.TSRuns {
+		values := rle.valueList[start:rle.tsRunEnds[i]]
+		agg := TSAggregate{TS: run.ts, Count: run.count, Min: values[0], Max: values[0]}
+		for _, v := range values {
+			agg.Sum += v
+			agg.Min = min(agg.Min, v)
+			agg.Max = max(agg.Max, v)
+		}
+		aggregates = append(aggregates, agg)
+		start = rle.tsRunEnds[i]
+	}
+	return aggregates
+}
+
+// SumConstantByTS returns SUM(c) GROUP BY ts for a constant c, which is just
+// count*c per run.
+// time complexity: O(r) where r is the number of runs
+func (rle *RLE) SumConstantByTS(c int) map[string]int {
+	sums := make(map[string]int, len(rle.TSRuns))
+	for _, run := range rle.TSRuns {
+		sums[run.ts] = run.count * c
+	}
+	return sums
+}
@@ -0,0 +1,41 @@
+package rle
+
+// MapTS rewrites the ts of every run with f, e.g. to truncate timestamps to the
+// minute. Runs that become equal stay separate until Coalesce merges them.
+// time complexity: O(r) where r is the number of runs
+func (rle *RLE) MapTS(f func(ts string) string) {
+	for i := range rle.TSRuns {
+		rle.TSRuns[i].ts = f(rle.TSRuns[i].ts)
+	}
+}
+
+// Coalesce merges adjacent runs with equal ts, as left behind by MapTS, and
+// rebuilds the prefix sums over the merged runs. It returns the number of runs
+// removed. Rows, ids and values are unchanged; only the run boundaries move.
+// time complexity: O(r) where r is the number of runs
+func (rle *RLE) Coalesce() int {
+	if len(rle.TSRuns) == 0 {
+		return 0
+	}
+	merged := rle.TSRuns[:1]
+	for _, run := range rle.TSRuns[1:] {
+		if last := &merged[len(merged)-1]; last.ts == run.ts {
+			last.count += run.count
+		} else {
+			merged = append(merged, run)
+		}
+	}
+	removed := len(rle.TSRuns) - len(merged)
+	rle.TSRuns = merged
+
+	rle.tsRunEnds = rle.tsRunEnds[:0]
+	end := 0
+	for _, run := range rle.TSRuns {
+		end += run.count
+		rle.tsRunEnds = append(rle.tsRunEnds, end)
+	}
+	if rle.logger != nil && removed > 0 {
+		rle.logger.Debug("coalesced ts runs", "removed", removed, "runs", len(rle.TSRuns))
+	}
+	return removed
+}
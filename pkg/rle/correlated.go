@@ -0,0 +1,128 @@
+package rle
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CorrelatedRLE run-length encodes two correlated columns (e.g. ts and a status
+// label that only changes when ts changes) with a single set of run boundaries.
+// If a label changes inside a ts run the correlation is broken, and the encoding
+// falls back to independent runs for each column.
+type CorrelatedRLE struct {
+	correlated bool
+	runs       []correlatedRun // shared runs, used while correlated
+	runEnds    []int           // end row index of each shared run (inclusive)
+
+	tsRuns       []valueRun // independent runs, used after fallback
+	tsRunEnds    []int
+	labelRuns    []valueRun
+	labelRunEnds []int
+}
+
+type correlatedRun struct {
+	ts    string
+	label string
+	count int
+}
+
+type valueRun struct {
+	value string
+	count int
+}
+
+func InitCorrelatedRLE() *CorrelatedRLE {
+	return &CorrelatedRLE{
+		correlated: true,
+		runs:       []correlatedRun{},
+		runEnds:    []int{},
+	}
+}
+
+// Append adds one (ts, label) pair.
+// time complexity: O(1), O(n) once when the correlation breaks
+func (c *CorrelatedRLE) Append(ts, label string) {
+	if c.correlated {
+		last := len(c.runs) - 1
+		switch {
+		case last >= 0 && c.runs[last].ts == ts && c.runs[last].label == label:
+			c.runs[last].count++
+			c.runEnds[last]++
+			return
+		case last < 0 || c.runs[last].ts != ts:
+			c.runs = append(c.runs, correlatedRun{ts: ts, label: label, count: 1})
+			c.runEnds = append(c.runEnds, c.Len()+1)
+			return
+		}
+		// Same ts, different label: the columns no longer share boundaries.
+		c.splitRuns()
+	}
+	c.tsRuns, c.tsRunEnds = appendValueRun(c.tsRuns, c.tsRunEnds, ts)
+	c.labelRuns, c.labelRunEnds = appendValueRun(c.labelRuns, c.labelRunEnds, label)
+}
+
+// splitRuns converts the shared runs into independent per-column runs.
+func (c *CorrelatedRLE) splitRuns() {
+	for _, run := range c.runs {
+		for range run.count {
+			c.tsRuns, c.tsRunEnds = appendValueRun(c.tsRuns, c.tsRunEnds, run.ts)
+			c.labelRuns, c.labelRunEnds = appendValueRun(c.labelRuns, c.labelRunEnds, run.label)
+		}
+	}
+	c.correlated = false
+	c.runs = nil
+	c.runEnds = nil
+}
+
+func appendValueRun(runs []valueRun, ends []int, value string) ([]valueRun, []int) {
+	if len(runs) > 0 && runs[len(runs)-1].value == value {
+		runs[len(runs)-1].count++
+		ends[len(ends)-1]++
+		return runs, ends
+	}
+	end := 1
+	if len(ends) > 0 {
+		end = ends[len(ends)-1] + 1
+	}
+	return append(runs, valueRun{value: value, count: 1}), append(ends, end)
+}
+
+// IsCorrelated reports whether both columns still share one set of runs.
+func (c *CorrelatedRLE) IsCorrelated() bool {
+	return c.correlated
+}
+
+// Len returns the number of encoded rows.
+func (c *CorrelatedRLE) Len() int {
+	ends := c.runEnds
+	if !c.correlated {
+		ends = c.tsRunEnds
+	}
+	if len(ends) == 0 {
+		return 0
+	}
+	return ends[len(ends)-1]
+}
+
+// RunCount returns the number of runs stored across both columns.
+func (c *CorrelatedRLE) RunCount() int {
+	if c.correlated {
+		return len(c.runs)
+	}
+	return len(c.tsRuns) + len(c.labelRuns)
+}
+
+// Get returns the ts and label of the given row using binary search over run ends.
+// time complexity: O(log n)
+func (c *CorrelatedRLE) Get(rowID int) (string, string, error) {
+	if rowID <= 0 || rowID > c.Len() {
+		return "", "", fmt.Errorf("%w: id %d", ErrRowNotFound, rowID)
+	}
+	if c.correlated {
+		run := c.runs[sort.SearchInts(c.runEnds, rowID)]
+		return run.ts, run.label, nil
+	}
+	ts := c.tsRuns[sort.SearchInts(c.tsRunEnds, rowID)].value
+	label := c.labelRuns[sort.SearchInts(c.labelRunEnds, rowID)].value
+	return ts, label, nil
+}
@@ -0,0 +1,12 @@
+package rle
+
+import "errors"
+
+// Errors returned by the encodings, wrapped with the row or ts they concern.
+// Callers test for them with errors.Is.
+var (
+	// ErrRowNotFound is returned when no row has the requested id.
+	ErrRowNotFound = errors.New("row does not exist")
+	// ErrTSNotFound is returned when no run has the requested ts.
+	ErrTSNotFound = errors.New("ts not found")
+)
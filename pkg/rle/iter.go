@@ -0,0 +1,45 @@
+package rle
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+)
+
+// All returns an iterator over every row in order, walking the runs alongside
+// the id and value columns:
+//
+//	for row := range rle.All() { ... }
+func (rle *RLE) All() iter.Seq[Row] {
+	return func(yield func(Row) bool) {
+		run := 0
+		for index := range len(rle.idList) {
+			for rle.tsRunEnds[run] <= index {
+				run++
+			}
+			if !yield(Row{rle.idList[index], rle.valueList[index], rle.TSRuns[run].ts}) {
+				return
+			}
+		}
+	}
+}
+
+// RangeSeq returns an iterator over the rows with fromID <= ID <= toID, found
+// by binary search over the run ends and then walked in order.
+// time complexity: O(log r + k) for r runs and k rows
+func (rle *RLE) RangeSeq(fromID, toID int) (iter.Seq[Row], error) {
+	if fromID < 1 || fromID > toID || toID > len(rle.idList) {
+		return nil, fmt.Errorf("%w: range [%d, %d] of %d rows", ErrRowNotFound, fromID, toID, len(rle.idList))
+	}
+	return func(yield func(Row) bool) {
+		run := sort.SearchInts(rle.tsRunEnds, fromID)
+		for index := fromID - 1; index < toID; index++ {
+			if rle.tsRunEnds[run] <= index {
+				run++
+			}
+			if !yield(Row{rle.idList[index], rle.valueList[index], rle.TSRuns[run].ts}) {
+				return
+			}
+		}
+	}, nil
+}
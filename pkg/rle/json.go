@@ -0,0 +1,70 @@
+package rle
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// rleJSON is the JSON form of an RLE, for inspecting runs and for test
+// fixtures shared with other languages:
+//
+//	{
+//	  "ids": [1, 2, 3],
+//	  "values": [100, 200, 300],
+//	  "tsRuns": [{"ts": "10:00:00", "count": 2}, {"ts": "10:00:01", "count": 1}]
+//	}
+//
+// The run counts add up to the number of rows.
+type rleJSON struct {
+	IDs    []int       `json:"ids"`
+	Values []int       `json:"values"`
+	TSRuns []tsRunJSON `json:"tsRuns"`
+}
+
+type tsRunJSON struct {
+	TS    string `json:"ts"`
+	Count int    `json:"count"`
+}
+
+// MarshalJSON encodes the columns and runs in the structure documented on
+// rleJSON.
+// time complexity: O(n)
+func (rle *RLE) MarshalJSON() ([]byte, error) {
+	out := rleJSON{IDs: rle.idList, Values: rle.valueList, TSRuns: make([]tsRunJSON, len(rle.TSRuns))}
+	for i, run := range rle.TSRuns {
+		out.TSRuns[i] = tsRunJSON{TS: run.ts, Count: run.count}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON replaces the rows of rle with those in data and rebuilds the
+// run ends. The logger is kept.
+// time complexity: O(n)
+func (rle *RLE) UnmarshalJSON(data []byte) error {
+	var in rleJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if len(in.Values) != len(in.IDs) {
+		return fmt.Errorf("invalid RLE JSON: %d ids and %d values", len(in.IDs), len(in.Values))
+	}
+	runs := make([]TSRun, len(in.TSRuns))
+	runEnds := make([]int, len(in.TSRuns))
+	end := 0
+	for i, run := range in.TSRuns {
+		if run.Count < 1 {
+			return fmt.Errorf("invalid RLE JSON: run %d has count %d", i, run.Count)
+		}
+		end += run.Count
+		runs[i] = TSRun{ts: run.TS, count: run.Count}
+		runEnds[i] = end
+	}
+	if end != len(in.IDs) {
+		return fmt.Errorf("invalid RLE JSON: runs cover %d rows, want %d", end, len(in.IDs))
+	}
+	rle.idList = append([]int{}, in.IDs...)
+	rle.valueList = append([]int{}, in.Values...)
+	rle.TSRuns = runs
+	rle.tsRunEnds = runEnds
+	return nil
+}
@@ -0,0 +1,32 @@
+package rle
+
+import "unsafe"
+
+// MemoryBreakdown reports the heap bytes held by an RLE encoding, per internal
+// slice. Slices are sized by capacity; run timestamps add their string bytes.
+type MemoryBreakdown struct {
+	IDs       int // id column
+	Values    int // value column
+	TSRuns    int // ts runs, including the ts strings
+	TSRunEnds int // prefix sums used by point queries
+}
+
+// Total returns the sum of all parts.
+func (m MemoryBreakdown) Total() int {
+	return m.IDs + m.Values + m.TSRuns + m.TSRunEnds
+}
+
+// MemoryUsage reports the memory held by the encoding.
+// time complexity: O(runs)
+func (rle *RLE) MemoryUsage() MemoryBreakdown {
+	m := MemoryBreakdown{
+		IDs:       cap(rle.idList) * int(unsafe.Sizeof(0)),
+		Values:    cap(rle.valueList) * int(unsafe.Sizeof(0)),
+		TSRuns:    cap(rle.TSRuns) * int(unsafe.Sizeof(TSRun{})),
+		TSRunEnds: cap(rle.tsRunEnds) * int(unsafe.Sizeof(0)),
+	}
+	for _, run := range rle.TSRuns {
+		m.TSRuns += len(run.ts)
+	}
+	return m
+}
@@ -1,6 +1,10 @@
 package rle
 
-import "fmt"
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+)
 
 type Row struct {
 	ID    int
@@ -18,6 +22,7 @@ type RLE struct {
 	valueList []int
 	TSRuns    []TSRun
 	tsRunEnds []int // rle.tsRunEnds stores the end row index of each TS run (inclusive)
+	logger    *slog.Logger
 }
 
 
@@ -46,12 +51,31 @@ func (rle *RLE) AppendRow(row Row) {
 		} else {
 			rle.tsRunEnds = append(rle.tsRunEnds, rle.tsRunEnds[len(rle.tsRunEnds)-1]+1)
 		}
+		if rle.logger != nil {
+			rle.logger.Debug("ts run started", "ts", row.TS, "runs", len(rle.TSRuns))
+		}
 	} else {
 		rle.TSRuns[len(rle.TSRuns)-1].count++
 		rle.tsRunEnds[len(rle.tsRunEnds)-1]++
 	}
 }
 
+// TS returns the timestamp shared by every row in the run.
+func (t TSRun) TS() string {
+	return t.ts
+}
+
+// Count returns the number of rows in the run.
+func (t TSRun) Count() int {
+	return t.count
+}
+
+// SetLogger sets the logger used for debug output. A nil logger, the default,
+// disables logging.
+func (rle *RLE) SetLogger(logger *slog.Logger) {
+	rle.logger = logger
+}
+
 func (t TSRun) String() string {
 	return fmt.Sprintf("{TS: %s, Count: %d}", t.ts, t.count)
 }
@@ -60,7 +84,7 @@ func (t TSRun) String() string {
 // time complexity: O(log n)
 func (rle *RLE) ReconstructRow(rowID int) (Row, error) {
 	if rowID <= 0 || rowID > len(rle.idList) {
-		return Row{}, fmt.Errorf("row with id %d does not exist", rowID)
+		return Row{}, fmt.Errorf("%w: id %d", ErrRowNotFound, rowID)
 	}
 	ts := rle.GetTSFromRowIDFaster(rowID)
 	return Row{rle.idList[rowID-1], rle.valueList[rowID-1], ts}, nil
@@ -111,7 +135,7 @@ func (rle *RLE) GetCountofTS(ts string) (int, error) {
 			return entry.count, nil
 		}
 	}
-	return 0, fmt.Errorf("ts %s not found", ts)
+	return 0, fmt.Errorf("%w: %s", ErrTSNotFound, ts)
 }
 
 // GetCountofTSFaster implements count(ts) query using binary search.
@@ -129,5 +153,29 @@ func (rle *RLE) GetCountofTSFaster(ts string) (int, error) {
 			high = mid - 1
 		}
 	}
-	return 0, fmt.Errorf("ts %s not found", ts)
+	return 0, fmt.Errorf("%w: %s", ErrTSNotFound, ts)
+}
+
+// GetCountofTSBatch implements count(ts) for many timestamps at once by sorting the
+// requested timestamps and merging them against the runs in a single pass.
+// Timestamps that are not present are left out of the result.
+// time complexity: O(k log k + n)
+func (rle *RLE) GetCountofTSBatch(tsList []string) map[string]int {
+	sorted := append([]string{}, tsList...)
+	sort.Strings(sorted)
+
+	counts := make(map[string]int, len(sorted))
+	runIndex := 0
+	for _, ts := range sorted {
+		for runIndex < len(rle.TSRuns) && rle.TSRuns[runIndex].ts < ts {
+			runIndex++
+		}
+		if runIndex == len(rle.TSRuns) {
+			break
+		}
+		if rle.TSRuns[runIndex].ts == ts {
+			counts[ts] = rle.TSRuns[runIndex].count
+		}
+	}
+	return counts
 }
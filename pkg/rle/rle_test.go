@@ -1,7 +1,11 @@
 package rle
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 )
@@ -48,7 +52,7 @@ func TestAllUtilities(t *testing.T) {
 	require.Error(t, err)
 
 	_, err = rle.ReconstructRow(8)
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrRowNotFound)
 
 	// Test GetCountofTS for edge case: first, last, and not found
 	count, err := rle.GetCountofTS("10:00:00")
@@ -72,12 +76,14 @@ func TestAllUtilities(t *testing.T) {
 	require.Equal(t, 1, count)
 
 	_, err = rle.GetCountofTSFaster("not-exist")
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrTSNotFound)
 
 	// Test TSRun String method
 	require.Equal(t, "{TS: 10:00:00, Count: 2}", rle.TSRuns[0].String())
 	require.Equal(t, "{TS: 10:00:02, Count: 3}", rle.TSRuns[1].String())
 	require.Equal(t, "{TS: 10:00:03, Count: 1}", rle.TSRuns[2].String())
+	require.Equal(t, "10:00:02", rle.TSRuns[1].TS())
+	require.Equal(t, 3, rle.TSRuns[1].Count())
 	})
 
 	t.Run("GetCountofTS happy path", func(t *testing.T) {
@@ -119,4 +125,255 @@ func TestAllUtilities(t *testing.T) {
 		require.Error(t, err)
 		require.Equal(t, 0, count)
 	})
+
+	t.Run("GetCountofTSBatch", func(t *testing.T) {
+		counts := rle.GetCountofTSBatch([]string{"10:00:03", "10:00:01", "10:00:00", "10:00:02", "10:00:00", "10:00:09"})
+		require.Equal(t, map[string]int{"10:00:00": 2, "10:00:02": 3, "10:00:03": 1}, counts)
+
+		require.Empty(t, rle.GetCountofTSBatch(nil))
+	})
+}
+
+func TestCorrelatedRLE(t *testing.T) {
+	t.Run("shared runs while correlated", func(t *testing.T) {
+		c := InitCorrelatedRLE()
+		c.Append("10:00:00", "ok")
+		c.Append("10:00:00", "ok")
+		c.Append("10:00:01", "degraded")
+		c.Append("10:00:02", "degraded")
+
+		require.True(t, c.IsCorrelated())
+		require.Equal(t, 3, c.RunCount())
+		require.Equal(t, 4, c.Len())
+
+		ts, label, err := c.Get(2)
+		require.NoError(t, err)
+		require.Equal(t, "10:00:00", ts)
+		require.Equal(t, "ok", label)
+
+		ts, label, err = c.Get(4)
+		require.NoError(t, err)
+		require.Equal(t, "10:00:02", ts)
+		require.Equal(t, "degraded", label)
+
+		_, _, err = c.Get(5)
+		require.Error(t, err)
+	})
+
+	t.Run("fallback when correlation breaks", func(t *testing.T) {
+		c := InitCorrelatedRLE()
+		c.Append("10:00:00", "ok")
+		c.Append("10:00:00", "ok")
+		c.Append("10:00:01", "ok")
+		c.Append("10:00:01", "down")
+		c.Append("10:00:02", "down")
+
+		require.False(t, c.IsCorrelated())
+		require.Equal(t, 5, c.Len())
+		require.Equal(t, 5, c.RunCount()) // 3 ts runs + 2 label runs
+
+		expected := [][2]string{
+			{"10:00:00", "ok"}, {"10:00:00", "ok"}, {"10:00:01", "ok"},
+			{"10:00:01", "down"}, {"10:00:02", "down"},
+		}
+		for i, want := range expected {
+			ts, label, err := c.Get(i + 1)
+			require.NoError(t, err)
+			require.Equal(t, want[0], ts)
+			require.Equal(t, want[1], label)
+		}
+	})
+}
+
+func TestSampling(t *testing.T) {
+	rle := InitRLE()
+	rle.AppendRow(Row{ID: 1, Value: 100, TS: "10:00:00"})
+	rle.AppendRow(Row{ID: 2, Value: 200, TS: "10:00:00"})
+	rle.AppendRow(Row{ID: 3, Value: 300, TS: "10:00:02"})
+	rle.AppendRow(Row{ID: 4, Value: 400, TS: "10:00:02"})
+	rle.AppendRow(Row{ID: 5, Value: 500, TS: "10:00:02"})
+	rle.AppendRow(Row{ID: 6, Value: 600, TS: "10:00:03"})
+
+	t.Run("SampleScan", func(t *testing.T) {
+		rows, err := rle.SampleScan(2)
+		require.NoError(t, err)
+		require.Equal(t, []Row{
+			{ID: 1, Value: 100, TS: "10:00:00"},
+			{ID: 3, Value: 300, TS: "10:00:02"},
+			{ID: 5, Value: 500, TS: "10:00:02"},
+		}, rows)
+
+		_, err = rle.SampleScan(0)
+		require.Error(t, err)
+	})
+
+	t.Run("RandomSample", func(t *testing.T) {
+		rows, err := rle.RandomSample(3, 7)
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		for _, row := range rows {
+			expected, err := rle.ReconstructRow(row.ID)
+			require.NoError(t, err)
+			require.Equal(t, expected, row)
+		}
+
+		again, err := rle.RandomSample(3, 7)
+		require.NoError(t, err)
+		require.Equal(t, rows, again)
+
+		all, err := rle.RandomSample(10, 7)
+		require.NoError(t, err)
+		require.Len(t, all, 6)
+		none, err := rle.RandomSample(0, 7)
+		require.NoError(t, err)
+		require.Empty(t, none)
+		_, err = rle.RandomSample(-1, 7)
+		require.Error(t, err)
+	})
+}
+
+func TestAllIterator(t *testing.T) {
+	rle := InitRLE()
+	rows := []Row{
+		{ID: 1, Value: 100, TS: "10:00:00"},
+		{ID: 2, Value: 200, TS: "10:00:00"},
+		{ID: 3, Value: 300, TS: "10:00:02"},
+	}
+	for _, row := range rows {
+		rle.AppendRow(row)
+	}
+	require.Equal(t, rows, slices.Collect(rle.All()))
+	require.Empty(t, slices.Collect(InitRLE().All()))
+
+	t.Run("RangeSeq", func(t *testing.T) {
+		seq, err := rle.RangeSeq(2, 3)
+		require.NoError(t, err)
+		require.Equal(t, rows[1:], slices.Collect(seq))
+		seq, err = rle.RangeSeq(3, 3)
+		require.NoError(t, err)
+		require.Equal(t, rows[2:], slices.Collect(seq))
+		for _, bad := range [][2]int{{0, 2}, {3, 2}, {1, 4}} {
+			_, err = rle.RangeSeq(bad[0], bad[1])
+			require.ErrorIs(t, err, ErrRowNotFound)
+		}
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		seq, err := rle.SampleScanSeq(2)
+		require.NoError(t, err)
+		want, err := rle.SampleScan(2)
+		require.NoError(t, err)
+		require.Equal(t, want, slices.Collect(seq))
+		_, err = rle.SampleScanSeq(0)
+		require.Error(t, err)
+
+		seq, err = rle.RandomSampleSeq(2, 7)
+		require.NoError(t, err)
+		want, err = rle.RandomSample(2, 7)
+		require.NoError(t, err)
+		require.Equal(t, want, slices.Collect(seq))
+		for row := range seq {
+			require.Equal(t, want[0], row)
+			break
+		}
+		_, err = rle.RandomSampleSeq(-1, 7)
+		require.Error(t, err)
+	})
+}
+
+func TestAggregateByTS(t *testing.T) {
+	rle := InitRLE()
+	rle.AppendRow(Row{ID: 1, Value: 100, TS: "10:00:00"})
+	rle.AppendRow(Row{ID: 2, Value: 200, TS: "10:00:00"})
+	rle.AppendRow(Row{ID: 3, Value: 300, TS: "10:00:02"})
+	rle.AppendRow(Row{ID: 4, Value: 50, TS: "10:00:02"})
+	rle.AppendRow(Row{ID: 5, Value: 500, TS: "10:00:02"})
+	rle.AppendRow(Row{ID: 6, Value: 600, TS: "10:00:03"})
+
+	require.Equal(t, []TSAggregate{
+		{TS: "10:00:00", Count: 2, Sum: 300, Min: 100, Max: 200},
+		{TS: "10:00:02", Count: 3, Sum: 850, Min: 50, Max: 500},
+		{TS: "10:00:03", Count: 1, Sum: 600, Min: 600, Max: 600},
+	}, rle.AggregateByTS())
+
+	require.Equal(t, map[string]int{"10:00:00": 14, "10:00:02": 21, "10:00:03": 7}, rle.SumConstantByTS(7))
+	require.Empty(t, InitRLE().AggregateByTS())
+}
+
+func TestMemoryUsage(t *testing.T) {
+	require.Zero(t, InitRLE().MemoryUsage().Total())
+
+	rle := InitRLE()
+	for i := range 100 {
+		rle.AppendRow(Row{ID: i + 1, Value: i, TS: fmt.Sprintf("10:00:%02d", i/10)})
+	}
+	m := rle.MemoryUsage()
+	require.GreaterOrEqual(t, m.IDs, 100*8)
+	require.GreaterOrEqual(t, m.Values, 100*8)
+	require.GreaterOrEqual(t, m.TSRuns, 10*(int(unsafe.Sizeof(TSRun{}))+8))
+	require.GreaterOrEqual(t, m.TSRunEnds, 10*8)
+	require.Less(t, m.TSRuns, m.IDs, "runs are smaller than a column")
+	require.Equal(t, m.IDs+m.Values+m.TSRuns+m.TSRunEnds, m.Total())
+}
+
+func TestCoalesce(t *testing.T) {
+	rle := InitRLE()
+	for i, ts := range []string{"10:00:05", "10:00:05", "10:00:40", "10:01:10", "10:01:10", "10:01:50", "10:02:00"} {
+		rle.AppendRow(Row{ID: i + 1, Value: i * 10, TS: ts})
+	}
+	require.Zero(t, rle.Coalesce())
+	require.Len(t, rle.TSRuns, 5)
+
+	// Truncate to the minute: runs 1-2 and runs 3-4 now share a ts.
+	rle.MapTS(func(ts string) string { return ts[:5] })
+	require.Len(t, rle.TSRuns, 5)
+	require.Equal(t, 2, rle.Coalesce())
+	require.Equal(t, []TSRun{{"10:00", 3}, {"10:01", 3}, {"10:02", 1}}, rle.TSRuns)
+
+	for i, want := range []string{"10:00", "10:00", "10:00", "10:01", "10:01", "10:01", "10:02"} {
+		row, err := rle.ReconstructRow(i + 1)
+		require.NoError(t, err)
+		require.Equal(t, Row{ID: i + 1, Value: i * 10, TS: want}, row)
+		require.Equal(t, want, rle.GetTSFromRowID(i+1))
+	}
+	count, err := rle.GetCountofTSFaster("10:01")
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// Appends continue the last run.
+	rle.AppendRow(Row{ID: 8, Value: 70, TS: "10:02"})
+	require.Equal(t, TSRun{"10:02", 2}, rle.TSRuns[2])
+	require.Equal(t, 8, rle.tsRunEnds[2])
+
+	require.Zero(t, InitRLE().Coalesce())
+}
+
+func TestJSON(t *testing.T) {
+	rle := InitRLE()
+	for i, ts := range []string{"10:00:00", "10:00:00", "10:00:01"} {
+		rle.AppendRow(Row{ID: i + 1, Value: (i + 1) * 100, TS: ts})
+	}
+	data, err := json.Marshal(rle)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ids":[1,2,3],"values":[100,200,300],"tsRuns":[{"ts":"10:00:00","count":2},{"ts":"10:00:01","count":1}]}`, string(data))
+
+	decoded := InitRLE()
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, rle.TSRuns, decoded.TSRuns)
+	require.Equal(t, rle.tsRunEnds, decoded.tsRunEnds)
+	for id := 1; id <= 3; id++ {
+		want, err := rle.ReconstructRow(id)
+		require.NoError(t, err)
+		got, err := decoded.ReconstructRow(id)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	for _, invalid := range []string{
+		`{"ids":[1,2],"values":[1],"tsRuns":[{"ts":"a","count":2}]}`,
+		`{"ids":[1],"values":[1],"tsRuns":[{"ts":"a","count":0},{"ts":"b","count":1}]}`,
+		`{"ids":[1],"values":[1],"tsRuns":[{"ts":"a","count":2}]}`,
+	} {
+		require.Error(t, json.Unmarshal([]byte(invalid), InitRLE()), invalid)
+	}
 }
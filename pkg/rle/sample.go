@@ -0,0 +1,67 @@
+package rle
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/rahil/database-internals/internal/sample"
+)
+
+// rowsAt iterates the rows at the given ascending row indexes, walking the
+// runs once instead of binary searching for each row.
+func (rle *RLE) rowsAt(indexes iter.Seq[int]) iter.Seq[Row] {
+	return func(yield func(Row) bool) {
+		run := 0
+		for index := range indexes {
+			for rle.tsRunEnds[run] <= index {
+				run++
+			}
+			if !yield(Row{rle.idList[index], rle.valueList[index], rle.TSRuns[run].ts}) {
+				return
+			}
+		}
+	}
+}
+
+// SampleScan returns every n-th row starting from the first one.
+func (rle *RLE) SampleScan(every int) ([]Row, error) {
+	seq, err := rle.SampleScanSeq(every)
+	if err != nil {
+		return nil, err
+	}
+	return slices.AppendSeq([]Row{}, seq), nil
+}
+
+// SampleScanSeq is SampleScan as an iterator.
+func (rle *RLE) SampleScanSeq(every int) (iter.Seq[Row], error) {
+	if every < 1 {
+		return nil, fmt.Errorf("sample step must be >= 1, got %d", every)
+	}
+	return rle.rowsAt(func(yield func(int) bool) {
+		for index := 0; index < len(rle.idList); index += every {
+			if !yield(index) {
+				return
+			}
+		}
+	}), nil
+}
+
+// RandomSample returns n distinct rows chosen uniformly at random, in row order.
+// The same seed always yields the same sample.
+func (rle *RLE) RandomSample(n int, seed int64) ([]Row, error) {
+	seq, err := rle.RandomSampleSeq(n, seed)
+	if err != nil {
+		return nil, err
+	}
+	return slices.AppendSeq([]Row{}, seq), nil
+}
+
+// RandomSampleSeq is RandomSample as an iterator. The sample is drawn up front.
+func (rle *RLE) RandomSampleSeq(n int, seed int64) (iter.Seq[Row], error) {
+	if n < 0 {
+		return nil, fmt.Errorf("sample size must be >= 0, got %d", n)
+	}
+	indexes := sample.Indexes(len(rle.idList), n, seed)
+	return rle.rowsAt(slices.Values(indexes)), nil
+}